@@ -1,21 +1,238 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"io"
 	"os"
 	"os/user"
+	"strings"
 
+	"github.com/maxwellgithinji/jaba/pkg/compiler"
+	"github.com/maxwellgithinji/jaba/pkg/evaluator"
+	"github.com/maxwellgithinji/jaba/pkg/formatter"
+	"github.com/maxwellgithinji/jaba/pkg/lexer"
+	"github.com/maxwellgithinji/jaba/pkg/object"
+	"github.com/maxwellgithinji/jaba/pkg/parser"
 	"github.com/maxwellgithinji/jaba/pkg/repl"
+	"github.com/maxwellgithinji/jaba/pkg/token"
+	"github.com/maxwellgithinji/jaba/pkg/vm"
 )
 
 func main() {
-	user, err := user.Current()
+	useVM := flag.Bool("vm", false, "run the script with the bytecode virtual machine instead of the evaluator")
+	check := flag.Bool("check", false, "parse the script and report syntax errors without running it")
+	noBanner := flag.Bool("no-banner", false, "start the REPL without the greeting and the prompt, only results are printed")
+	allowFiles := flag.Bool("allow-files", false, "let read_file and write_file read and write the user's files")
+	flag.Parse()
+
+	// file access stays off unless the user asks for it, a script from somewhere else should not touch the user's files
+	evaluator.SetFileAccess(*allowFiles)
+
+	if *check {
+		if flag.NArg() == 0 {
+			fmt.Fprintln(os.Stderr, "--check needs a script to check")
+			os.Exit(2)
+		}
+
+		if err := checkFile(flag.Arg(0), os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if flag.NArg() > 1 && flag.Arg(0) == "fmt" {
+		fmtFlags := flag.NewFlagSet("fmt", flag.ExitOnError)
+		write := fmtFlags.Bool("w", false, "write the formatted source back to the script instead of printing it")
+		fmtFlags.Parse(flag.Args()[1:])
+
+		if fmtFlags.NArg() == 0 {
+			fmt.Fprintln(os.Stderr, "fmt needs a script to format")
+			os.Exit(2)
+		}
+
+		if err := formatFile(fmtFlags.Arg(0), *write, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if flag.NArg() > 0 && flag.Arg(0) == "tokens" {
+		if flag.NArg() != 2 {
+			fmt.Fprintln(os.Stderr, "tokens needs exactly one script to lex")
+			os.Exit(2)
+		}
+
+		if err := printTokens(flag.Arg(1), os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if flag.NArg() > 0 {
+		if err := runFile(flag.Arg(0), *useVM, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// piped input e.g. echo 'let x = 1; x' | jaba has nobody to greet so only the results are printed
+	quiet := *noBanner || !isTerminal(os.Stdin)
+	if !quiet {
+		user, err := user.Current()
+		if err != nil {
+			panic(err)
+		}
+
+		fmt.Printf("Hi %s! Welcome to jaba programming language\n", user.Username)
+		fmt.Println("Enter the jaba program below:")
+	}
+	repl.Run(os.Stdin, os.Stdout, repl.Options{Quiet: quiet})
+
+}
+
+// isTerminal reports whether a file is a terminal rather than a pipe or a regular file
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// runFile runs a jaba script and prints the value of its last expression
+func runFile(path string, useVM bool, out io.Writer) error {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	l := lexer.New(string(source))
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		return fmt.Errorf("parser errors:\n\t%s", strings.Join(p.Errors(), "\n\t"))
+	}
+
+	// an empty script has no value to print
+	if len(program.Statements) == 0 {
+		return nil
+	}
+
+	var result object.Object
+
+	if useVM {
+		c := compiler.New()
+		if err := c.Compile(program); err != nil {
+			return fmt.Errorf("compiler error: %s", err)
+		}
+
+		machine := vm.New(c.Bytecode())
+		if err := machine.Run(); err != nil {
+			return fmt.Errorf("vm error: %s", err)
+		}
+
+		result = machine.LastPoppedStackElem()
+	} else {
+		evaluator.SetOutput(out)
+		result = evaluator.Eval(program, object.NewEnvironment())
+
+		if errorObject, ok := result.(*object.Error); ok {
+			return fmt.Errorf("%s", errorObject.Inspect())
+		}
+	}
+
+	if result != nil {
+		fmt.Fprintln(out, result.Inspect())
+	}
+
+	return nil
+}
+
+// checkFile parses a jaba script without running it and writes every parser error to out
+// the returned error tells the caller that the script has syntax errors
+func checkFile(path string, out io.Writer) error {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	p := parser.New(lexer.New(string(source)))
+	p.ParseProgram()
+
+	errors := p.PositionedErrors()
+	for _, err := range errors {
+		fmt.Fprintf(out, "%s:%d:%d: %s\n", path, err.Line, err.Column, err.Message)
+	}
+
+	if len(errors) != 0 {
+		return fmt.Errorf("%s: %d parser errors", path, len(errors))
+	}
+
+	return nil
+}
+
+// printTokens lexes a jaba script and writes its tokens to out one per line e.g. IDENTIFIER: x
+// the returned error reports the position of the first illegal token
+func printTokens(path string, out io.Writer) error {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var illegal *token.Token
+
+	tokens := lexer.New(string(source)).Tokens()
+	for i, tok := range tokens {
+		fmt.Fprintf(out, "%s: %s\n", tok.Type, tok.Literal)
+
+		if tok.Type == token.ILLEGAL && illegal == nil {
+			illegal = &tokens[i]
+		}
+	}
+
+	if illegal != nil {
+		return fmt.Errorf("%s:%d:%d: illegal token %q", path, illegal.Line, illegal.Column, illegal.Literal)
+	}
+
+	return nil
+}
+
+// formatFile writes the canonical formatting of a jaba script to out, or back to the script when write is set.
+// a script with syntax errors is left untouched and the errors are returned
+func formatFile(path string, write bool, out io.Writer) error {
+	source, err := os.ReadFile(path)
 	if err != nil {
-		panic(err)
+		return err
+	}
+
+	p := parser.New(lexer.New(string(source)))
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		return fmt.Errorf("%s: parser errors:\n\t%s", path, strings.Join(p.Errors(), "\n\t"))
+	}
+
+	formatted := formatter.Format(program)
+
+	if !write {
+		_, err := io.WriteString(out, formatted)
+		return err
 	}
 
-	fmt.Printf("Hi %s! Welcome to jaba programming language\n", user.Username)
-	fmt.Println("Enter the jaba program below:")
-	repl.Run(os.Stdin, os.Stdout)
+	if formatted == string(source) {
+		return nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
 
+	return os.WriteFile(path, []byte(formatted), info.Mode().Perm())
 }
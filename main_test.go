@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeScript(t *testing.T, source string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "script.jaba")
+	if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+		t.Fatalf("could not write script: %s", err)
+	}
+
+	return path
+}
+
+func TestCheckFileReportsEveryError(t *testing.T) {
+	path := writeScript(t, "let x 5;\nlet y = 10;\nlet = 1;\n")
+
+	var out bytes.Buffer
+	err := checkFile(path, &out)
+	if err == nil {
+		t.Fatalf("checkFile returned no error for a script with syntax errors")
+	}
+
+	expected := []string{
+		path + ":1:7: expected next token to be =, got INTEGER(\"5\")",
+		path + ":3:5: expected next token to be IDENTIFIER, got =",
+	}
+
+	for _, line := range expected {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Errorf("output does not report %q, got: %q", line, out.String())
+		}
+	}
+}
+
+func TestCheckFileDoesNotRunTheScript(t *testing.T) {
+	path := writeScript(t, `puts("ran"); 1 + true`)
+
+	var out bytes.Buffer
+	if err := checkFile(path, &out); err != nil {
+		t.Fatalf("checkFile returned an error for a valid script: %s", err)
+	}
+
+	if out.Len() != 0 {
+		t.Errorf("checkFile wrote output for a valid script, got: %q", out.String())
+	}
+}
+
+func TestPrintTokens(t *testing.T) {
+	path := writeScript(t, "let x = 5;\nputs(x);\n")
+
+	var out bytes.Buffer
+	if err := printTokens(path, &out); err != nil {
+		t.Fatalf("printTokens returned an error for a valid script: %s", err)
+	}
+
+	expected := "LET: let\nIDENTIFIER: x\n=: =\nINTEGER: 5\n;: ;\n" +
+		"IDENTIFIER: puts\n(: (\nIDENTIFIER: x\n): )\n;: ;\nEOF: \n"
+
+	if out.String() != expected {
+		t.Errorf("wrong tokens printed. expected: %q, got: %q", expected, out.String())
+	}
+}
+
+func TestPrintTokensReportsIllegalToken(t *testing.T) {
+	path := writeScript(t, "let x = 5;\nx & 1;\n")
+
+	var out bytes.Buffer
+	err := printTokens(path, &out)
+	if err == nil {
+		t.Fatalf("printTokens returned no error for a script with an illegal token")
+	}
+
+	expected := path + ":2:3: illegal token \"&\""
+	if err.Error() != expected {
+		t.Errorf("wrong error. expected: %q, got: %q", expected, err.Error())
+	}
+
+	if !strings.HasSuffix(out.String(), "EOF: \n") {
+		t.Errorf("tokens after the illegal one were not printed, got: %q", out.String())
+	}
+}
+
+func TestFormatFile(t *testing.T) {
+	path := writeScript(t, "let add=fn(a,b){a+b};\n\n\nputs( add(1 ,2) )")
+
+	expected := "let add = fn(a, b) {\n  a + b;\n};\nputs(add(1, 2));\n"
+
+	var out bytes.Buffer
+	if err := formatFile(path, false, &out); err != nil {
+		t.Fatalf("formatFile returned an error: %s", err)
+	}
+
+	if out.String() != expected {
+		t.Errorf("wrong formatted output. expected: %q, got: %q", expected, out.String())
+	}
+
+	source, _ := os.ReadFile(path)
+	if string(source) == expected {
+		t.Errorf("formatFile without write changed the script")
+	}
+
+	out.Reset()
+	if err := formatFile(path, true, &out); err != nil {
+		t.Fatalf("formatFile returned an error: %s", err)
+	}
+
+	if out.Len() != 0 {
+		t.Errorf("formatFile with write printed the output, got: %q", out.String())
+	}
+
+	source, _ = os.ReadFile(path)
+	if string(source) != expected {
+		t.Errorf("wrong formatted script. expected: %q, got: %q", expected, string(source))
+	}
+}
+
+func TestFormatFileLeavesInvalidScriptsAlone(t *testing.T) {
+	input := "let x 5;\nlet y=1"
+	path := writeScript(t, input)
+
+	var out bytes.Buffer
+	err := formatFile(path, true, &out)
+	if err == nil {
+		t.Fatalf("formatFile returned no error for a script with syntax errors")
+	}
+
+	if !strings.Contains(err.Error(), "expected next token to be =") {
+		t.Errorf("error does not report the syntax error, got: %q", err.Error())
+	}
+
+	source, _ := os.ReadFile(path)
+	if string(source) != input {
+		t.Errorf("formatFile changed a script with syntax errors, got: %q", string(source))
+	}
+}
@@ -47,6 +47,13 @@ type Expression interface {
 	expressionNode()
 }
 
+// Span is the part of the source a node was parsed from, counted in bytes
+// Start is the offset of the first byte of the node and End the offset just past its last byte, so source[Start:End] is the node
+type Span struct {
+	Start int
+	End   int
+}
+
 // Program represents entry point where the root of the AST is initialized and other child nodes are built into the AST
 // It by extension fulfills the Node interface which is part of the Statement interface
 // by implementing TokenLiteral() and String() methods from the Node interface
@@ -110,6 +117,48 @@ func (l *LetStatement) String() string {
 	return out.String()
 }
 
+// DestructuringLetStatement binds every element of an array to a name e.g. let a, b = [1, 2];
+// It fulfils the Statement interface by implementing statementNode() method
+// It by extension fulfills the Node interface which is part of the Statement interface
+// by implementing TokenLiteral() and String() methods from the Node interface
+type DestructuringLetStatement struct {
+	//  Token represents the "let" token
+	Token token.Token
+
+	// Names are the identifiers the elements are bound to, in order
+	Names []*Identifier
+
+	// Value represents the expression that evaluates to the array being destructured
+	Value Expression
+}
+
+// statementNode method constructs a statement node in the Abstract Syntax Tree (AST) from the destructuring let statement
+func (d *DestructuringLetStatement) statementNode() {}
+
+// TokenLiteral returns the "let" literal of the statement
+func (d *DestructuringLetStatement) TokenLiteral() string {
+	return d.Token.Literal
+}
+
+// String returns a string representation of a DestructuringLetStatement node
+func (d *DestructuringLetStatement) String() string {
+	var out bytes.Buffer
+
+	names := []string{}
+	for _, name := range d.Names {
+		names = append(names, name.String())
+	}
+
+	out.WriteString(d.TokenLiteral() + " ")
+	out.WriteString(strings.Join(names, ", "))
+	out.WriteString(" = ")
+	if d.Value != nil {
+		out.WriteString(d.Value.String())
+	}
+	out.WriteString(";")
+	return out.String()
+}
+
 // Identifier represents the 2 parts of an identifier, IDENTIFIER and Value e.g. IDENTIFIER("foo")
 // It fulfils the Expression interface by implementing expressionNode() method
 // It by extension fulfills the Node interface which is part of the Expression interface
@@ -166,6 +215,68 @@ func (r *ReturnStatement) String() string {
 	return out.String()
 }
 
+// YieldStatement contains the 2 parts of the yield statement, YIELD(expression) e.g. "yield i"
+// It fulfils the Statement interface by implementing statementNode() method
+// It by extension fulfills the Node interface which is part of the Statement interface
+// by implementing TokenLiteral() and String() methods from the Node interface
+type YieldStatement struct {
+	// Token represent the yield token
+	Token token.Token
+
+	// Value is the expression handed to the caller of the generator
+	Value Expression
+}
+
+// statementNode method constructs a statement node in the Abstract Syntax Tree (AST) from the yield statement
+func (y *YieldStatement) statementNode() {}
+
+// TokenLiteral returns the literal of the yield token
+func (y *YieldStatement) TokenLiteral() string {
+	return y.Token.Literal
+}
+
+// String returns a string representation of a YieldStatement node
+func (y *YieldStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString(y.TokenLiteral() + " ")
+	if y.Value != nil {
+		out.WriteString(y.Value.String())
+	}
+	out.WriteString(";")
+	return out.String()
+}
+
+// ThrowStatement contains the 2 parts of the throw statement, THROW(expression) e.g. "throw "not found""
+// It fulfils the Statement interface by implementing statementNode() method
+// It by extension fulfills the Node interface which is part of the Statement interface
+// by implementing TokenLiteral() and String() methods from the Node interface
+type ThrowStatement struct {
+	// Token represent the throw token
+	Token token.Token
+
+	// Value is the expression whose string form becomes the error message
+	Value Expression
+}
+
+// statementNode method constructs a statement node in the Abstract Syntax Tree (AST) from the throw statement
+func (t *ThrowStatement) statementNode() {}
+
+// TokenLiteral returns the literal of the throw token
+func (t *ThrowStatement) TokenLiteral() string {
+	return t.Token.Literal
+}
+
+// String returns a string representation of a ThrowStatement node
+func (t *ThrowStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString(t.TokenLiteral() + " ")
+	if t.Value != nil {
+		out.WriteString(t.Value.String())
+	}
+	out.WriteString(";")
+	return out.String()
+}
+
 // ExpressionStatement is an expression wrapper that groups expressions
 // It fulfils the Statement interface by implementing statementNode() method
 // It by extension fulfills the Node interface which is part of the Statement interface
@@ -331,6 +442,10 @@ type IfExpression struct {
 
 	// Alternative represents the block statement to be executed when the condition is not met (ELSE)
 	Alternative *BlockStatement
+
+	// ElseIf represents the if expression that directly follows else e.g. else if (x > 1) { ... }
+	// at most one of Alternative and ElseIf is set
+	ElseIf *IfExpression
 }
 
 // expressionNode method constructs an expression node in the Abstract Syntax Tree (AST) from the if expression
@@ -354,6 +469,151 @@ func (i *IfExpression) String() string {
 		out.WriteString(i.Alternative.String())
 	}
 
+	if i.ElseIf != nil {
+		out.WriteString("else ")
+		out.WriteString(i.ElseIf.String())
+	}
+
+	return out.String()
+}
+
+// DoWhileExpression represents a loop that runs its body once and then keeps running it while the condition is truthy
+// e.g. do { x = x + 1 } while (x < 10)
+// It fulfils the Expression interface by implementing expressionNode() method
+// It by extension fulfills the Node interface which is part of the Expression interface
+// by implementing TokenLiteral() and String() methods from the Node interface
+type DoWhileExpression struct {
+	// Token represents the do token
+	Token token.Token
+
+	// Body represents the block statement that is executed on every iteration
+	Body *BlockStatement
+
+	// Condition represents the expression checked after every iteration
+	Condition Expression
+}
+
+// expressionNode method constructs an expression node in the Abstract Syntax Tree (AST) from the do while expression
+func (d *DoWhileExpression) expressionNode() {}
+
+// TokenLiteral returns the actual value of the do while expression
+func (d *DoWhileExpression) TokenLiteral() string {
+	return d.Token.Literal
+}
+
+// String returns a string representation of a DoWhileExpression node
+func (d *DoWhileExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("do ")
+	out.WriteString(d.Body.String())
+	out.WriteString(" while")
+	out.WriteString(d.Condition.String())
+
+	return out.String()
+}
+
+// ForInExpression represents a loop that runs its body once for every element of an array or value of an iterator
+// e.g. for (x in [1, 2, 3]) { puts(x) }
+// It fulfils the Expression interface by implementing expressionNode() method
+// It by extension fulfills the Node interface which is part of the Expression interface
+// by implementing TokenLiteral() and String() methods from the Node interface
+type ForInExpression struct {
+	// Token represents the for token
+	Token token.Token
+
+	// Variable represents the identifier bound to the current element
+	Variable *Identifier
+
+	// Iterable represents the expression that evaluates to the array or iterator being looped over
+	Iterable Expression
+
+	// Body represents the block statement that is executed for every element
+	Body *BlockStatement
+}
+
+// expressionNode method constructs an expression node in the Abstract Syntax Tree (AST) from the for in expression
+func (f *ForInExpression) expressionNode() {}
+
+// TokenLiteral returns the actual value of the for in expression
+func (f *ForInExpression) TokenLiteral() string {
+	return f.Token.Literal
+}
+
+// String returns a string representation of a ForInExpression node
+func (f *ForInExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("for (")
+	out.WriteString(f.Variable.String())
+	out.WriteString(" in ")
+	out.WriteString(f.Iterable.String())
+	out.WriteString(") ")
+	out.WriteString(f.Body.String())
+
+	return out.String()
+}
+
+// CollectExpression represents a loop whose iterations are gathered into an array e.g. collect for (x in xs) { x * 2 }
+// It fulfils the Expression interface by implementing expressionNode() method
+// It by extension fulfills the Node interface which is part of the Expression interface
+// by implementing TokenLiteral() and String() methods from the Node interface
+type CollectExpression struct {
+	// Token represents the collect token
+	Token token.Token
+
+	// Loop represents the do while or for in expression whose iterations are collected
+	Loop Expression
+}
+
+// expressionNode method constructs an expression node in the Abstract Syntax Tree (AST) from the collect expression
+func (c *CollectExpression) expressionNode() {}
+
+// TokenLiteral returns the actual value of the collect expression
+func (c *CollectExpression) TokenLiteral() string {
+	return c.Token.Literal
+}
+
+// String returns a string representation of a CollectExpression node
+func (c *CollectExpression) String() string {
+	return "collect " + c.Loop.String()
+}
+
+// TryExpression represents a block whose runtime error is handled by a catch block instead of stopping the program
+// e.g. try { 1 / 0 } catch (e) { puts(e) }
+// It fulfils the Expression interface by implementing expressionNode() method
+// It by extension fulfills the Node interface which is part of the Expression interface
+// by implementing TokenLiteral() and String() methods from the Node interface
+type TryExpression struct {
+	// Token represents the try token
+	Token token.Token
+
+	// Block represents the block statement that may fail
+	Block *BlockStatement
+
+	// Parameter represents the identifier bound to the error message in the catch block
+	Parameter *Identifier
+
+	// Catch represents the block statement that is executed when Block fails
+	Catch *BlockStatement
+}
+
+// expressionNode method constructs an expression node in the Abstract Syntax Tree (AST) from the try expression
+func (t *TryExpression) expressionNode() {}
+
+// TokenLiteral returns the actual value of the try expression
+func (t *TryExpression) TokenLiteral() string {
+	return t.Token.Literal
+}
+
+// String returns a string representation of a TryExpression node
+func (t *TryExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("try ")
+	out.WriteString(t.Block.String())
+	out.WriteString(" catch (")
+	out.WriteString(t.Parameter.String())
+	out.WriteString(") ")
+	out.WriteString(t.Catch.String())
+
 	return out.String()
 }
 
@@ -397,8 +657,15 @@ type FunctionLiteral struct {
 	// Parameters represents the parameters of the function
 	Parameters []*Identifier
 
+	// Defaults holds the default value of every parameter in the same order as Parameters
+	// the entry of a parameter without a default is nil, the slice is nil when no parameter has a default
+	Defaults []Expression
+
 	// Body represents the body of the function
 	Body *BlockStatement
+
+	// Generator is set when the body yields, calling the function then gives an iterator over the yielded values
+	Generator bool
 }
 
 // expressionNode method constructs an expression node in the Abstract Syntax Tree (AST) from the function literal
@@ -415,7 +682,11 @@ func (f *FunctionLiteral) String() string {
 
 	params := []string{}
 
-	for _, param := range f.Parameters {
+	for i, param := range f.Parameters {
+		if f.Defaults != nil && f.Defaults[i] != nil {
+			params = append(params, param.String()+" = "+f.Defaults[i].String())
+			continue
+		}
 		params = append(params, param.String())
 	}
 
@@ -499,6 +770,32 @@ func (s *StringLiteral) String() string {
 	return s.Token.Literal
 }
 
+// SpreadExpression represents an array that is expanded into the arguments of a call or the elements of an array literal
+// e.g. the ...args in f(...args)
+// It fulfils the Expression interface by implementing expressionNode() method
+// It by extension fulfills the Node interface which is part of the Expression interface
+// by implementing TokenLiteral() and String() methods from the Node interface
+type SpreadExpression struct {
+	// Token represents the ... token
+	Token token.Token
+
+	// Value represents the expression whose elements are spread
+	Value Expression
+}
+
+// expressionNode method constructs an expression node in the Abstract Syntax Tree (AST) from the spread expression
+func (s *SpreadExpression) expressionNode() {}
+
+// TokenLiteral returns the actual value of the spread expression
+func (s *SpreadExpression) TokenLiteral() string {
+	return s.Token.Literal
+}
+
+// String returns a string representation of a SpreadExpression node
+func (s *SpreadExpression) String() string {
+	return "..." + s.Value.String()
+}
+
 // ArrayLiteral returns an array literal representation which can support any value including functions
 // It fulfils the Expression interface by implementing expressionNode() method
 // It by extension fulfills the Node interface which is part of the Expression interface
@@ -582,6 +879,9 @@ type HashLiteral struct {
 
 	// Pairs represents the pairs of the hash literal which are both expressions
 	Pairs map[Expression]Expression
+
+	// Keys holds the keys of Pairs in the order they appear in the source
+	Keys []Expression
 }
 
 // expressionNode method constructs an expression node in the Abstract Syntax Tree (AST) from the hash literal
@@ -598,8 +898,8 @@ func (h *HashLiteral) String() string {
 
 	pairs := []string{}
 
-	for key, value := range h.Pairs {
-		pairs = append(pairs, key.String()+":"+value.String())
+	for _, key := range h.Keys {
+		pairs = append(pairs, key.String()+":"+h.Pairs[key].String())
 	}
 
 	out.WriteString("{")
@@ -608,3 +908,39 @@ func (h *HashLiteral) String() string {
 
 	return out.String()
 }
+
+// AssignExpression represents the assignment of a new value to an existing binding or to an index e.g. x = 5 or a[0] = 5
+// It fulfils the Expression interface by implementing expressionNode() method
+// It by extension fulfills the Node interface which is part of the Expression interface
+// by implementing TokenLiteral() and String() methods from the Node interface
+type AssignExpression struct {
+	// Token represents the = token
+	Token token.Token
+
+	// Target represents what is being assigned to, an identifier or an index expression
+	Target Expression
+
+	// Value represents the expression whose result is assigned
+	Value Expression
+}
+
+// expressionNode method constructs an expression node in the Abstract Syntax Tree (AST) from the assign expression
+func (a *AssignExpression) expressionNode() {}
+
+// TokenLiteral returns the actual value of the assign expression
+func (a *AssignExpression) TokenLiteral() string {
+	return a.Token.Literal
+}
+
+// String returns a string representation of an AssignExpression node
+func (a *AssignExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(a.Target.String())
+	out.WriteString(" = ")
+	out.WriteString(a.Value.String())
+	out.WriteString(")")
+
+	return out.String()
+}
@@ -0,0 +1,240 @@
+/*
+* Package code defines the bytecode instructions understood by the jaba virtual machine.
+* An instruction is a one byte opcode followed by zero or more operands encoded in big endian.
+*
+* Example:
+* OpConstant 65534 is encoded as
+* [OpConstant, 255, 254]
+ */
+package code
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// Instructions is a flat list of encoded bytecode instructions
+type Instructions []byte
+
+// String returns a human readable listing of the instructions, one instruction per line prefixed by its offset
+func (ins Instructions) String() string {
+	var out bytes.Buffer
+
+	i := 0
+	for i < len(ins) {
+		definition, err := Lookup(ins[i])
+		if err != nil {
+			fmt.Fprintf(&out, "ERROR: %s\n", err)
+			i++
+			continue
+		}
+
+		operands, read := ReadOperands(definition, ins[i+1:])
+
+		fmt.Fprintf(&out, "%04d %s\n", i, ins.formatInstruction(definition, operands))
+
+		i += 1 + read
+	}
+
+	return out.String()
+}
+
+// formatInstruction returns an instruction with its operands as a string
+func (ins Instructions) formatInstruction(definition *Definition, operands []int) string {
+	operandCount := len(definition.OperandWidths)
+
+	if len(operands) != operandCount {
+		return fmt.Sprintf("ERROR: operand len %d does not match defined %d\n", len(operands), operandCount)
+	}
+
+	switch operandCount {
+	case 0:
+		return definition.Name
+	case 1:
+		return fmt.Sprintf("%s %d", definition.Name, operands[0])
+	}
+
+	return fmt.Sprintf("ERROR: unhandled operandCount for %s\n", definition.Name)
+}
+
+// Opcode is the first byte of every instruction and tells the virtual machine what to do
+type Opcode byte
+
+const (
+	// OpConstant pushes the constant at the given index of the constant pool onto the stack
+	OpConstant Opcode = iota
+
+	// OpPop removes the topmost element from the stack
+	OpPop
+
+	// OpAdd pops two integers and pushes their sum
+	OpAdd
+
+	// OpSub pops two integers and pushes their difference
+	OpSub
+
+	// OpMul pops two integers and pushes their product
+	OpMul
+
+	// OpDiv pops two integers and pushes their quotient
+	OpDiv
+
+	// OpTrue pushes true onto the stack
+	OpTrue
+
+	// OpFalse pushes false onto the stack
+	OpFalse
+
+	// OpEqual pops two objects and pushes whether they are equal
+	OpEqual
+
+	// OpNotEqual pops two objects and pushes whether they are not equal
+	OpNotEqual
+
+	// OpGreaterThan pops two integers and pushes whether the first is greater than the second.
+	// a < b is compiled as b > a so there is no less than opcode
+	OpGreaterThan
+
+	// OpMinus negates the integer on top of the stack
+	OpMinus
+
+	// OpBang negates the truthiness of the object on top of the stack
+	OpBang
+
+	// OpJumpNotTruthy pops the stack and jumps to the given offset if the popped object is not truthy
+	OpJumpNotTruthy
+
+	// OpJump jumps to the given offset
+	OpJump
+
+	// OpNull pushes null onto the stack
+	OpNull
+
+	// OpGetGlobal pushes the global binding with the given index onto the stack
+	OpGetGlobal
+
+	// OpSetGlobal pops the stack into the global binding with the given index
+	OpSetGlobal
+
+	// OpGetLocal pushes the local binding with the given index onto the stack
+	OpGetLocal
+
+	// OpSetLocal pops the stack into the local binding with the given index
+	OpSetLocal
+
+	// OpCall calls the function below the given number of arguments on the stack
+	OpCall
+
+	// OpReturnValue returns from the current function with the value on top of the stack
+	OpReturnValue
+
+	// OpReturn returns from the current function without a value
+	OpReturn
+)
+
+// Definition describes an opcode, its readable name and the width in bytes of each operand
+type Definition struct {
+	// Name is the readable name of the opcode
+	Name string
+
+	// OperandWidths holds the number of bytes each operand takes
+	OperandWidths []int
+}
+
+// definitions maps every opcode to its definition
+var definitions = map[Opcode]*Definition{
+	OpConstant:      {"OpConstant", []int{2}},
+	OpPop:           {"OpPop", []int{}},
+	OpAdd:           {"OpAdd", []int{}},
+	OpSub:           {"OpSub", []int{}},
+	OpMul:           {"OpMul", []int{}},
+	OpDiv:           {"OpDiv", []int{}},
+	OpTrue:          {"OpTrue", []int{}},
+	OpFalse:         {"OpFalse", []int{}},
+	OpEqual:         {"OpEqual", []int{}},
+	OpNotEqual:      {"OpNotEqual", []int{}},
+	OpGreaterThan:   {"OpGreaterThan", []int{}},
+	OpMinus:         {"OpMinus", []int{}},
+	OpBang:          {"OpBang", []int{}},
+	OpJumpNotTruthy: {"OpJumpNotTruthy", []int{2}},
+	OpJump:          {"OpJump", []int{2}},
+	OpNull:          {"OpNull", []int{}},
+	OpGetGlobal:     {"OpGetGlobal", []int{2}},
+	OpSetGlobal:     {"OpSetGlobal", []int{2}},
+	OpGetLocal:      {"OpGetLocal", []int{1}},
+	OpSetLocal:      {"OpSetLocal", []int{1}},
+	OpCall:          {"OpCall", []int{1}},
+	OpReturnValue:   {"OpReturnValue", []int{}},
+	OpReturn:        {"OpReturn", []int{}},
+}
+
+// Lookup returns the definition of an opcode or an error if the opcode is not defined
+func Lookup(op byte) (*Definition, error) {
+	definition, ok := definitions[Opcode(op)]
+	if !ok {
+		return nil, fmt.Errorf("opcode %d undefined", op)
+	}
+
+	return definition, nil
+}
+
+// Make encodes an opcode and its operands into an instruction
+// it returns an empty instruction if the opcode is not defined
+func Make(op Opcode, operands ...int) []byte {
+	definition, ok := definitions[op]
+	if !ok {
+		return []byte{}
+	}
+
+	instructionLength := 1
+	for _, width := range definition.OperandWidths {
+		instructionLength += width
+	}
+
+	instruction := make([]byte, instructionLength)
+	instruction[0] = byte(op)
+
+	offset := 1
+	for i, operand := range operands {
+		width := definition.OperandWidths[i]
+		switch width {
+		case 2:
+			binary.BigEndian.PutUint16(instruction[offset:], uint16(operand))
+		case 1:
+			instruction[offset] = byte(operand)
+		}
+		offset += width
+	}
+
+	return instruction
+}
+
+// ReadOperands decodes the operands of an instruction and returns them with the number of bytes read
+func ReadOperands(definition *Definition, ins Instructions) ([]int, int) {
+	operands := make([]int, len(definition.OperandWidths))
+	offset := 0
+
+	for i, width := range definition.OperandWidths {
+		switch width {
+		case 2:
+			operands[i] = int(ReadUint16(ins[offset:]))
+		case 1:
+			operands[i] = int(ReadUint8(ins[offset:]))
+		}
+
+		offset += width
+	}
+
+	return operands, offset
+}
+
+// ReadUint16 decodes a two byte operand
+func ReadUint16(ins Instructions) uint16 {
+	return binary.BigEndian.Uint16(ins)
+}
+
+// ReadUint8 decodes a one byte operand
+func ReadUint8(ins Instructions) uint8 {
+	return uint8(ins[0])
+}
@@ -0,0 +1,444 @@
+/*
+* Package compiler turns the AST into bytecode that can be executed by the jaba virtual machine.
+* It walks the tree once, emitting instructions into the current compilation scope and
+* collecting literals into a constant pool that the instructions refer to by index.
+*
+* The compiler currently supports integers, booleans, prefix and infix expressions,
+* conditionals, let bindings, functions, calls and returns.
+ */
+package compiler
+
+import (
+	"fmt"
+
+	"github.com/maxwellgithinji/jaba/pkg/ast"
+	"github.com/maxwellgithinji/jaba/pkg/code"
+	"github.com/maxwellgithinji/jaba/pkg/object"
+)
+
+// Compiler holds the state needed while turning the AST into bytecode
+type Compiler struct {
+	// constants is the constant pool, instructions refer to its entries by index
+	constants []object.Object
+
+	// constantIndexes maps the hash key of a hashable literal to its slot in the constant pool
+	// so repeated literals share a single slot
+	constantIndexes map[object.HashKey]int
+
+	// symbolTable keeps track of the bindings of the scope being compiled
+	symbolTable *SymbolTable
+
+	// scopes holds one compilation scope per function being compiled, the first one is the main program
+	scopes []CompilationScope
+
+	// scopeIndex is the index of the scope being compiled
+	scopeIndex int
+}
+
+// CompilationScope holds the instructions of a function body or of the main program
+type CompilationScope struct {
+	// instructions holds the bytecode emitted so far
+	instructions code.Instructions
+
+	// lastInstruction is the last instruction emitted
+	lastInstruction EmittedInstruction
+
+	// previousInstruction is the instruction emitted before the last one
+	previousInstruction EmittedInstruction
+}
+
+// EmittedInstruction remembers an emitted opcode and where it was emitted
+type EmittedInstruction struct {
+	// Opcode is the opcode of the instruction
+	Opcode code.Opcode
+
+	// Position is the offset of the instruction in the instructions of its scope
+	Position int
+}
+
+// Bytecode is the output of the compiler which is handed to the virtual machine
+type Bytecode struct {
+	// Instructions holds the bytecode of the main program
+	Instructions code.Instructions
+
+	// Constants holds the constant pool
+	Constants []object.Object
+}
+
+// New returns a new compiler with an empty main scope
+func New() *Compiler {
+	return &Compiler{
+		constants:       []object.Object{},
+		constantIndexes: make(map[object.HashKey]int),
+		symbolTable:     NewSymbolTable(),
+		scopes:          []CompilationScope{{instructions: code.Instructions{}}},
+		scopeIndex:      0,
+	}
+}
+
+// Bytecode returns the instructions of the main program and the constant pool
+func (c *Compiler) Bytecode() *Bytecode {
+	return &Bytecode{
+		Instructions: c.currentInstructions(),
+		Constants:    c.constants,
+	}
+}
+
+// Compile is a recursive function that emits the bytecode for a node and its children
+func (c *Compiler) Compile(node ast.Node) error {
+	switch node := node.(type) {
+	// Statements
+	case *ast.Program:
+		for _, statement := range node.Statements {
+			if err := c.Compile(statement); err != nil {
+				return err
+			}
+		}
+
+	case *ast.ExpressionStatement:
+		if err := c.Compile(node.Value); err != nil {
+			return err
+		}
+		c.emit(code.OpPop)
+
+	case *ast.BlockStatement:
+		for _, statement := range node.Statements {
+			if err := c.Compile(statement); err != nil {
+				return err
+			}
+		}
+
+	case *ast.LetStatement:
+		// a function is defined before its body is compiled so it can call itself,
+		// any other value is compiled first so it cannot read the name it is about to be bound to
+		_, isFunction := node.Value.(*ast.FunctionLiteral)
+
+		var symbol Symbol
+		if isFunction {
+			symbol = c.symbolTable.Define(node.Name.Value)
+		}
+
+		if err := c.Compile(node.Value); err != nil {
+			return err
+		}
+
+		if !isFunction {
+			symbol = c.symbolTable.Define(node.Name.Value)
+		}
+
+		if symbol.Scope == GlobalScope {
+			c.emit(code.OpSetGlobal, symbol.Index)
+		} else {
+			c.emit(code.OpSetLocal, symbol.Index)
+		}
+
+	case *ast.ReturnStatement:
+		if c.scopeIndex == 0 {
+			return fmt.Errorf("return outside function is not supported by the vm")
+		}
+
+		if err := c.Compile(node.Value); err != nil {
+			return err
+		}
+		c.emit(code.OpReturnValue)
+
+	// Expressions
+	case *ast.IntegerLiteral:
+		integer := &object.Integer{Value: node.Value}
+		c.emit(code.OpConstant, c.addConstant(integer))
+
+	case *ast.Boolean:
+		if node.Value {
+			c.emit(code.OpTrue)
+		} else {
+			c.emit(code.OpFalse)
+		}
+
+	case *ast.PrefixExpression:
+		if err := c.Compile(node.Right); err != nil {
+			return err
+		}
+
+		switch node.Operator {
+		case "!":
+			c.emit(code.OpBang)
+		case "-":
+			c.emit(code.OpMinus)
+		default:
+			return fmt.Errorf("unknown operator %s", node.Operator)
+		}
+
+	case *ast.InfixExpression:
+		return c.compileInfixExpression(node)
+
+	case *ast.IfExpression:
+		return c.compileIfExpression(node)
+
+	case *ast.Identifier:
+		symbol, ok := c.symbolTable.Resolve(node.Value)
+		if !ok {
+			return fmt.Errorf("identifier not found: %s", node.Value)
+		}
+
+		switch symbol.Scope {
+		case GlobalScope:
+			c.emit(code.OpGetGlobal, symbol.Index)
+		case LocalScope:
+			c.emit(code.OpGetLocal, symbol.Index)
+		default:
+			return fmt.Errorf("closures are not supported by the vm yet, cannot reach %s", node.Value)
+		}
+
+	case *ast.FunctionLiteral:
+		return c.compileFunctionLiteral(node)
+
+	case *ast.CallExpression:
+		if err := c.Compile(node.Function); err != nil {
+			return err
+		}
+
+		for _, argument := range node.Arguments {
+			if err := c.Compile(argument); err != nil {
+				return err
+			}
+		}
+
+		c.emit(code.OpCall, len(node.Arguments))
+
+	default:
+		return fmt.Errorf("%T is not supported by the vm yet", node)
+	}
+
+	return nil
+}
+
+// compileInfixExpression emits the bytecode for an infix expression
+// a < b is compiled as b > a so the virtual machine only needs to know about greater than
+func (c *Compiler) compileInfixExpression(node *ast.InfixExpression) error {
+	if node.Operator == "<" {
+		if err := c.Compile(node.Right); err != nil {
+			return err
+		}
+		if err := c.Compile(node.Left); err != nil {
+			return err
+		}
+		c.emit(code.OpGreaterThan)
+		return nil
+	}
+
+	if err := c.Compile(node.Left); err != nil {
+		return err
+	}
+	if err := c.Compile(node.Right); err != nil {
+		return err
+	}
+
+	switch node.Operator {
+	case "+":
+		c.emit(code.OpAdd)
+	case "-":
+		c.emit(code.OpSub)
+	case "*":
+		c.emit(code.OpMul)
+	case "/":
+		c.emit(code.OpDiv)
+	case ">":
+		c.emit(code.OpGreaterThan)
+	case "==":
+		c.emit(code.OpEqual)
+	case "!=":
+		c.emit(code.OpNotEqual)
+	default:
+		return fmt.Errorf("unknown operator %s", node.Operator)
+	}
+
+	return nil
+}
+
+// compileIfExpression emits the bytecode for a conditional.
+// The jump offsets are not known until the branches are compiled so placeholders are emitted and patched afterwards
+func (c *Compiler) compileIfExpression(node *ast.IfExpression) error {
+	if err := c.Compile(node.Condition); err != nil {
+		return err
+	}
+
+	jumpNotTruthyPosition := c.emit(code.OpJumpNotTruthy, 9999)
+
+	if err := c.compileBranch(node.Consequence); err != nil {
+		return err
+	}
+
+	jumpPosition := c.emit(code.OpJump, 9999)
+
+	c.changeOperand(jumpNotTruthyPosition, len(c.currentInstructions()))
+
+	switch {
+	case node.Alternative != nil:
+		if err := c.compileBranch(node.Alternative); err != nil {
+			return err
+		}
+
+	case node.ElseIf != nil:
+		// the chained if expression leaves its own value on the stack
+		if err := c.compileIfExpression(node.ElseIf); err != nil {
+			return err
+		}
+
+	default:
+		c.emit(code.OpNull)
+	}
+
+	c.changeOperand(jumpPosition, len(c.currentInstructions()))
+
+	return nil
+}
+
+// compileBranch emits a block of a conditional so that it leaves its value on the stack
+func (c *Compiler) compileBranch(block *ast.BlockStatement) error {
+	if err := c.Compile(block); err != nil {
+		return err
+	}
+
+	if c.lastInstructionIs(code.OpPop) {
+		c.removeLastInstruction()
+	} else {
+		// the block ended with a statement that has no value, e.g. a let statement or nothing at all
+		c.emit(code.OpNull)
+	}
+
+	return nil
+}
+
+// compileFunctionLiteral compiles the function body in its own scope and adds the result to the constant pool
+func (c *Compiler) compileFunctionLiteral(node *ast.FunctionLiteral) error {
+	if node.Defaults != nil {
+		return fmt.Errorf("default parameter values are not supported by the vm yet")
+	}
+
+	if node.Generator {
+		return fmt.Errorf("generators are not supported by the vm yet")
+	}
+
+	c.enterScope()
+
+	for _, param := range node.Parameters {
+		c.symbolTable.Define(param.Value)
+	}
+
+	if err := c.Compile(node.Body); err != nil {
+		return err
+	}
+
+	// the value of the last expression is the implicit return value
+	if c.lastInstructionIs(code.OpPop) {
+		lastPosition := c.scopes[c.scopeIndex].lastInstruction.Position
+		c.replaceInstruction(lastPosition, code.Make(code.OpReturnValue))
+		c.scopes[c.scopeIndex].lastInstruction.Opcode = code.OpReturnValue
+	}
+
+	if !c.lastInstructionIs(code.OpReturnValue) {
+		c.emit(code.OpReturn)
+	}
+
+	numLocals := c.symbolTable.numDefinitions
+	instructions := c.leaveScope()
+
+	compiledFunction := &object.CompiledFunction{
+		Instructions:  instructions,
+		NumLocals:     numLocals,
+		NumParameters: len(node.Parameters),
+	}
+
+	c.emit(code.OpConstant, c.addConstant(compiledFunction))
+
+	return nil
+}
+
+// addConstant adds an object to the constant pool and returns its index
+// integers, strings and booleans are compared by value, an equal literal that is already in the pool reuses its index
+func (c *Compiler) addConstant(obj object.Object) int {
+	hashable, ok := obj.(object.Hashable)
+	if !ok {
+		c.constants = append(c.constants, obj)
+		return len(c.constants) - 1
+	}
+
+	key := hashable.HashKey()
+	if index, ok := c.constantIndexes[key]; ok {
+		return index
+	}
+
+	c.constants = append(c.constants, obj)
+	c.constantIndexes[key] = len(c.constants) - 1
+
+	return len(c.constants) - 1
+}
+
+// emit adds an instruction to the current scope and returns its position
+func (c *Compiler) emit(op code.Opcode, operands ...int) int {
+	instruction := code.Make(op, operands...)
+
+	position := len(c.currentInstructions())
+	c.scopes[c.scopeIndex].instructions = append(c.currentInstructions(), instruction...)
+
+	c.scopes[c.scopeIndex].previousInstruction = c.scopes[c.scopeIndex].lastInstruction
+	c.scopes[c.scopeIndex].lastInstruction = EmittedInstruction{Opcode: op, Position: position}
+
+	return position
+}
+
+// currentInstructions returns the instructions of the scope being compiled
+func (c *Compiler) currentInstructions() code.Instructions {
+	return c.scopes[c.scopeIndex].instructions
+}
+
+// lastInstructionIs checks the opcode of the last emitted instruction
+func (c *Compiler) lastInstructionIs(op code.Opcode) bool {
+	if len(c.currentInstructions()) == 0 {
+		return false
+	}
+
+	return c.scopes[c.scopeIndex].lastInstruction.Opcode == op
+}
+
+// removeLastInstruction drops the last emitted instruction
+func (c *Compiler) removeLastInstruction() {
+	last := c.scopes[c.scopeIndex].lastInstruction
+	previous := c.scopes[c.scopeIndex].previousInstruction
+
+	c.scopes[c.scopeIndex].instructions = c.currentInstructions()[:last.Position]
+	c.scopes[c.scopeIndex].lastInstruction = previous
+}
+
+// replaceInstruction overwrites the instruction at the given position, the new instruction must have the same width
+func (c *Compiler) replaceInstruction(position int, instruction []byte) {
+	instructions := c.currentInstructions()
+
+	for i := 0; i < len(instruction); i++ {
+		instructions[position+i] = instruction[i]
+	}
+}
+
+// changeOperand re-encodes the instruction at the given position with a new operand
+func (c *Compiler) changeOperand(position int, operand int) {
+	op := code.Opcode(c.currentInstructions()[position])
+	c.replaceInstruction(position, code.Make(op, operand))
+}
+
+// enterScope starts compiling a function body
+func (c *Compiler) enterScope() {
+	c.scopes = append(c.scopes, CompilationScope{instructions: code.Instructions{}})
+	c.scopeIndex++
+	c.symbolTable = NewEnclosedSymbolTable(c.symbolTable)
+}
+
+// leaveScope finishes compiling a function body and returns its instructions
+func (c *Compiler) leaveScope() code.Instructions {
+	instructions := c.currentInstructions()
+
+	c.scopes = c.scopes[:len(c.scopes)-1]
+	c.scopeIndex--
+	c.symbolTable = c.symbolTable.outer
+
+	return instructions
+}
@@ -0,0 +1,86 @@
+package compiler
+
+// SymbolScope tells the compiler where a binding lives
+type SymbolScope string
+
+const (
+	// GlobalScope is used for bindings made at the top level of the program
+	GlobalScope SymbolScope = "GLOBAL"
+
+	// LocalScope is used for parameters and bindings made inside a function body
+	LocalScope SymbolScope = "LOCAL"
+
+	// FreeScope is used for local bindings of an enclosing function, which would need a closure to be reached
+	FreeScope SymbolScope = "FREE"
+)
+
+// Symbol holds what the compiler needs to know about a binding
+type Symbol struct {
+	// Name is the identifier of the binding
+	Name string
+
+	// Scope is where the binding lives
+	Scope SymbolScope
+
+	// Index is the slot of the binding in its scope
+	Index int
+}
+
+// SymbolTable associates identifiers with symbols.
+// every function body gets its own table which is enclosed by the table of the surrounding scope
+type SymbolTable struct {
+	// outer is the table of the surrounding scope, it is nil for the global table
+	outer *SymbolTable
+
+	// store holds the symbols defined in this scope
+	store map[string]Symbol
+
+	// numDefinitions is the number of symbols defined in this scope
+	numDefinitions int
+}
+
+// NewSymbolTable creates a new global symbol table
+func NewSymbolTable() *SymbolTable {
+	return &SymbolTable{store: make(map[string]Symbol)}
+}
+
+// NewEnclosedSymbolTable creates a symbol table for a function body
+func NewEnclosedSymbolTable(outer *SymbolTable) *SymbolTable {
+	table := NewSymbolTable()
+	table.outer = outer
+	return table
+}
+
+// Define creates a symbol for the identifier in the current scope
+// redefining an identifier in the same scope reuses its slot
+func (s *SymbolTable) Define(name string) Symbol {
+	if symbol, ok := s.store[name]; ok {
+		return symbol
+	}
+
+	symbol := Symbol{Name: name, Index: s.numDefinitions, Scope: LocalScope}
+	if s.outer == nil {
+		symbol.Scope = GlobalScope
+	}
+
+	s.store[name] = symbol
+	s.numDefinitions++
+
+	return symbol
+}
+
+// Resolve looks up the symbol of an identifier in the current and the enclosing scopes
+// local bindings of an enclosing function are returned with the FreeScope
+func (s *SymbolTable) Resolve(name string) (Symbol, bool) {
+	symbol, ok := s.store[name]
+	if ok || s.outer == nil {
+		return symbol, ok
+	}
+
+	symbol, ok = s.outer.Resolve(name)
+	if ok && symbol.Scope != GlobalScope {
+		symbol.Scope = FreeScope
+	}
+
+	return symbol, ok
+}
@@ -4,46 +4,124 @@
 package evaluator
 
 import (
+	"bufio"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/maxwellgithinji/jaba/pkg/object"
 )
 
+// output is where puts writes to, it is the standard output unless it is changed with SetOutput
+var output io.Writer = os.Stdout
+
+// SetOutput changes where puts writes to, e.g. the out writer of the REPL or a buffer in tests
+func SetOutput(w io.Writer) {
+	output = w
+}
+
+// input is where input reads lines from, it is the standard input unless it is changed with SetInput
+// it is buffered once so the text read past a line is kept for the next call
+var input = bufio.NewReader(os.Stdin)
+
+// SetInput changes where input reads lines from, e.g. a strings.Reader in tests.
+// A bufio.Reader is used as it is so a caller that reads from it too, like the REPL, shares its buffer with input
+func SetInput(r io.Reader) {
+	if reader, ok := r.(*bufio.Reader); ok {
+		input = reader
+		return
+	}
+	input = bufio.NewReader(r)
+}
+
+// maxPowBits is the size in bits of the largest result pow is allowed to produce
+const maxPowBits = 1 << 20
+
+// fileAccess allows read_file and write_file to touch the filesystem
+// it is off by default so a program given to an embedder can not read or change files unless the embedder allows it
+var fileAccess bool
+
+// SetFileAccess turns the filesystem builtins read_file and write_file on or off
+func SetFileAccess(enabled bool) {
+	fileAccess = enabled
+}
+
+// random is the generator behind rand, seed replaces it so programs can produce the same values on every run
+var random = rand.New(rand.NewSource(time.Now().UnixNano()))
+
 // builtins is a hashmap to keep track of the variables during program execution
 var builtins = map[string]*object.Builtin{
 	"len": {
+		Doc: "len(x) returns the number of elements of an array, characters of a string or pairs of a hash",
 		Function: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newError("wrong number of arguments. got: %d want: %d", len(args), 1)
+				return builtinError("len", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d", len(args), 1)
 			}
 
 			switch arg := args[0].(type) {
 			case *object.Array:
-				return &object.Integer{Value: int64(len(arg.Elements))}
+				return intObject(int64(len(arg.Elements)))
 
 			case *object.String:
-				return &object.Integer{Value: int64(len(arg.Value))}
+				// strings are measured in characters, byte_len gives the size in bytes
+				return intObject(int64(utf8.RuneCountInString(arg.Value)))
+
+			case *object.Hash:
+				return intObject(int64(len(arg.Pairs)))
 
 			default:
-				return newError("argument to len not supported, got: %s", args[0].Type())
+				return newTypedError(object.TYPE_ERROR, "argument to len not supported, got: %s", args[0].Type())
 
 			}
 		},
 	},
-	"first": {
+	"byte_len": {
+		Doc: "byte_len(s) returns the number of bytes in the utf-8 encoding of a string",
 		Function: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newError("wrong number of arguments. got: %d want: %d", len(args), 1)
+				return builtinError("byte_len", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d", len(args), 1)
+			}
+
+			str, ok := args[0].(*object.String)
+			if !ok {
+				return newTypedError(object.TYPE_ERROR, "argument to byte_len must be a string, got: %s", args[0].Type())
 			}
 
-			if args[0].Type() != object.ARRAY_OBJECT {
-				return newError("argument to first must be an array, got: %s", args[0].Type())
+			return intObject(int64(len(str.Value)))
+		},
+	},
+	"first": {
+		Doc: "first(x) returns the first element of an array or the first character of a string, null when it is empty",
+		Function: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return builtinError("first", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d", len(args), 1)
 			}
 
-			array := args[0].(*object.Array)
+			switch arg := args[0].(type) {
+			case *object.Array:
+				if len(arg.Elements) > 0 {
+					return arg.Elements[0]
+				}
+
+			case *object.String:
+				if len(arg.Value) > 0 {
+					_, size := utf8.DecodeRuneInString(arg.Value)
+					return &object.String{Value: arg.Value[:size]}
+				}
 
-			if len(array.Elements) > 0 {
-				return array.Elements[0]
+			default:
+				return newTypedError(object.TYPE_ERROR, "argument to first must be an array or string, got: %s", args[0].Type())
 			}
 
 			return NULL
@@ -51,78 +129,1509 @@ var builtins = map[string]*object.Builtin{
 	},
 
 	"last": {
+		Doc: "last(x) returns the last element of an array or the last character of a string, null when it is empty",
 		Function: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newError("wrong number of arguments. got: %d want: %d", len(args), 1)
+				return builtinError("last", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d", len(args), 1)
 			}
 
-			if args[0].Type() != object.ARRAY_OBJECT {
-				return newError("argument to last must be an array, got: %s", args[0].Type())
-			}
+			switch arg := args[0].(type) {
+			case *object.Array:
+				length := len(arg.Elements)
 
-			array := args[0].(*object.Array)
+				if length > 0 {
+					return arg.Elements[length-1]
+				}
 
-			length := len(array.Elements)
+			case *object.String:
+				if len(arg.Value) > 0 {
+					_, size := utf8.DecodeLastRuneInString(arg.Value)
+					return &object.String{Value: arg.Value[len(arg.Value)-size:]}
+				}
 
-			if length > 0 {
-				return array.Elements[length-1]
+			default:
+				return newTypedError(object.TYPE_ERROR, "argument to last must be an array or string, got: %s", args[0].Type())
 			}
 
 			return NULL
 		},
 	},
 	"rest": {
+		Doc: "rest(x) returns a new array or string without the first element, null when it is empty",
 		Function: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newError("wrong number of arguments. got: %d want: %d", len(args), 1)
+				return builtinError("rest", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d", len(args), 1)
 			}
 
-			if args[0].Type() != object.ARRAY_OBJECT {
-				return newError("argument to rest must be an array, got: %s", args[0].Type())
-			}
+			switch arg := args[0].(type) {
+			case *object.Array:
+				length := len(arg.Elements)
 
-			array := args[0].(*object.Array)
+				if length > 0 {
+					newElements := make([]object.Object, length-1)
+					copy(newElements, arg.Elements[1:length])
+					return &object.Array{Elements: newElements}
+				}
 
-			length := len(array.Elements)
+			case *object.String:
+				if len(arg.Value) > 0 {
+					_, size := utf8.DecodeRuneInString(arg.Value)
+					return &object.String{Value: arg.Value[size:]}
+				}
 
-			if length > 0 {
-				newElements := make([]object.Object, length-1)
-				copy(newElements, array.Elements[1:length])
-				return &object.Array{Elements: newElements}
+			default:
+				return newTypedError(object.TYPE_ERROR, "argument to rest must be an array or string, got: %s", args[0].Type())
 			}
 
 			return NULL
 		},
 	},
+	"get_or": {
+		Doc: "get_or(array, index, default) returns the element at index or default when the index is out of range, negative indices count from the end",
+		Function: func(args ...object.Object) object.Object {
+			if len(args) != 3 {
+				return builtinError("get_or", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d", len(args), 3)
+			}
+
+			array, ok := args[0].(*object.Array)
+			if !ok {
+				return newTypedError(object.TYPE_ERROR, "first argument to get_or must be an array, got: %s", args[0].Type())
+			}
+
+			index, ok := args[1].(*object.Integer)
+			if !ok {
+				return newTypedError(object.TYPE_ERROR, "second argument to get_or must be an integer, got: %s", args[1].Type())
+			}
+
+			length := int64(len(array.Elements))
+			position := index.Value
+
+			// negative indices count from the end of the array, -1 is the last element
+			if position < 0 {
+				position += length
+			}
+
+			if position < 0 || position >= length {
+				return args[2]
+			}
+
+			return array.Elements[position]
+		},
+	},
 	"push": {
+		Doc: "push(x, value) returns a new array with value appended, or a new string with a string appended",
 		Function: func(args ...object.Object) object.Object {
 			if len(args) != 2 {
-				return newError("wrong number of arguments. got: %d want: %d", len(args), 2)
+				return builtinError("push", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d", len(args), 2)
 			}
 
-			if args[0].Type() != object.ARRAY_OBJECT {
-				return newError("argument to push must be an array, got: %s", args[0].Type())
+			switch arg := args[0].(type) {
+			case *object.Array:
+				length := len(arg.Elements)
+
+				newElements := make([]object.Object, length+1)
+
+				copy(newElements, arg.Elements)
+
+				newElements[length] = args[1]
+
+				return &object.Array{Elements: newElements}
+
+			case *object.String:
+				suffix, ok := args[1].(*object.String)
+				if !ok {
+					return newTypedError(object.TYPE_ERROR, "second argument to push must be a string when pushing onto a string, got: %s", args[1].Type())
+				}
+
+				if err := checkStringLength(len(arg.Value) + len(suffix.Value)); err != nil {
+					return err
+				}
+
+				return &object.String{Value: arg.Value + suffix.Value}
+
+			default:
+				return newTypedError(object.TYPE_ERROR, "argument to push must be an array or string, got: %s", args[0].Type())
+			}
+		},
+	},
+	"pop": {
+		Doc: "pop(array) returns [last element, the rest of the array], null when the array is empty",
+		Function: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return builtinError("pop", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d", len(args), 1)
 			}
 
-			array := args[0].(*object.Array)
+			array, ok := args[0].(*object.Array)
+			if !ok {
+				return newTypedError(object.TYPE_ERROR, "argument to pop must be an array, got: %s", args[0].Type())
+			}
 
 			length := len(array.Elements)
 
-			newElements := make([]object.Object, length+1)
+			if length == 0 {
+				return NULL
+			}
+
+			// arrays are immutable so the rest is a new array like the one rest returns
+			newElements := make([]object.Object, length-1)
+			copy(newElements, array.Elements[:length-1])
+
+			return &object.Array{Elements: []object.Object{array.Elements[length-1], &object.Array{Elements: newElements}}}
+		},
+	},
+	"zfill": {
+		Doc: "zfill(n, width) returns n as a string padded with zeros to width characters",
+		Function: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return builtinError("zfill", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d", len(args), 2)
+			}
+
+			number, ok := args[0].(*object.Integer)
+			if !ok {
+				return newTypedError(object.TYPE_ERROR, "first argument to zfill must be an integer, got: %s", args[0].Type())
+			}
+
+			width, ok := args[1].(*object.Integer)
+			if !ok {
+				return newTypedError(object.TYPE_ERROR, "second argument to zfill must be an integer, got: %s", args[1].Type())
+			}
+
+			if width.Value <= 0 {
+				return newTypedError(object.VALUE_ERROR, "width for zfill must be positive, got: %d", width.Value)
+			}
+
+			digits := strconv.FormatInt(number.Value, 10)
+			sign := ""
+
+			// the minus sign counts towards the width but stays in front of the padding
+			if number.Value < 0 {
+				sign = "-"
+				digits = digits[1:]
+			}
 
-			copy(newElements, array.Elements)
+			if width.Value > int64(maxStringLength) {
+				return checkStringLength(int(width.Value))
+			}
 
-			newElements[length] = args[1]
+			padding := int(width.Value) - len(sign) - len(digits)
+			if padding > 0 {
+				digits = strings.Repeat("0", padding) + digits
+			}
 
-			return &object.Array{Elements: newElements}
+			return &object.String{Value: sign + digits}
 		},
 	},
-	"puts": {
+	"repeat": {
+		Doc: "repeat(s, count) returns the string s repeated count times",
 		Function: func(args ...object.Object) object.Object {
-			for _, arg := range args {
-				fmt.Println(arg.Inspect())
+			if len(args) != 2 {
+				return builtinError("repeat", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d", len(args), 2)
 			}
-			return NULL
+
+			str, ok := args[0].(*object.String)
+			if !ok {
+				return newTypedError(object.TYPE_ERROR, "first argument to repeat must be a string, got: %s", args[0].Type())
+			}
+
+			count, ok := args[1].(*object.Integer)
+			if !ok {
+				return newTypedError(object.TYPE_ERROR, "second argument to repeat must be an integer, got: %s", args[1].Type())
+			}
+
+			if count.Value < 0 {
+				return newTypedError(object.VALUE_ERROR, "count for repeat must not be negative, got: %d", count.Value)
+			}
+
+			return repeatString(str.Value, count.Value)
+		},
+	},
+	"windows": {
+		Doc: "windows(array, size) returns every run of size neighbouring elements of the array",
+		Function: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return builtinError("windows", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d", len(args), 2)
+			}
+
+			array, ok := args[0].(*object.Array)
+			if !ok {
+				return newTypedError(object.TYPE_ERROR, "first argument to windows must be an array, got: %s", args[0].Type())
+			}
+
+			size, ok := args[1].(*object.Integer)
+			if !ok {
+				return newTypedError(object.TYPE_ERROR, "second argument to windows must be an integer, got: %s", args[1].Type())
+			}
+
+			if size.Value <= 0 {
+				return newTypedError(object.VALUE_ERROR, "size for windows must be positive, got: %d", size.Value)
+			}
+
+			length := int64(len(array.Elements))
+			if size.Value > length {
+				return &object.Array{Elements: []object.Object{}}
+			}
+
+			windows := make([]object.Object, 0, length-size.Value+1)
+
+			for start := int64(0); start+size.Value <= length; start++ {
+				// every window gets its own elements so assigning to one window does not change its neighbours
+				elements := make([]object.Object, size.Value)
+				copy(elements, array.Elements[start:start+size.Value])
+				windows = append(windows, &object.Array{Elements: elements})
+			}
+
+			return &object.Array{Elements: windows}
+		},
+	},
+	"transpose": {
+		Doc: "transpose(rows) returns the columns of an array of equally long arrays",
+		Function: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return builtinError("transpose", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d", len(args), 1)
+			}
+
+			matrix, ok := args[0].(*object.Array)
+			if !ok {
+				return newTypedError(object.TYPE_ERROR, "argument to transpose must be an array, got: %s", args[0].Type())
+			}
+
+			rows := make([]*object.Array, len(matrix.Elements))
+
+			for i, element := range matrix.Elements {
+				row, ok := element.(*object.Array)
+				if !ok {
+					return newTypedError(object.TYPE_ERROR, "rows of transpose must be arrays, got: %s", element.Type())
+				}
+
+				if i > 0 && len(row.Elements) != len(rows[0].Elements) {
+					return newTypedError(object.VALUE_ERROR, "rows of transpose must have equal lengths, row %d has %d elements want %d", i, len(row.Elements), len(rows[0].Elements))
+				}
+
+				rows[i] = row
+			}
+
+			if len(rows) == 0 {
+				return &object.Array{Elements: []object.Object{}}
+			}
+
+			columns := make([]object.Object, len(rows[0].Elements))
+
+			for j := range columns {
+				column := make([]object.Object, len(rows))
+				for i, row := range rows {
+					column[i] = row.Elements[j]
+				}
+				columns[j] = &object.Array{Elements: column}
+			}
+
+			return &object.Array{Elements: columns}
+		},
+	},
+	"dot": {
+		Doc: "dot(a, b) returns the dot product of two arrays of integers with the same length",
+		Function: func(args ...object.Object) object.Object {
+			left, right, err := integerVectors("dot", args)
+			if err != nil {
+				return err
+			}
+
+			// the sum moves to a big integer once an element is big or a product or the sum itself does not fit in an int64, like + and * do
+			var sum int64
+			var bigSum *big.Int
+			for i := range left {
+				l, leftSmall := left[i].(*object.Integer)
+				r, rightSmall := right[i].(*object.Integer)
+				if leftSmall && rightSmall && bigSum == nil {
+					if product, ok := multiplyIntegers(l.Value, r.Value); ok {
+						if total, ok := addIntegers(sum, product); ok {
+							sum = total
+							continue
+						}
+					}
+				}
+
+				if bigSum == nil {
+					bigSum = big.NewInt(sum)
+				}
+				bigSum.Add(bigSum, new(big.Int).Mul(toBigInt(left[i]), toBigInt(right[i])))
+			}
+
+			if bigSum != nil {
+				return normalizeBigInt(bigSum)
+			}
+
+			return intObject(sum)
+		},
+	},
+	"vadd": {
+		Doc: "vadd(a, b) returns the element wise sum of two arrays of integers with the same length",
+		Function: func(args ...object.Object) object.Object {
+			left, right, err := integerVectors("vadd", args)
+			if err != nil {
+				return err
+			}
+
+			elements := make([]object.Object, len(left))
+			for i := range left {
+				l, leftSmall := left[i].(*object.Integer)
+				r, rightSmall := right[i].(*object.Integer)
+				if leftSmall && rightSmall {
+					if sum, ok := addIntegers(l.Value, r.Value); ok {
+						elements[i] = intObject(sum)
+						continue
+					}
+				}
+				elements[i] = normalizeBigInt(new(big.Int).Add(toBigInt(left[i]), toBigInt(right[i])))
+			}
+
+			return &object.Array{Elements: elements}
+		},
+	},
+	"zip": {
+		Doc: "zip(a, b) returns an array of [a element, b element] pairs, as long as the shorter array",
+		Function: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return builtinError("zip", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d", len(args), 2)
+			}
+
+			left, ok := args[0].(*object.Array)
+			if !ok {
+				return newTypedError(object.TYPE_ERROR, "first argument to zip must be an array, got: %s", args[0].Type())
+			}
+
+			right, ok := args[1].(*object.Array)
+			if !ok {
+				return newTypedError(object.TYPE_ERROR, "second argument to zip must be an array, got: %s", args[1].Type())
+			}
+
+			// the extra elements of the longer array are dropped
+			length := len(left.Elements)
+			if len(right.Elements) < length {
+				length = len(right.Elements)
+			}
+
+			pairs := make([]object.Object, length)
+			for i := range pairs {
+				pairs[i] = &object.Array{Elements: []object.Object{left.Elements[i], right.Elements[i]}}
+			}
+
+			return &object.Array{Elements: pairs}
+		},
+	},
+	"flatten": {
+		Doc: "flatten(array, depth = 1) returns a new array with nested arrays up to depth levels deep spliced in",
+		Function: func(args ...object.Object) object.Object {
+			if len(args) != 1 && len(args) != 2 {
+				return builtinError("flatten", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d or %d", len(args), 1, 2)
+			}
+
+			array, ok := args[0].(*object.Array)
+			if !ok {
+				return newTypedError(object.TYPE_ERROR, "first argument to flatten must be an array, got: %s", args[0].Type())
+			}
+
+			depth := int64(1)
+
+			if len(args) == 2 {
+				integer, ok := args[1].(*object.Integer)
+				if !ok {
+					return newTypedError(object.TYPE_ERROR, "second argument to flatten must be an integer, got: %s", args[1].Type())
+				}
+
+				if integer.Value < 0 {
+					return newTypedError(object.VALUE_ERROR, "depth for flatten must not be negative, got: %d", integer.Value)
+				}
+
+				depth = integer.Value
+			}
+
+			return &object.Array{Elements: flattenElements(array.Elements, depth, map[*object.Array]bool{array: true})}
+		},
+	},
+	"copy": {
+		Doc: "copy(x) returns a deep copy of an array or hash, other values are returned as they are",
+		Function: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return builtinError("copy", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d", len(args), 1)
+			}
+
+			switch args[0].(type) {
+			case *object.Function, *object.Builtin:
+				return newTypedError(object.TYPE_ERROR, "argument to copy not supported, got: %s", args[0].Type())
+			}
+
+			return deepCopy(args[0], map[object.Object]object.Object{})
+		},
+	},
+	"freeze": {
+		Doc: "freeze(x) marks an array or hash as unchangeable and returns it, copy(x) gives a copy that can be changed",
+		Function: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return builtinError("freeze", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d", len(args), 1)
+			}
+
+			switch arg := args[0].(type) {
+			case *object.Array:
+				arg.Frozen = true
+			case *object.Hash:
+				arg.Frozen = true
+			default:
+				return newTypedError(object.TYPE_ERROR, "argument to freeze must be an array or hash, got: %s", args[0].Type())
+			}
+
+			return args[0]
+		},
+	},
+	"keys": {
+		Doc: "keys(hash) returns the keys of a hash in the order they were first set",
+		Function: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return builtinError("keys", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d", len(args), 1)
+			}
+
+			hash, ok := args[0].(*object.Hash)
+			if !ok {
+				return newTypedError(object.TYPE_ERROR, "argument to keys must be a hash, got: %s", args[0].Type())
+			}
+
+			pairs := hash.OrderedPairs()
+			keys := make([]object.Object, len(pairs))
+			for i, pair := range pairs {
+				keys[i] = pair.Key
+			}
+
+			return &object.Array{Elements: keys}
+		},
+	},
+	"keys_sorted": {
+		Doc: "keys_sorted(hash) returns the keys of a hash sorted by their printed form",
+		Function: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return builtinError("keys_sorted", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d", len(args), 1)
+			}
+
+			hash, ok := args[0].(*object.Hash)
+			if !ok {
+				return newTypedError(object.TYPE_ERROR, "argument to keys_sorted must be a hash, got: %s", args[0].Type())
+			}
+
+			pairs := hash.SortedPairs()
+			keys := make([]object.Object, len(pairs))
+			for i, pair := range pairs {
+				keys[i] = pair.Key
+			}
+
+			return &object.Array{Elements: keys}
+		},
+	},
+	"values": {
+		Doc: "values(hash) returns the values of a hash in the order their keys were first set",
+		Function: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return builtinError("values", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d", len(args), 1)
+			}
+
+			hash, ok := args[0].(*object.Hash)
+			if !ok {
+				return newTypedError(object.TYPE_ERROR, "argument to values must be a hash, got: %s", args[0].Type())
+			}
+
+			pairs := hash.OrderedPairs()
+			values := make([]object.Object, len(pairs))
+			for i, pair := range pairs {
+				values[i] = pair.Value
+			}
+
+			return &object.Array{Elements: values}
+		},
+	},
+	"to_array": {
+		Doc: "to_array(hash) returns the pairs of a hash as [key, value] arrays in the order their keys were first set",
+		Function: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return builtinError("to_array", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d", len(args), 1)
+			}
+
+			hash, ok := args[0].(*object.Hash)
+			if !ok {
+				return newTypedError(object.TYPE_ERROR, "argument to to_array must be a hash, got: %s", args[0].Type())
+			}
+
+			pairs := hash.OrderedPairs()
+			entries := make([]object.Object, len(pairs))
+			for i, pair := range pairs {
+				entries[i] = &object.Array{Elements: []object.Object{pair.Key, pair.Value}}
+			}
+
+			return &object.Array{Elements: entries}
+		},
+	},
+	"json_encode": {
+		Doc: "json_encode(x) returns the JSON text of integers, booleans, strings, null and arrays and hashes of them, hash keys must be strings",
+		Function: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return builtinError("json_encode", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d", len(args), 1)
+			}
+
+			var out strings.Builder
+			if err := encodeJSON(&out, args[0], map[object.Object]bool{}); err != nil {
+				return err
+			}
+
+			return &object.String{Value: out.String()}
 		},
 	},
+	"json_decode": {
+		Doc: "json_decode(text) returns the value of a JSON text, objects become hashes with their keys in the order they are written",
+		Function: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return builtinError("json_decode", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d", len(args), 1)
+			}
+
+			text, ok := args[0].(*object.String)
+			if !ok {
+				return newTypedError(object.TYPE_ERROR, "argument to json_decode must be a string, got: %s", args[0].Type())
+			}
+
+			decoder := json.NewDecoder(strings.NewReader(text.Value))
+			decoder.UseNumber()
+
+			value, err := decodeJSON(decoder)
+			if err == nil {
+				// the text must hold a single value
+				if _, err = decoder.Token(); err == io.EOF {
+					return value
+				} else if err == nil {
+					err = errors.New("unexpected data after the value")
+				}
+			}
+
+			return builtinError("json_decode", object.VALUE_ERROR, "invalid JSON: %s", err)
+		},
+	},
+	"from_array": {
+		Doc: "from_array(pairs) builds a hash from [key, value] arrays, a key that comes again keeps its first place and takes the last value",
+		Function: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return builtinError("from_array", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d", len(args), 1)
+			}
+
+			array, ok := args[0].(*object.Array)
+			if !ok {
+				return newTypedError(object.TYPE_ERROR, "argument to from_array must be an array, got: %s", args[0].Type())
+			}
+
+			hash := object.NewHash()
+			for _, element := range array.Elements {
+				entry, ok := element.(*object.Array)
+				if !ok || len(entry.Elements) != 2 {
+					return newTypedError(object.TYPE_ERROR, "elements of from_array must be [key, value] arrays, got: %s", element.Inspect())
+				}
+
+				key, ok := entry.Elements[0].(object.Hashable)
+				if !ok {
+					return newTypedError(object.TYPE_ERROR, "unusable as hash key: %s", entry.Elements[0].Type())
+				}
+
+				hash.Set(key.HashKey(), object.HashPair{Key: entry.Elements[0], Value: entry.Elements[1]})
+			}
+
+			return hash
+		},
+	},
+	"bool": {
+		Doc: "bool(x) returns whether x counts as true in a condition",
+		Function: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return builtinError("bool", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d", len(args), 1)
+			}
+
+			return boolObject(isTruthy(args[0]))
+		},
+	},
+	"assert": {
+		Doc: "assert(condition, message = \"assertion failed\") stops the program with message when condition is not true",
+		Function: func(args ...object.Object) object.Object {
+			if len(args) != 1 && len(args) != 2 {
+				return builtinError("assert", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d or %d", len(args), 1, 2)
+			}
+
+			message := "assertion failed"
+
+			if len(args) == 2 {
+				custom, ok := args[1].(*object.String)
+				if !ok {
+					return newTypedError(object.TYPE_ERROR, "second argument to assert must be a string, got: %s", args[1].Type())
+				}
+				message = custom.Value
+			}
+
+			if !isTruthy(args[0]) {
+				return newError("%s", message)
+			}
+
+			return NULL
+		},
+	},
+	"cwd": {
+		Doc: "cwd() returns the working directory",
+		Function: func(args ...object.Object) object.Object {
+			if len(args) != 0 {
+				return builtinError("cwd", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d", len(args), 0)
+			}
+
+			directory, err := os.Getwd()
+			if err != nil {
+				return newError("could not get the working directory: %s", err)
+			}
+
+			return &object.String{Value: directory}
+		},
+	},
+	"abs_path": {
+		Doc: "abs_path(path) returns the absolute form of a path",
+		Function: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return builtinError("abs_path", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d", len(args), 1)
+			}
+
+			path, ok := args[0].(*object.String)
+			if !ok {
+				return newTypedError(object.TYPE_ERROR, "argument to abs_path must be a string, got: %s", args[0].Type())
+			}
+
+			absolute, err := filepath.Abs(path.Value)
+			if err != nil {
+				return newError("could not resolve %q: %s", path.Value, err)
+			}
+
+			return &object.String{Value: absolute}
+		},
+	},
+	"read_file": {
+		Doc: "read_file(path) returns the contents of a file as a string, it needs file access to be turned on",
+		Function: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return builtinError("read_file", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d", len(args), 1)
+			}
+
+			path, ok := args[0].(*object.String)
+			if !ok {
+				return newTypedError(object.TYPE_ERROR, "argument to read_file must be a string, got: %s", args[0].Type())
+			}
+
+			if !fileAccess {
+				return builtinError("read_file", object.GENERIC_ERROR, "file access is turned off")
+			}
+
+			contents, err := os.ReadFile(path.Value)
+			if err != nil {
+				return builtinError("read_file", object.GENERIC_ERROR, "%s", err)
+			}
+
+			return &object.String{Value: string(contents)}
+		},
+	},
+	"write_file": {
+		Doc: "write_file(path, s) writes a string to a file, replacing what it held, it needs file access to be turned on",
+		Function: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return builtinError("write_file", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d", len(args), 2)
+			}
+
+			path, ok := args[0].(*object.String)
+			if !ok {
+				return newTypedError(object.TYPE_ERROR, "first argument to write_file must be a string, got: %s", args[0].Type())
+			}
+
+			contents, ok := args[1].(*object.String)
+			if !ok {
+				return newTypedError(object.TYPE_ERROR, "second argument to write_file must be a string, got: %s", args[1].Type())
+			}
+
+			if !fileAccess {
+				return builtinError("write_file", object.GENERIC_ERROR, "file access is turned off")
+			}
+
+			if err := os.WriteFile(path.Value, []byte(contents.Value), 0644); err != nil {
+				return builtinError("write_file", object.GENERIC_ERROR, "%s", err)
+			}
+
+			return NULL
+		},
+	},
+	"codes": {
+		Doc: "codes(s) returns the unicode code points of the characters of a string",
+		Function: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return builtinError("codes", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d", len(args), 1)
+			}
+
+			str, ok := args[0].(*object.String)
+			if !ok {
+				return newTypedError(object.TYPE_ERROR, "argument to codes must be a string, got: %s", args[0].Type())
+			}
+
+			elements := make([]object.Object, 0, utf8.RuneCountInString(str.Value))
+			for _, r := range str.Value {
+				elements = append(elements, intObject(int64(r)))
+			}
+
+			return &object.Array{Elements: elements}
+		},
+	},
+	"from_codes": {
+		Doc: "from_codes(array) returns the string made of an array of unicode code points",
+		Function: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return builtinError("from_codes", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d", len(args), 1)
+			}
+
+			array, ok := args[0].(*object.Array)
+			if !ok {
+				return newTypedError(object.TYPE_ERROR, "argument to from_codes must be an array, got: %s", args[0].Type())
+			}
+
+			var out strings.Builder
+
+			for _, element := range array.Elements {
+				code, ok := element.(*object.Integer)
+				if !ok {
+					return newTypedError(object.TYPE_ERROR, "elements of from_codes argument must be integers, got: %s", element.Type())
+				}
+
+				// the range check comes first so values outside int32 are not truncated into a valid rune
+				if code.Value < 0 || code.Value > utf8.MaxRune || !utf8.ValidRune(rune(code.Value)) {
+					return newTypedError(object.VALUE_ERROR, "invalid code point for from_codes: %d", code.Value)
+				}
+
+				if err := checkStringLength(out.Len() + utf8.RuneLen(rune(code.Value))); err != nil {
+					return err
+				}
+
+				out.WriteRune(rune(code.Value))
+			}
+
+			return &object.String{Value: out.String()}
+		},
+	},
+	"lazy_range": {
+		Doc: "lazy_range(start, end) returns an iterator over the integers from start up to but not including end",
+		Function: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return builtinError("lazy_range", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d", len(args), 2)
+			}
+
+			start, ok := args[0].(*object.Integer)
+			if !ok {
+				return newTypedError(object.TYPE_ERROR, "first argument to lazy_range must be an integer, got: %s", args[0].Type())
+			}
+
+			end, ok := args[1].(*object.Integer)
+			if !ok {
+				return newTypedError(object.TYPE_ERROR, "second argument to lazy_range must be an integer, got: %s", args[1].Type())
+			}
+
+			current := start.Value
+			limit := end.Value
+
+			// the values are produced on demand so a large range never exists as an array
+			return object.NewIterator(func() (object.Object, bool) {
+				if current >= limit {
+					return nil, false
+				}
+
+				value := intObject(current)
+				current++
+
+				return value, true
+			})
+		},
+	},
+	"next": {
+		Doc: "next(iterator) returns the next value of an iterator, null when it is done",
+		Function: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return builtinError("next", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d", len(args), 1)
+			}
+
+			iterator, ok := args[0].(*object.Iterator)
+			if !ok {
+				return newTypedError(object.TYPE_ERROR, "argument to next must be an iterator, got: %s", args[0].Type())
+			}
+
+			// null marks the end of the iterator
+			value, ok := iterator.Next()
+			if !ok {
+				return NULL
+			}
+
+			return value
+		},
+	},
+	"pow": {
+		Doc: "pow(base, exponent) returns base raised to a non negative exponent",
+		Function: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return builtinError("pow", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d", len(args), 2)
+			}
+
+			base, ok := args[0].(*object.Integer)
+			if !ok {
+				return newTypedError(object.TYPE_ERROR, "first argument to pow must be an integer, got: %s", args[0].Type())
+			}
+
+			exponent, ok := args[1].(*object.Integer)
+			if !ok {
+				return newTypedError(object.TYPE_ERROR, "second argument to pow must be an integer, got: %s", args[1].Type())
+			}
+
+			if exponent.Value < 0 {
+				return newTypedError(object.VALUE_ERROR, "second argument to pow must not be negative, got: %d", exponent.Value)
+			}
+
+			// powers of 0, 1 and -1 stay small whatever the exponent, every other base at least doubles with each step
+			if base.Value > 1 || base.Value < -1 {
+				if bits := big.NewInt(base.Value).BitLen() - 1; exponent.Value > maxPowBits/int64(bits) {
+					return newTypedError(object.VALUE_ERROR, "result of pow is too large, got exponent: %d", exponent.Value)
+				}
+			}
+
+			// results that do not fit in an int64 become big integers like the results of * do
+			return normalizeBigInt(new(big.Int).Exp(big.NewInt(base.Value), big.NewInt(exponent.Value), nil))
+		},
+	},
+	"sqrt": {
+		Doc: "sqrt(n) returns the square root of a non negative integer rounded down",
+		Function: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return builtinError("sqrt", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d", len(args), 1)
+			}
+
+			if bigInteger, ok := args[0].(*object.BigInt); ok {
+				if bigInteger.Value.Sign() < 0 {
+					return newTypedError(object.VALUE_ERROR, "argument to sqrt must not be negative, got: %s", bigInteger.Value)
+				}
+				return normalizeBigInt(new(big.Int).Sqrt(bigInteger.Value))
+			}
+
+			integer, ok := args[0].(*object.Integer)
+			if !ok {
+				return newTypedError(object.TYPE_ERROR, "argument to sqrt must be an integer, got: %s", args[0].Type())
+			}
+
+			if integer.Value < 0 {
+				return newTypedError(object.VALUE_ERROR, "argument to sqrt must not be negative, got: %d", integer.Value)
+			}
+
+			// jaba has no floats so the root is rounded down
+			// the comparisons divide instead of squaring so they cannot overflow near the largest integer
+			root := int64(math.Sqrt(float64(integer.Value)))
+			for root > 0 && root > integer.Value/root {
+				root--
+			}
+			for root+1 <= integer.Value/(root+1) {
+				root++
+			}
+
+			return intObject(root)
+		},
+	},
+	"floor": {
+		Doc: "floor(n) returns n rounded down, integers are returned as they are",
+		Function: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return builtinError("floor", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d", len(args), 1)
+			}
+
+			// integers are already whole numbers
+			if !isInteger(args[0]) {
+				return newTypedError(object.TYPE_ERROR, "argument to floor must be an integer, got: %s", args[0].Type())
+			}
+
+			return args[0]
+		},
+	},
+	"ceil": {
+		Doc: "ceil(n) returns n rounded up, integers are returned as they are",
+		Function: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return builtinError("ceil", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d", len(args), 1)
+			}
+
+			if !isInteger(args[0]) {
+				return newTypedError(object.TYPE_ERROR, "argument to ceil must be an integer, got: %s", args[0].Type())
+			}
+
+			return args[0]
+		},
+	},
+	"rand": {
+		Doc: "rand(n) returns a random integer from 0 up to but not including n",
+		Function: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return builtinError("rand", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d", len(args), 1)
+			}
+
+			limit, ok := args[0].(*object.Integer)
+			if !ok {
+				return newTypedError(object.TYPE_ERROR, "argument to rand must be an integer, got: %s", args[0].Type())
+			}
+
+			if limit.Value <= 0 {
+				return newTypedError(object.VALUE_ERROR, "argument to rand must be positive, got: %d", limit.Value)
+			}
+
+			return intObject(random.Int63n(limit.Value))
+		},
+	},
+	"seed": {
+		Doc: "seed(n) seeds the generator behind rand so it produces the same values on every run",
+		Function: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return builtinError("seed", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d", len(args), 1)
+			}
+
+			seed, ok := args[0].(*object.Integer)
+			if !ok {
+				return newTypedError(object.TYPE_ERROR, "argument to seed must be an integer, got: %s", args[0].Type())
+			}
+
+			random = rand.New(rand.NewSource(seed.Value))
+
+			return NULL
+		},
+	},
+	"error_kind": {
+		Doc: "error_kind(e) returns the kind of the error a catch block caught e.g. TypeError",
+		Function: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return builtinError("error_kind", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d", len(args), 1)
+			}
+
+			caught, ok := args[0].(*object.CaughtError)
+			if !ok {
+				return newTypedError(object.TYPE_ERROR, "argument to error_kind must be a caught error, got: %s", args[0].Type())
+			}
+
+			return &object.String{Value: string(caught.Kind)}
+		},
+	},
+	"error_message": {
+		Doc: "error_message(e) returns the message of the error a catch block caught as a string",
+		Function: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return builtinError("error_message", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d", len(args), 1)
+			}
+
+			caught, ok := args[0].(*object.CaughtError)
+			if !ok {
+				return newTypedError(object.TYPE_ERROR, "argument to error_message must be a caught error, got: %s", args[0].Type())
+			}
+
+			return &object.String{Value: caught.Message}
+		},
+	},
+	"puts": {
+		Doc: "puts(values...) prints every value on its own line",
+		Function: func(args ...object.Object) object.Object {
+			for _, arg := range args {
+				fmt.Fprintln(output, arg.Inspect())
+			}
+			return NULL
+		},
+	},
+	"input": {
+		Doc: "input(prompt) prints the optional prompt and returns the next line of input without its line ending, or null at the end of the input",
+		Function: func(args ...object.Object) object.Object {
+			if len(args) > 1 {
+				return builtinError("input", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: 0 or 1", len(args))
+			}
+
+			if len(args) == 1 {
+				prompt, ok := args[0].(*object.String)
+				if !ok {
+					return newTypedError(object.TYPE_ERROR, "argument to input must be a string, got: %s", args[0].Type())
+				}
+				fmt.Fprint(output, prompt.Value)
+			}
+
+			line, err := input.ReadString('\n')
+			if err == io.EOF && line == "" {
+				return NULL
+			}
+			if err != nil && err != io.EOF {
+				return builtinError("input", object.GENERIC_ERROR, "%s", err)
+			}
+
+			line = strings.TrimSuffix(line, "\n")
+			line = strings.TrimSuffix(line, "\r")
+
+			return &object.String{Value: line}
+		},
+	},
+	"pprint": {
+		Doc: "pprint(values...) prints every value with nested arrays and hashes spread over indented lines",
+		Function: func(args ...object.Object) object.Object {
+			for _, arg := range args {
+				fmt.Fprintln(output, object.PrettyInspect(arg))
+			}
+			return NULL
+		},
+	},
+	"tap": {
+		Doc: "tap(x) prints x and returns it",
+		Function: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return builtinError("tap", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d", len(args), 1)
+			}
+
+			// unlike puts the argument is handed back so tap can wrap any part of an expression
+			fmt.Fprintln(output, args[0].Inspect())
+			return args[0]
+		},
+	},
+}
+
+// evalBuiltin is the eval builtin, direct calls to it are recognised by the evaluator so they can use the environment of the caller
+var evalBuiltin *object.Builtin
+
+// unsetBuiltin is the unset builtin, like eval it needs the environment of the caller
+var unsetBuiltin *object.Builtin
+
+// init registers the builtins that call back into the evaluator
+// they can not be part of the builtins literal because applyFunctions depends on builtins through Eval
+func init() {
+	evalBuiltin = &object.Builtin{
+		Doc: "eval(source) evaluates a string of jaba source in the current environment and returns its value",
+		Function: func(args ...object.Object) object.Object {
+			// eval that is not called directly, e.g. passed to iterate, has no caller environment to use
+			return evalSource(args, object.NewEnvironment())
+		},
+	}
+	builtins["eval"] = evalBuiltin
+
+	unsetBuiltin = &object.Builtin{
+		Doc: "unset(name) removes the binding called name from the current environment, it returns whether the binding existed",
+		Function: func(args ...object.Object) object.Object {
+			return newTypedError(object.TYPE_ERROR, "unset must be called directly")
+		},
+	}
+	builtins["unset"] = unsetBuiltin
+
+	builtins["iterate"] = &object.Builtin{
+		Doc: "iterate(f, value, count) applies f to value count times and returns the result",
+		Function: func(args ...object.Object) object.Object {
+			if len(args) != 3 {
+				return builtinError("iterate", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d", len(args), 3)
+			}
+
+			switch args[0].(type) {
+			case *object.Function, *object.Builtin:
+			default:
+				return newTypedError(object.TYPE_ERROR, "first argument to iterate must be a function, got: %s", args[0].Type())
+			}
+
+			count, ok := args[2].(*object.Integer)
+			if !ok {
+				return newTypedError(object.TYPE_ERROR, "third argument to iterate must be an integer, got: %s", args[2].Type())
+			}
+
+			if count.Value < 0 {
+				return newTypedError(object.VALUE_ERROR, "count for iterate must not be negative, got: %d", count.Value)
+			}
+
+			result := args[1]
+
+			for i := int64(0); i < count.Value; i++ {
+				result = applyFunctions(args[0], []object.Object{result})
+				if isError(result) {
+					return result
+				}
+			}
+
+			return result
+		},
+	}
+
+	builtins["partial"] = &object.Builtin{
+		Doc: "partial(f, args...) returns a function that calls f with args in front of its own arguments",
+		Function: func(args ...object.Object) object.Object {
+			if len(args) < 1 {
+				return builtinError("partial", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: at least %d", len(args), 1)
+			}
+
+			switch args[0].(type) {
+			case *object.Function, *object.Builtin:
+			default:
+				return newTypedError(object.TYPE_ERROR, "first argument to partial must be a function, got: %s", args[0].Type())
+			}
+
+			function := args[0]
+			captured := args[1:]
+
+			return &object.Builtin{
+				Function: func(rest ...object.Object) object.Object {
+					// a new slice so calls never write into each other's arguments
+					all := make([]object.Object, 0, len(captured)+len(rest))
+					all = append(all, captured...)
+					all = append(all, rest...)
+
+					return applyFunctions(function, all)
+				},
+			}
+		},
+	}
+
+	builtins["each"] = &object.Builtin{
+		Doc: "each(hash, f) calls f(key, value) for every pair of a hash in the order the keys were first set",
+		Function: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return builtinError("each", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d", len(args), 2)
+			}
+
+			hash, ok := args[0].(*object.Hash)
+			if !ok {
+				return newTypedError(object.TYPE_ERROR, "first argument to each must be a hash, got: %s", args[0].Type())
+			}
+
+			// the arity of builtins is not known so only jaba functions are checked
+			switch fn := args[1].(type) {
+			case *object.Function:
+				// parameters with a default value may be left out so a callback can take more than 2 of them
+				if fn.RequiredParameters() > 2 || len(fn.Parameters) < 2 {
+					return newTypedError(object.TYPE_ERROR, "function passed to each must take 2 parameters, got: %d", len(fn.Parameters))
+				}
+			case *object.Builtin:
+			default:
+				return newTypedError(object.TYPE_ERROR, "second argument to each must be a function, got: %s", args[1].Type())
+			}
+
+			for _, pair := range hash.OrderedPairs() {
+				result := applyFunctions(args[1], []object.Object{pair.Key, pair.Value})
+				if isError(result) {
+					return result
+				}
+			}
+
+			return NULL
+		},
+	}
+
+	builtins["help"] = &object.Builtin{
+		Doc: "help(name) returns the description of a builtin, help() returns the names of all builtins",
+		Function: func(args ...object.Object) object.Object {
+			if len(args) > 1 {
+				return builtinError("help", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d or %d", len(args), 0, 1)
+			}
+
+			if len(args) == 0 {
+				names := make([]string, 0, len(builtins))
+				for name := range builtins {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+
+				elements := make([]object.Object, len(names))
+				for i, name := range names {
+					elements[i] = &object.String{Value: name}
+				}
+				return &object.Array{Elements: elements}
+			}
+
+			name, ok := args[0].(*object.String)
+			if !ok {
+				return newTypedError(object.TYPE_ERROR, "argument to help must be a string, got: %s", args[0].Type())
+			}
+
+			builtin, ok := builtins[name.Value]
+			if !ok {
+				return newTypedError(object.NAME_ERROR, "help: no builtin named %s", name.Value)
+			}
+
+			return &object.String{Value: builtin.Doc}
+		},
+	}
+
+	for name, builtin := range builtins {
+		builtin.Name = name
+	}
+}
+
+// builtinError returns an error of the named builtin, the message starts with the name
+// so an error that travels through nested calls still says which builtin failed
+func builtinError(name string, kind object.ErrorKind, format string, a ...interface{}) *object.Error {
+	return newTypedError(kind, name+": "+format, a...)
+}
+
+// evalSource evaluates the jaba source passed to eval in the given environment
+func evalSource(args []object.Object, env *object.Environment) object.Object {
+	if len(args) != 1 {
+		return builtinError("eval", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d", len(args), 1)
+	}
+
+	source, ok := args[0].(*object.String)
+	if !ok {
+		return newTypedError(object.TYPE_ERROR, "argument to eval must be a string, got: %s", args[0].Type())
+	}
+
+	result := EvalString(source.Value, env)
+	if result == nil {
+		return NULL
+	}
+
+	return result
+}
+
+// unsetBinding removes the binding named by the argument of unset from the environment
+// it returns true if the binding existed
+func unsetBinding(args []object.Object, env *object.Environment) object.Object {
+	if len(args) != 1 {
+		return builtinError("unset", object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d", len(args), 1)
+	}
+
+	name, ok := args[0].(*object.String)
+	if !ok {
+		return newTypedError(object.TYPE_ERROR, "argument to unset must be a string, got: %s", args[0].Type())
+	}
+
+	return boolObject(env.Delete(name.Value))
+}
+
+// integerVectors checks that a vector builtin got two arrays of integers with the same length and returns their elements.
+// the elements are integers or big integers
+func integerVectors(name string, args []object.Object) ([]object.Object, []object.Object, *object.Error) {
+	if len(args) != 2 {
+		return nil, nil, builtinError(name, object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d", len(args), 2)
+	}
+
+	vectors := make([][]object.Object, 2)
+
+	for i, arg := range args {
+		array, ok := arg.(*object.Array)
+		if !ok {
+			return nil, nil, newTypedError(object.TYPE_ERROR, "arguments to %s must be arrays, got: %s", name, arg.Type())
+		}
+
+		for _, element := range array.Elements {
+			if !isInteger(element) {
+				return nil, nil, newTypedError(object.TYPE_ERROR, "elements of %s arguments must be integers, got: %s", name, element.Type())
+			}
+		}
+		vectors[i] = array.Elements
+	}
+
+	if len(vectors[0]) != len(vectors[1]) {
+		return nil, nil, newTypedError(object.VALUE_ERROR, "arguments to %s must have the same length, got: %d and %d", name, len(vectors[0]), len(vectors[1]))
+	}
+
+	return vectors[0], vectors[1], nil
+}
+
+// flattenElements splices nested arrays into a new slice, depth is how many levels of nesting are removed.
+// flattening holds the arrays being spliced, an array inside itself is kept as it is instead of being spliced forever
+func flattenElements(elements []object.Object, depth int64, flattening map[*object.Array]bool) []object.Object {
+	flat := make([]object.Object, 0, len(elements))
+
+	for _, element := range elements {
+		nested, ok := element.(*object.Array)
+		if !ok || depth == 0 || flattening[nested] {
+			flat = append(flat, element)
+			continue
+		}
+
+		flattening[nested] = true
+		flat = append(flat, flattenElements(nested.Elements, depth-1, flattening)...)
+		delete(flattening, nested)
+	}
+
+	return flat
+}
+
+// deepCopy returns a structurally new copy of arrays and hashes, nested arrays and hashes are copied too
+// integers, strings, booleans and every other object are shared because they are never changed in place.
+// copies maps the arrays and hashes already copied to their copy so one that contains itself makes a copy that contains itself
+func deepCopy(obj object.Object, copies map[object.Object]object.Object) object.Object {
+	if copied, ok := copies[obj]; ok {
+		return copied
+	}
+
+	switch obj := obj.(type) {
+	case *object.Array:
+		array := &object.Array{Elements: make([]object.Object, len(obj.Elements))}
+		copies[obj] = array
+		for i, element := range obj.Elements {
+			array.Elements[i] = deepCopy(element, copies)
+		}
+		return array
+
+	case *object.Hash:
+		hash := object.NewHash()
+		copies[obj] = hash
+		for _, pair := range obj.OrderedPairs() {
+			hash.Set(pair.Key.(object.Hashable).HashKey(), object.HashPair{Key: pair.Key, Value: deepCopy(pair.Value, copies)})
+		}
+		return hash
+
+	default:
+		return obj
+	}
+}
+
+// encodeJSON writes the JSON text of a value to out, hashes keep the order their keys were first set.
+// encoding holds the arrays and hashes being written so one that contains itself is an error instead of endless output
+func encodeJSON(out *strings.Builder, obj object.Object, encoding map[object.Object]bool) *object.Error {
+	switch obj := obj.(type) {
+	case *object.Integer, *object.BigInt, *object.Boolean:
+		out.WriteString(obj.Inspect())
+
+	case *object.Null:
+		out.WriteString("null")
+
+	case *object.String:
+		writeJSONString(out, obj.Value)
+
+	case *object.Array:
+		if encoding[obj] {
+			return newTypedError(object.VALUE_ERROR, "json_encode: cannot encode an array that contains itself")
+		}
+		encoding[obj] = true
+		defer delete(encoding, obj)
+
+		out.WriteString("[")
+		for i, element := range obj.Elements {
+			if i > 0 {
+				out.WriteString(",")
+			}
+			if err := encodeJSON(out, element, encoding); err != nil {
+				return err
+			}
+		}
+		out.WriteString("]")
+
+	case *object.Hash:
+		if encoding[obj] {
+			return newTypedError(object.VALUE_ERROR, "json_encode: cannot encode a hash that contains itself")
+		}
+		encoding[obj] = true
+		defer delete(encoding, obj)
+
+		out.WriteString("{")
+		for i, pair := range obj.OrderedPairs() {
+			key, ok := pair.Key.(*object.String)
+			if !ok {
+				return newTypedError(object.TYPE_ERROR, "json_encode: hash keys must be strings, got: %s", pair.Key.Type())
+			}
+
+			if i > 0 {
+				out.WriteString(",")
+			}
+			writeJSONString(out, key.Value)
+			out.WriteString(":")
+			if err := encodeJSON(out, pair.Value, encoding); err != nil {
+				return err
+			}
+		}
+		out.WriteString("}")
+
+	default:
+		return newTypedError(object.TYPE_ERROR, "json_encode: cannot encode %s", obj.Type())
+	}
+
+	return nil
+}
+
+// writeJSONString writes a string in double quotes, escaping the quotes, backslashes and control characters JSON does not allow as they are
+func writeJSONString(out *strings.Builder, value string) {
+	out.WriteString(`"`)
+	for _, char := range value {
+		switch char {
+		case '"':
+			out.WriteString(`\"`)
+		case '\\':
+			out.WriteString(`\\`)
+		case '\n':
+			out.WriteString(`\n`)
+		case '\r':
+			out.WriteString(`\r`)
+		case '\t':
+			out.WriteString(`\t`)
+		default:
+			if char < 0x20 {
+				fmt.Fprintf(out, `\u%04x`, char)
+				continue
+			}
+			out.WriteRune(char)
+		}
+	}
+	out.WriteString(`"`)
+}
+
+// decodeJSON reads the next JSON value from the decoder, the decoder has to use json.Number for numbers.
+// the tokens are read one at a time rather than into a map so the pairs of an object keep the order they are written in
+func decodeJSON(decoder *json.Decoder) (object.Object, error) {
+	tok, err := decoder.Token()
+	if err == io.EOF {
+		return nil, errors.New("unexpected end of JSON input")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok := tok.(type) {
+	case nil:
+		return NULL, nil
+
+	case bool:
+		return boolObject(tok), nil
+
+	case string:
+		return &object.String{Value: tok}, nil
+
+	case json.Number:
+		integer, ok := new(big.Int).SetString(tok.String(), 10)
+		if !ok {
+			return nil, fmt.Errorf("%s is not an integer", tok)
+		}
+		return normalizeBigInt(integer), nil
+
+	case json.Delim:
+		if tok == '[' {
+			elements := []object.Object{}
+			for decoder.More() {
+				element, err := decodeJSON(decoder)
+				if err != nil {
+					return nil, err
+				}
+				elements = append(elements, element)
+			}
+			// the closing bracket
+			if _, err := decoder.Token(); err != nil {
+				return nil, err
+			}
+			return &object.Array{Elements: elements}, nil
+		}
+
+		hash := object.NewHash()
+		for decoder.More() {
+			keyToken, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			key := &object.String{Value: keyToken.(string)}
+
+			value, err := decodeJSON(decoder)
+			if err != nil {
+				return nil, err
+			}
+			hash.Set(key.HashKey(), object.HashPair{Key: key, Value: value})
+		}
+		// the closing brace
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+		return hash, nil
+	}
+
+	return nil, fmt.Errorf("unexpected token %v", tok)
 }
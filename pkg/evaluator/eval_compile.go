@@ -0,0 +1,275 @@
+package evaluator
+
+import (
+	"github.com/maxwellgithinji/jaba/pkg/ast"
+	"github.com/maxwellgithinji/jaba/pkg/object"
+)
+
+// compiled is a node turned into a closure that evaluates it.
+// The type of the node and of its children is only looked at once, when the node is compiled,
+// so evaluating the same function body again does not go through the type switch of eval for every node
+type compiled func(env *object.Environment) object.Object
+
+// evalFunctionBody evaluates the body of a function call.
+// The body is compiled on the first call and the compiled body is reused by every call after that.
+// A hook or a trace has to see every node so the body is evaluated node by node while one of them is set
+func evalFunctionBody(fn *object.Function, env *object.Environment) object.Object {
+	if env.Evaluation().Hook != nil || traceWriter != nil {
+		return Eval(fn.Body, env)
+	}
+
+	if fn.Compiled == nil {
+		fn.Compiled = compileBlockStatement(fn.Body)
+	}
+
+	return fn.Compiled(env)
+}
+
+// compile turns a node into a closure that gives the same result as Eval.
+// Only the nodes function bodies use the most are compiled, every other node is handed to Eval when the closure runs
+func compile(node ast.Node) compiled {
+	switch node := node.(type) {
+	case *ast.BlockStatement:
+		return compileBlockStatement(node)
+
+	case *ast.ExpressionStatement:
+		return compile(node.Value)
+
+	case *ast.ReturnStatement:
+		value := compile(node.Value)
+		return func(env *object.Environment) object.Object {
+			result := value(env)
+			if isError(result) {
+				return result
+			}
+			return &object.ReturnValue{Value: result}
+		}
+
+	case *ast.LetStatement:
+		name := node.Name.Value
+		value := compile(node.Value)
+		return func(env *object.Environment) object.Object {
+			result := value(env)
+			if isErrorOrReturn(result) {
+				return result
+			}
+			env.Set(name, result)
+			return nil
+		}
+
+	case *ast.IntegerLiteral:
+		// integer objects are never mutated so the literal can give the same object every time
+		integer := intObject(node.Value)
+		return func(env *object.Environment) object.Object { return integer }
+
+	case *ast.Boolean:
+		boolean := boolObject(node.Value)
+		return func(env *object.Environment) object.Object { return boolean }
+
+	case *ast.Identifier:
+		return func(env *object.Environment) object.Object { return evalIdentifier(node, env) }
+
+	case *ast.PrefixExpression:
+		return compilePrefixExpression(node)
+
+	case *ast.InfixExpression:
+		return compileInfixExpression(node)
+
+	case *ast.IfExpression:
+		return compileIfExpression(node)
+
+	case *ast.IndexExpression:
+		return compileIndexExpression(node)
+
+	case *ast.CallExpression:
+		return compileCallExpression(node)
+
+	case *ast.FunctionLiteral:
+		// the body of a function defined inside another one is compiled along with it, every closure created from it shares the compiled body
+		body := compileBlockStatement(node.Body)
+		return func(env *object.Environment) object.Object {
+			return &object.Function{Parameters: node.Parameters, Defaults: node.Defaults, Env: env, Body: node.Body, Generator: node.Generator, Compiled: body}
+		}
+	}
+
+	return func(env *object.Environment) object.Object { return Eval(node, env) }
+}
+
+// compileBlockStatement compiles every statement of a block into a flat list that is run in order like evalBlockStatements
+func compileBlockStatement(block *ast.BlockStatement) compiled {
+	statements := make([]compiled, len(block.Statements))
+	for i, statement := range block.Statements {
+		statements[i] = compile(statement)
+	}
+
+	return func(env *object.Environment) object.Object {
+		var result object.Object
+
+		for _, statement := range statements {
+			result = statement(env)
+
+			if result != nil {
+				resultType := result.Type()
+				if resultType == object.RETURN_VALUE_OBJECT || resultType == object.ERROR_OBJECT {
+					return result
+				}
+			}
+		}
+
+		return result
+	}
+}
+
+// compilePrefixExpression compiles a prefix expression, see the *ast.PrefixExpression case of eval
+func compilePrefixExpression(node *ast.PrefixExpression) compiled {
+	right := compile(node.Right)
+
+	return func(env *object.Environment) object.Object {
+		value := right(env)
+		if isError(value) {
+			return value
+		}
+		return evalPrefixExpression(node.Operator, value)
+	}
+}
+
+// compileInfixExpression compiles an infix expression, see the *ast.InfixExpression case of eval
+func compileInfixExpression(node *ast.InfixExpression) compiled {
+	left := compile(node.Left)
+
+	// the right side of && and || is only evaluated when the left side does not decide the result
+	if node.Operator == "&&" || node.Operator == "||" {
+		return func(env *object.Environment) object.Object {
+			value := left(env)
+			if isError(value) {
+				return value
+			}
+			return evalLogicalExpression(node.Operator, value, node.Right, env)
+		}
+	}
+
+	right := compile(node.Right)
+
+	return func(env *object.Environment) object.Object {
+		leftValue := left(env)
+		if isError(leftValue) {
+			return leftValue
+		}
+
+		rightValue := right(env)
+		if isError(rightValue) {
+			return rightValue
+		}
+		return evalInfixExpression(node.Operator, leftValue, rightValue)
+	}
+}
+
+// compileIfExpression compiles an if expression, see evalIfExpression
+func compileIfExpression(node *ast.IfExpression) compiled {
+	condition := compile(node.Condition)
+	consequence := compile(node.Consequence)
+
+	var alternative compiled
+	switch {
+	case node.Alternative != nil:
+		alternative = compile(node.Alternative)
+	case node.ElseIf != nil:
+		alternative = compile(node.ElseIf)
+	}
+
+	return func(env *object.Environment) object.Object {
+		value := condition(env)
+		if isError(value) {
+			return value
+		}
+
+		if strict && value.Type() != object.BOOLEAN_OBJECT {
+			return newTypedError(object.TYPE_ERROR, "condition must be boolean, got: %s", value.Type())
+		}
+
+		if isTruthy(value) {
+			return consequence(env)
+		} else if alternative != nil {
+			return alternative(env)
+		}
+		return NULL
+	}
+}
+
+// compileIndexExpression compiles an index expression, see the *ast.IndexExpression case of eval
+func compileIndexExpression(node *ast.IndexExpression) compiled {
+	left := compile(node.Left)
+	index := compile(node.Index)
+
+	return func(env *object.Environment) object.Object {
+		leftValue := left(env)
+		if isError(leftValue) {
+			return leftValue
+		}
+
+		indexValue := index(env)
+		if isError(indexValue) {
+			return indexValue
+		}
+		return evalIndexExpression(leftValue, indexValue)
+	}
+}
+
+// compileCallExpression compiles a call expression, see the *ast.CallExpression case of eval.
+// Calls of a method out of a hash and calls with spread arguments are left to evalCallee and evalExpressions
+func compileCallExpression(node *ast.CallExpression) compiled {
+	callee := func(env *object.Environment) object.Object { return evalCallee(node.Function, env) }
+	if _, isIndex := node.Function.(*ast.IndexExpression); !isIndex {
+		callee = compile(node.Function)
+	}
+
+	arguments := compileArguments(node.Arguments)
+
+	return func(env *object.Environment) object.Object {
+		function := callee(env)
+		if isError(function) {
+			return function
+		}
+
+		args := arguments(env)
+		if len(args) == 1 && isErrorOrReturn(args[0]) {
+			return args[0]
+		}
+
+		// direct calls to eval and unset work on the environment of the caller
+		switch function {
+		case evalBuiltin:
+			return evalSource(args, env)
+
+		case unsetBuiltin:
+			return unsetBinding(args, env)
+		}
+
+		return applyFunctions(function, args)
+	}
+}
+
+// compileArguments compiles the arguments of a call, an error or a return value is returned on its own like evalExpressions does
+func compileArguments(expressions []ast.Expression) func(env *object.Environment) []object.Object {
+	arguments := make([]compiled, len(expressions))
+	for i, expression := range expressions {
+		if _, isSpread := expression.(*ast.SpreadExpression); isSpread {
+			return func(env *object.Environment) []object.Object { return evalExpressions(expressions, env) }
+		}
+		arguments[i] = compile(expression)
+	}
+
+	return func(env *object.Environment) []object.Object {
+		evaluated := make([]object.Object, 0, len(arguments))
+
+		for _, argument := range arguments {
+			result := argument(env)
+			if isErrorOrReturn(result) {
+				return []object.Object{result}
+			}
+			evaluated = append(evaluated, result)
+		}
+
+		return evaluated
+	}
+}
@@ -0,0 +1,40 @@
+package evaluator
+
+import (
+	"context"
+
+	"github.com/maxwellgithinji/jaba/pkg/ast"
+	"github.com/maxwellgithinji/jaba/pkg/object"
+)
+
+// EvalWithContext evaluates a node like Eval but stops with an error once ctx is cancelled or its deadline passes.
+// The context is checked before every run of a loop body and every function call, so a host can stop a program that never ends.
+// The context is kept on the evaluation of env, programs evaluated at the same time in other environments keep their own
+func EvalWithContext(ctx context.Context, node ast.Node, env *object.Environment) object.Object {
+	evaluation := env.Evaluation()
+
+	previous := evaluation.Context
+	evaluation.Context = ctx
+	defer func() { evaluation.Context = previous }()
+
+	if err := checkCancelled(env); err != nil {
+		return err
+	}
+
+	return Eval(node, env)
+}
+
+// checkCancelled returns an error when the context of the evaluation env belongs to is done, otherwise nil
+func checkCancelled(env *object.Environment) *object.Error {
+	ctx := env.Evaluation().Context
+	if ctx == nil {
+		return nil
+	}
+
+	select {
+	case <-ctx.Done():
+		return newError("evaluation cancelled")
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,23 @@
+package evaluator
+
+import (
+	"github.com/maxwellgithinji/jaba/pkg/ast"
+	"github.com/maxwellgithinji/jaba/pkg/object"
+)
+
+// Hook is called before the evaluator evaluates a node with the node and the environment it is evaluated in,
+// a debugger can use it to stop at breakpoints or to step through a program
+type Hook func(node ast.Node, env *object.Environment)
+
+// EvalWithHook evaluates a node like Eval and calls hook before every statement and expression it evaluates,
+// including the nodes inside function bodies.
+// The hook is kept on the evaluation of env, programs evaluated at the same time in other environments are not hooked
+func EvalWithHook(node ast.Node, env *object.Environment, hook Hook) object.Object {
+	evaluation := env.Evaluation()
+
+	previous := evaluation.Hook
+	evaluation.Hook = hook
+	defer func() { evaluation.Hook = previous }()
+
+	return Eval(node, env)
+}
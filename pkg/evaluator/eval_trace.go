@@ -0,0 +1,44 @@
+package evaluator
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/maxwellgithinji/jaba/pkg/ast"
+	"github.com/maxwellgithinji/jaba/pkg/object"
+)
+
+// traceWriter receives a line for every node the evaluator enters and leaves, tracing is off when it is nil
+var traceWriter io.Writer
+
+// traceLevel is the depth of the node being evaluated, it is used to indent the trace
+var traceLevel int
+
+const traceIdentPlaceholder string = "\t"
+
+// SetTrace turns the evaluation trace on by writing it to w, passing nil turns it off
+func SetTrace(w io.Writer) {
+	traceWriter = w
+	traceLevel = 0
+}
+
+// evalTraced evaluates a node and logs the node type on the way in and the returned object on the way out
+func evalTraced(node ast.Node, env *object.Environment) object.Object {
+	name := strings.TrimPrefix(fmt.Sprintf("%T", node), "*ast.")
+	indent := strings.Repeat(traceIdentPlaceholder, traceLevel)
+
+	fmt.Fprintf(traceWriter, "%sBEGIN %s\n", indent, name)
+
+	traceLevel++
+	result := eval(node, env)
+	traceLevel--
+
+	if result == nil {
+		fmt.Fprintf(traceWriter, "%sEND %s\n", indent, name)
+	} else {
+		fmt.Fprintf(traceWriter, "%sEND %s -> %s\n", indent, name, result.Inspect())
+	}
+
+	return result
+}
@@ -5,9 +5,14 @@ package evaluator
 
 import (
 	"fmt"
+	"math"
+	"math/big"
+	"strings"
 
 	"github.com/maxwellgithinji/jaba/pkg/ast"
+	"github.com/maxwellgithinji/jaba/pkg/lexer"
 	"github.com/maxwellgithinji/jaba/pkg/object"
+	"github.com/maxwellgithinji/jaba/pkg/parser"
 )
 
 var (
@@ -16,8 +21,78 @@ var (
 	FALSE = &object.Boolean{Value: false}
 )
 
+// cLikeLogic allows && and || to accept integer operands the way C does
+// it is off by default which means logical operators only accept booleans
+var cLikeLogic bool
+
+// SetCLikeLogic turns the C-like logical operator mode on or off
+// when on, non-zero integers are true, zero is false and logical operations on integers return 1 or 0
+func SetCLikeLogic(enabled bool) {
+	cLikeLogic = enabled
+}
+
+// strict makes conditions of if expressions and loops accept only booleans
+// it is off by default which means any value can be used as a condition and is checked for truthiness
+var strict bool
+
+// SetStrict turns strict mode on or off
+func SetStrict(enabled bool) {
+	strict = enabled
+}
+
+// maxCallDepth is the number of nested function calls allowed before evaluation stops with an error
+// it keeps runaway recursion from overflowing the Go stack
+var maxCallDepth = 10000
+
+// SetMaxCallDepth changes the number of nested function calls allowed
+func SetMaxCallDepth(depth int) {
+	maxCallDepth = depth
+}
+
+// maxStringLength is the length in bytes of the longest string an operation is allowed to produce
+var maxStringLength = 1 << 24
+
+// SetMaxStringLength changes the length of the longest string an operation is allowed to produce
+func SetMaxStringLength(length int) {
+	maxStringLength = length
+}
+
+// checkStringLength returns an error when a string of the given length would be longer than allowed, otherwise nil
+func checkStringLength(length int) *object.Error {
+	if length > maxStringLength {
+		return newError("string length exceeds the maximum of %d", maxStringLength)
+	}
+	return nil
+}
+
 // Eval is a recursive function that that evaluates the AST and returns an object representation as output
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	if hook := env.Evaluation().Hook; hook != nil {
+		hook(node, env)
+	}
+
+	if traceWriter != nil {
+		return evalTraced(node, env)
+	}
+
+	return eval(node, env)
+}
+
+// EvalString parses the source code and evaluates it in the environment
+// parser errors are returned as a single error object
+func EvalString(src string, env *object.Environment) object.Object {
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		return newError("parser errors: %s", strings.Join(p.Errors(), "; "))
+	}
+
+	return Eval(program, env)
+}
+
+// eval does the actual evaluation of a node, Eval decides whether the step is traced
+func eval(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 	// Statements
 	case *ast.Program:
@@ -36,19 +111,47 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		}
 		return &object.ReturnValue{Value: value}
 
-	case *ast.LetStatement:
+	case *ast.ThrowStatement:
 		value := Eval(node.Value, env)
 		if isError(value) {
 			return value
 		}
+		// the thrown value becomes an ordinary error so it stops the program or reaches a catch block the same way
+		// rethrowing a caught error keeps its kind
+		if caught, ok := value.(*object.CaughtError); ok {
+			return newTypedError(caught.Kind, "%s", caught.Message)
+		}
+		return newError("%s", value.Inspect())
+
+	case *ast.YieldStatement:
+		value := Eval(node.Value, env)
+		if isError(value) {
+			return value
+		}
+
+		// generators bind yield to the function that hands values to their caller, yield is a keyword so it cannot clash with a user binding
+		yield, ok := env.Get("yield")
+		if !ok {
+			return newError("yield outside generator")
+		}
+		return applyFunctions(yield, []object.Object{value})
+
+	case *ast.LetStatement:
+		value := Eval(node.Value, env)
+		if isErrorOrReturn(value) {
+			return value
+		}
 		env.Set(node.Name.Value, value)
 
+	case *ast.DestructuringLetStatement:
+		return evalDestructuringLetStatement(node, env)
+
 	// Expressions
 	case *ast.IntegerLiteral:
-		return &object.Integer{Value: node.Value}
+		return intObject(node.Value)
 
 	case *ast.Boolean:
-		return nativeBooleanToBooleanObject(node.Value)
+		return boolObject(node.Value)
 
 	case *ast.PrefixExpression:
 		right := Eval(node.Right, env) // evaluates expression on the right hand side of the operator
@@ -62,6 +165,9 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		if isError(left) {
 			return left
 		}
+		if node.Operator == "&&" || node.Operator == "||" {
+			return evalLogicalExpression(node.Operator, left, node.Right, env)
+		}
 		right := Eval(node.Right, env) // evaluates expression on the right hand side of the operator
 		if isError(right) {
 			return right
@@ -71,22 +177,44 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.IfExpression:
 		return evalIfExpression(node, env)
 
+	case *ast.DoWhileExpression:
+		return evalDoWhileExpression(node, env, nil)
+
+	case *ast.ForInExpression:
+		return evalForInExpression(node, env, nil)
+
+	case *ast.CollectExpression:
+		return evalCollectExpression(node, env)
+
+	case *ast.TryExpression:
+		return evalTryExpression(node, env)
+
 	case *ast.FunctionLiteral:
 		params := node.Parameters
 		body := node.Body
-		return &object.Function{Parameters: params, Env: env, Body: body}
+		return &object.Function{Parameters: params, Defaults: node.Defaults, Env: env, Body: body, Generator: node.Generator}
 
 	case *ast.CallExpression:
-		function := Eval(node.Function, env)
+		function := evalCallee(node.Function, env)
 
 		if isError(function) {
 			return function
 		}
 
 		args := evalExpressions(node.Arguments, env)
-		if len(args) == 1 && isError(args[0]) {
+		if len(args) == 1 && isErrorOrReturn(args[0]) {
 			return args[0]
 		}
+
+		// direct calls to eval and unset work on the environment of the caller
+		switch function {
+		case evalBuiltin:
+			return evalSource(args, env)
+
+		case unsetBuiltin:
+			return unsetBinding(args, env)
+		}
+
 		return applyFunctions(function, args)
 
 	case *ast.StringLiteral:
@@ -94,7 +222,7 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 
 	case *ast.ArrayLiteral:
 		elements := evalExpressions(node.Elements, env)
-		if len(elements) == 1 && isError(elements[0]) {
+		if len(elements) == 1 && isErrorOrReturn(elements[0]) {
 			return elements[0]
 		}
 		return &object.Array{Elements: elements}
@@ -114,6 +242,9 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.HashLiteral:
 		return evalHashLiteral(node, env)
 
+	case *ast.AssignExpression:
+		return evalAssignExpression(node, env)
+
 	// Identifier
 	case *ast.Identifier:
 		return evalIdentifier(node, env)
@@ -123,7 +254,12 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 }
 
 // evalProgram evaluates the entry point of the program
+// an empty program evaluates to null, a return that reaches the program is an error because it is not inside a function
 func evalProgram(statements []ast.Statement, env *object.Environment) object.Object {
+	if len(statements) == 0 {
+		return NULL
+	}
+
 	var result object.Object
 
 	for _, statement := range statements {
@@ -132,7 +268,7 @@ func evalProgram(statements []ast.Statement, env *object.Environment) object.Obj
 		switch r := result.(type) {
 
 		case *object.ReturnValue:
-			return r.Value
+			return newError("return outside function")
 
 		case *object.Error:
 			return r
@@ -160,8 +296,32 @@ func evalBlockStatements(block *ast.BlockStatement, env *object.Environment) obj
 	return result
 }
 
-// nativeBooleanToBooleanObject is a helper function that converts a native boolean to a boolean object
-func nativeBooleanToBooleanObject(input bool) object.Object {
+// the integers from minCachedInteger to maxCachedInteger are allocated once and shared, they are the values loops and counters use most
+const (
+	minCachedInteger = -128
+	maxCachedInteger = 256
+)
+
+var cachedIntegers = func() []*object.Integer {
+	integers := make([]*object.Integer, maxCachedInteger-minCachedInteger+1)
+	for i := range integers {
+		integers[i] = &object.Integer{Value: int64(i + minCachedInteger)}
+	}
+	return integers
+}()
+
+// intObject is a helper function that returns the integer object for a value, small values come from the cache instead of a new allocation.
+// integer objects are never mutated so sharing them is safe
+func intObject(value int64) *object.Integer {
+	if value >= minCachedInteger && value <= maxCachedInteger {
+		return cachedIntegers[value-minCachedInteger]
+	}
+	return &object.Integer{Value: value}
+}
+
+// boolObject is a helper function that converts a native boolean to one of the shared TRUE or FALSE objects.
+// every boolean must be created through it because isTruthy and == compare booleans by identity
+func boolObject(input bool) object.Object {
 	if input {
 		return TRUE
 	}
@@ -178,100 +338,395 @@ func evalPrefixExpression(operator string, right object.Object) object.Object {
 		return evalMinusPrefixOperatorExpression(right)
 
 	}
-	return newError("unknown operation: %s %s", operator, right.Type())
+	return newTypedError(object.TYPE_ERROR, "unknown operation: %s %s", operator, right.Type())
 }
 
 // evalNopeOperatorExpression is a helper function that evaluates a nope operator that appears at the beginning of the expression
 func evalNopePrefixOperatorExpression(right object.Object) object.Object {
-	switch right {
-	case TRUE:
-		return FALSE
-
-	case FALSE:
-		return TRUE
-
-	case NULL:
-		return TRUE
-
-	default:
-		return FALSE
-	}
+	return boolObject(!isTruthy(right))
 }
 
 // evalMinusPrefixOperatorExpression is a helper function that evaluates a minus operator that appears at the beginning of the expression
 // minus prefix only applies to numbers
 func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
-	if right.Type() != object.INTEGER_OBJECT {
-		return newError("unknown operation: -%s", right.Type())
+	if !isInteger(right) {
+		return newTypedError(object.TYPE_ERROR, "unknown operation: -%s", right.Type())
 	}
 
-	value := right.(*object.Integer).Value
+	// the smallest integer has no positive counterpart in an int64
+	if integer, ok := right.(*object.Integer); ok && integer.Value != math.MinInt64 {
+		return intObject(-integer.Value)
+	}
 
-	return &object.Integer{Value: -value}
+	return normalizeBigInt(new(big.Int).Neg(toBigInt(right)))
 }
 
 // evalInfixExpression evaluates an expression that have operands in between themselves
 func evalInfixExpression(operator string, left object.Object, right object.Object) object.Object {
+	// a caught error takes part in operations as its message e.g. "failed: " + e
+	left, right = caughtMessage(left), caughtMessage(right)
+
+	// hashes can define how an operator works on them
+	if method, ok := operatorMethod(operator, left, right); ok {
+		return applyFunctions(method, []object.Object{left, right})
+	}
 
 	switch {
 	case left.Type() == object.INTEGER_OBJECT && right.Type() == object.INTEGER_OBJECT: // integer based infix expression
 		return evalIntegerInfixExpression(operator, left, right)
 
+	case isInteger(left) && isInteger(right): // one of the integers is too big for an int64
+		return evalBigIntInfixExpression(operator, left, right)
+
+	case (operator == "==" || operator == "!=") && (left.Type() == object.NULL_OBJECT || right.Type() == object.NULL_OBJECT):
+		return evalNullEquality(operator, left, right)
+
 	case operator == "==":
-		return nativeBooleanToBooleanObject(left == right)
+		return boolObject(objectsEqual(left, right))
 
 	case operator == "!=":
-		return nativeBooleanToBooleanObject(left != right)
+		return boolObject(!objectsEqual(left, right))
 
 	case right.Type() == object.STRING_OBJECT && left.Type() == object.STRING_OBJECT:
 		return evalStringInfixExpression(operator, left, right)
 
+	case right.Type() == object.HASH_OBJECT && left.Type() == object.HASH_OBJECT:
+		return evalHashInfixExpression(operator, left, right)
+
+	case left.Type() == object.STRING_OBJECT && right.Type() == object.INTEGER_OBJECT && operator == "*":
+		return evalStringRepetition(left.(*object.String), right.(*object.Integer))
+
+	case left.Type() == object.INTEGER_OBJECT && right.Type() == object.STRING_OBJECT && operator == "*":
+		return evalStringRepetition(right.(*object.String), left.(*object.Integer))
+
 	case left.Type() != right.Type():
-		return newError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
+		return newTypedError(object.TYPE_ERROR, "type mismatch: %s %s %s", left.Type(), operator, right.Type())
 
 	default:
-		return newError("unknown operation: %s %s %s", left.Type(), operator, right.Type())
+		return newTypedError(object.TYPE_ERROR, "unknown operation: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
+// caughtMessage returns the message of a caught error as a string, other objects are returned as they are
+func caughtMessage(obj object.Object) object.Object {
+	if caught, ok := obj.(*object.CaughtError); ok {
+		return &object.String{Value: caught.Message}
+	}
+	return obj
+}
+
+// operatorMethods maps the operators a hash can overload to the key that holds the function implementing them
+var operatorMethods = map[string]string{
+	"+":  "__add__",
+	"==": "__eq__",
+}
+
+// operatorMethod returns the function a hash operand stores for an operator, the left operand is looked up first
+// the function is called with both operands in their order around the operator
+func operatorMethod(operator string, left, right object.Object) (object.Object, bool) {
+	name, ok := operatorMethods[operator]
+	if !ok {
+		return nil, false
+	}
+
+	key := (&object.String{Value: name}).HashKey()
+	for _, operand := range []object.Object{left, right} {
+		hash, ok := operand.(*object.Hash)
+		if !ok {
+			continue
+		}
+		if pair, ok := hash.Pairs[key]; ok {
+			return pair.Value, true
+		}
+	}
+
+	return nil, false
+}
+
 // evalIntegerInfixExpression returns evaluated integer based infix expression
 func evalIntegerInfixExpression(operator string, left object.Object, right object.Object) object.Object {
 	leftValue := left.(*object.Integer).Value
 	rightValue := right.(*object.Integer).Value
 
+	// results that do not fit in an int64 are computed again as big integers
 	switch operator {
 	case "+":
-		return &object.Integer{Value: leftValue + rightValue}
+		if sum, ok := addIntegers(leftValue, rightValue); ok {
+			return intObject(sum)
+		}
+		return evalBigIntInfixExpression(operator, left, right)
 
 	case "-":
-		return &object.Integer{Value: leftValue - rightValue}
+		if difference, ok := subtractIntegers(leftValue, rightValue); ok {
+			return intObject(difference)
+		}
+		return evalBigIntInfixExpression(operator, left, right)
 
 	case "*":
-		return &object.Integer{Value: leftValue * rightValue}
+		if product, ok := multiplyIntegers(leftValue, rightValue); ok {
+			return intObject(product)
+		}
+		return evalBigIntInfixExpression(operator, left, right)
 
 	case "/":
-		return &object.Integer{Value: leftValue / rightValue}
+		if rightValue == 0 {
+			return newTypedError(object.DIVISION_ERROR, "division by zero")
+		}
+		// the smallest integer divided by -1 is the only quotient that does not fit
+		if leftValue == math.MinInt64 && rightValue == -1 {
+			return evalBigIntInfixExpression(operator, left, right)
+		}
+		return intObject(leftValue / rightValue)
 
 	case "<":
-		return nativeBooleanToBooleanObject(leftValue < rightValue)
+		return boolObject(leftValue < rightValue)
 
 	case ">":
-		return nativeBooleanToBooleanObject(leftValue > rightValue)
+		return boolObject(leftValue > rightValue)
 
 	case "==":
-		return nativeBooleanToBooleanObject(leftValue == rightValue)
+		return boolObject(leftValue == rightValue)
 
 	case "!=":
-		return nativeBooleanToBooleanObject(leftValue != rightValue)
+		return boolObject(leftValue != rightValue)
 
 	default:
-		return newError("unknown operation %s %s %s", left.Type(), operator, right.Type())
+		return newTypedError(object.TYPE_ERROR, "unknown operation %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
+// evalBigIntInfixExpression evaluates an infix expression between integers where at least one of them does not fit in an int64
+func evalBigIntInfixExpression(operator string, left object.Object, right object.Object) object.Object {
+	leftValue := toBigInt(left)
+	rightValue := toBigInt(right)
+
+	switch operator {
+	case "+":
+		return normalizeBigInt(new(big.Int).Add(leftValue, rightValue))
+
+	case "-":
+		return normalizeBigInt(new(big.Int).Sub(leftValue, rightValue))
+
+	case "*":
+		return normalizeBigInt(new(big.Int).Mul(leftValue, rightValue))
+
+	case "/":
+		if rightValue.Sign() == 0 {
+			return newTypedError(object.DIVISION_ERROR, "division by zero")
+		}
+		// Quo truncates towards zero like the division of integers
+		return normalizeBigInt(new(big.Int).Quo(leftValue, rightValue))
+
+	case "<":
+		return boolObject(leftValue.Cmp(rightValue) < 0)
+
+	case ">":
+		return boolObject(leftValue.Cmp(rightValue) > 0)
+
+	case "==":
+		return boolObject(leftValue.Cmp(rightValue) == 0)
+
+	case "!=":
+		return boolObject(leftValue.Cmp(rightValue) != 0)
+
+	default:
+		return newTypedError(object.TYPE_ERROR, "unknown operation %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+// evalNullEquality compares a value with null, null is only equal to null whatever the other value is
+func evalNullEquality(operator string, left object.Object, right object.Object) object.Object {
+	equal := left.Type() == object.NULL_OBJECT && right.Type() == object.NULL_OBJECT
+
+	if operator == "!=" {
+		return boolObject(!equal)
+	}
+
+	return boolObject(equal)
+}
+
+// objectsEqual compares arrays element by element and hashes by their keys and values, nested arrays and hashes are compared the same way
+// other values are compared the way == compares them on their own
+func objectsEqual(left, right object.Object) bool {
+	return equalObjects(left, right, map[[2]object.Object]bool{})
+}
+
+// equalObjects is objectsEqual, comparing keeps the pairs of arrays and hashes it is inside of
+// a pair met again is a structure that refers to itself, it is taken as equal so the comparison ends
+func equalObjects(left, right object.Object, comparing map[[2]object.Object]bool) bool {
+	if left == right {
+		return true
+	}
+
+	switch {
+	case isInteger(left) && isInteger(right):
+		return toBigInt(left).Cmp(toBigInt(right)) == 0
+
+	case left.Type() != right.Type():
+		return false
+	}
+
+	pair := [2]object.Object{left, right}
+	if comparing[pair] {
+		return true
+	}
+
+	switch left := left.(type) {
+	case *object.Array:
+		right := right.(*object.Array)
+		if len(left.Elements) != len(right.Elements) {
+			return false
+		}
+
+		comparing[pair] = true
+		defer delete(comparing, pair)
+
+		for i := range left.Elements {
+			if !equalObjects(left.Elements[i], right.Elements[i], comparing) {
+				return false
+			}
+		}
+		return true
+
+	case *object.Hash:
+		right := right.(*object.Hash)
+		if len(left.Pairs) != len(right.Pairs) {
+			return false
+		}
+
+		comparing[pair] = true
+		defer delete(comparing, pair)
+
+		for key, leftPair := range left.Pairs {
+			rightPair, ok := right.Pairs[key]
+			if !ok || !equalObjects(leftPair.Value, rightPair.Value, comparing) {
+				return false
+			}
+		}
+		return true
+
+	case *object.Null:
+		return true
+	}
+
+	return false
+}
+
+// isInteger reports whether the object is an integer or a big integer
+func isInteger(obj object.Object) bool {
+	return obj.Type() == object.INTEGER_OBJECT || obj.Type() == object.BIGINT_OBJECT
+}
+
+// toBigInt returns the value of an integer or a big integer as a big.Int
+func toBigInt(obj object.Object) *big.Int {
+	if integer, ok := obj.(*object.Integer); ok {
+		return big.NewInt(integer.Value)
+	}
+
+	return obj.(*object.BigInt).Value
+}
+
+// normalizeBigInt turns the result of big integer arithmetic back into an integer when it fits in an int64
+func normalizeBigInt(value *big.Int) object.Object {
+	if value.IsInt64() {
+		return intObject(value.Int64())
+	}
+
+	return &object.BigInt{Value: value}
+}
+
+// addIntegers returns the sum of a and b, the boolean is false when the sum does not fit in an int64
+func addIntegers(a, b int64) (int64, bool) {
+	sum := a + b
+	return sum, (sum > a) == (b > 0)
+}
+
+// subtractIntegers returns the difference of a and b, the boolean is false when the difference does not fit in an int64
+func subtractIntegers(a, b int64) (int64, bool) {
+	difference := a - b
+	return difference, (difference < a) == (b > 0)
+}
+
+// multiplyIntegers returns the product of a and b, the boolean is false when the product does not fit in an int64
+func multiplyIntegers(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+
+	product := a * b
+
+	// dividing by -1 cannot detect these because the smallest integer divided by -1 wraps back to itself
+	if (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return product, false
+	}
+
+	return product, product/b == a
+}
+
+// evalLogicalExpression evaluates && and || expressions.
+// The right hand side is only evaluated when the left hand side does not already decide the result (short-circuiting)
+func evalLogicalExpression(operator string, left object.Object, rightNode ast.Expression, env *object.Environment) object.Object {
+	leftValue, ok := logicalOperand(left)
+	if !ok {
+		return newTypedError(object.TYPE_ERROR, "invalid operand for %s: %s", operator, left.Type())
+	}
+
+	integerResult := isInteger(left)
+
+	if (operator == "&&" && !leftValue) || (operator == "||" && leftValue) {
+		return logicalResult(leftValue, integerResult)
+	}
+
+	right := Eval(rightNode, env)
+	if isError(right) {
+		return right
+	}
+
+	rightValue, ok := logicalOperand(right)
+	if !ok {
+		return newTypedError(object.TYPE_ERROR, "invalid operand for %s: %s", operator, right.Type())
+	}
+
+	return logicalResult(rightValue, integerResult || isInteger(right))
+}
+
+// logicalOperand returns the truth value of an operand of a logical operator
+// integers are only accepted in C-like mode
+func logicalOperand(obj object.Object) (bool, bool) {
+	switch obj := obj.(type) {
+	case *object.Boolean:
+		return obj.Value, true
+
+	case *object.Integer:
+		if cLikeLogic {
+			return obj.Value != 0, true
+		}
+
+	case *object.BigInt:
+		if cLikeLogic {
+			return obj.Value.Sign() != 0, true
+		}
+	}
+
+	return false, false
+}
+
+// logicalResult returns the result of a logical operation
+// in C-like mode, an operation that involved an integer returns 1 or 0 instead of a boolean
+func logicalResult(value bool, integer bool) object.Object {
+	if !integer {
+		return boolObject(value)
+	}
+
+	if value {
+		return intObject(1)
+	}
+	return intObject(0)
+}
+
 // evalIfExpression returns an evaluated result of the if expression
 func evalIfExpression(i *ast.IfExpression, env *object.Environment) object.Object {
-	condition := Eval(i.Condition, env)
+	condition := evalCondition(i.Condition, env)
 	if isError(condition) {
 		return condition
 	}
@@ -280,11 +735,164 @@ func evalIfExpression(i *ast.IfExpression, env *object.Environment) object.Objec
 		return Eval(i.Consequence, env)
 	} else if i.Alternative != nil {
 		return Eval(i.Alternative, env)
+	} else if i.ElseIf != nil {
+		return Eval(i.ElseIf, env)
 	} else {
 		return NULL
 	}
 }
 
+// evalDoWhileExpression runs the body of the loop and then checks the condition, so the body always runs at least once.
+// a loop evaluates to null unless a return or an error stops it. iteration, when set, is given the value of every run of the body
+func evalDoWhileExpression(d *ast.DoWhileExpression, env *object.Environment, iteration func(object.Object)) object.Object {
+	for {
+		if err := checkCancelled(env); err != nil {
+			return err
+		}
+
+		result := Eval(d.Body, env)
+		if isErrorOrReturn(result) {
+			return result
+		}
+
+		if iteration != nil {
+			iteration(result)
+		}
+
+		condition := evalCondition(d.Condition, env)
+		if isError(condition) {
+			return condition
+		}
+
+		if !isTruthy(condition) {
+			return NULL
+		}
+	}
+}
+
+// evalForInExpression runs the body of the loop once for every element of an array or value of an iterator.
+// iterators are consumed one value at a time so the values never have to exist at the same time.
+// like a do while loop it evaluates to null unless a return or an error stops it and it passes the value of every run of the body to iteration
+func evalForInExpression(f *ast.ForInExpression, env *object.Environment, iteration func(object.Object)) object.Object {
+	iterable := Eval(f.Iterable, env)
+	if isError(iterable) {
+		return iterable
+	}
+
+	var next func() (object.Object, bool)
+
+	switch iterable := iterable.(type) {
+	case *object.Array:
+		// the elements are read by index so assignments to the array inside the body are seen by the loop
+		i := 0
+		next = func() (object.Object, bool) {
+			if i >= len(iterable.Elements) {
+				return nil, false
+			}
+			i++
+			return iterable.Elements[i-1], true
+		}
+
+	case *object.Iterator:
+		next = iterable.Next
+
+	default:
+		return newTypedError(object.TYPE_ERROR, "cannot loop over %s", iterable.Type())
+	}
+
+	for {
+		value, ok := next()
+		if !ok {
+			return NULL
+		}
+
+		// a generator hands out the error that stopped it
+		if isError(value) {
+			return value
+		}
+
+		if err := checkCancelled(env); err != nil {
+			return err
+		}
+
+		env.Set(f.Variable.Value, value)
+
+		result := Eval(f.Body, env)
+		if isErrorOrReturn(result) {
+			return result
+		}
+
+		if iteration != nil {
+			iteration(result)
+		}
+	}
+}
+
+// evalCollectExpression runs a loop and returns an array holding the value of every run of its body, a body without a value adds null
+func evalCollectExpression(c *ast.CollectExpression, env *object.Environment) object.Object {
+	elements := []object.Object{}
+	collect := func(value object.Object) {
+		if value == nil {
+			value = NULL
+		}
+		elements = append(elements, value)
+	}
+
+	var result object.Object
+
+	switch loop := c.Loop.(type) {
+	case *ast.DoWhileExpression:
+		result = evalDoWhileExpression(loop, env, collect)
+
+	case *ast.ForInExpression:
+		result = evalForInExpression(loop, env, collect)
+
+	default:
+		return newTypedError(object.TYPE_ERROR, "collect needs a loop, got: %s", c.Loop.String())
+	}
+
+	if isErrorOrReturn(result) {
+		return result
+	}
+
+	return &object.Array{Elements: elements}
+}
+
+// evalTryExpression runs the try block and, when it fails with a runtime error, the catch block with the error message bound to the catch parameter.
+// like the loop variable, the parameter is bound in the enclosing environment. return values pass through untouched
+func evalTryExpression(t *ast.TryExpression, env *object.Environment) object.Object {
+	result := Eval(t.Block, env)
+
+	errorObject, ok := result.(*object.Error)
+	if !ok {
+		return result
+	}
+
+	// a cancelled evaluation has to stop so its error can not be caught
+	if err := checkCancelled(env); err != nil {
+		return err
+	}
+
+	env.Set(t.Parameter.Value, &object.CaughtError{Message: errorObject.Message, Kind: errorObject.Kind})
+
+	return Eval(t.Catch, env)
+}
+
+// evalCondition evaluates the condition of an if expression or a loop
+// in strict mode the condition has to be a boolean
+func evalCondition(node ast.Expression, env *object.Environment) object.Object {
+	condition := Eval(node, env)
+	if isError(condition) {
+		return condition
+	}
+
+	if strict && condition.Type() != object.BOOLEAN_OBJECT {
+		return newTypedError(object.TYPE_ERROR, "condition must be boolean, got: %s", condition.Type())
+	}
+
+	return condition
+}
+
 // isTruthy checks if an expression can be evaluated or skipped
 func isTruthy(object object.Object) bool {
 	switch object {
@@ -303,9 +911,14 @@ func isTruthy(object object.Object) bool {
 }
 
 // newError returns a meaningful error message to the user of the jaba program when they write unexpected jaba code
-// it uses the standard golang Sprintf to format the error message
+// it uses the standard golang Sprintf to format the error message. the error is of the generic kind, see newTypedError
 func newError(format string, a ...interface{}) *object.Error {
-	return &object.Error{Message: fmt.Sprintf(format, a...)}
+	return newTypedError(object.GENERIC_ERROR, format, a...)
+}
+
+// newTypedError returns an error of the given kind, catch blocks can tell the kind apart with error_kind
+func newTypedError(kind object.ErrorKind, format string, a ...interface{}) *object.Error {
+	return &object.Error{Message: fmt.Sprintf(format, a...), Kind: kind}
 }
 
 // isError is a helper function that helps check error early and allows them not to stray far away from their origin
@@ -316,6 +929,38 @@ func isError(obj object.Object) bool {
 	return false
 }
 
+// isErrorOrReturn checks if the object is an error or a return value
+// both have to be passed up unchanged instead of being used as an operand or stored in a container
+func isErrorOrReturn(obj object.Object) bool {
+	if obj != nil {
+		return obj.Type() == object.ERROR_OBJECT || obj.Type() == object.RETURN_VALUE_OBJECT
+	}
+	return false
+}
+
+// evalDestructuringLetStatement binds every element of the evaluated array to the name in the same position
+func evalDestructuringLetStatement(node *ast.DestructuringLetStatement, env *object.Environment) object.Object {
+	value := Eval(node.Value, env)
+	if isErrorOrReturn(value) {
+		return value
+	}
+
+	array, ok := value.(*object.Array)
+	if !ok {
+		return newTypedError(object.TYPE_ERROR, "cannot destructure %s, want: ARRAY", value.Type())
+	}
+
+	if len(array.Elements) != len(node.Names) {
+		return newTypedError(object.VALUE_ERROR, "wrong number of values to destructure. got: %d want: %d", len(array.Elements), len(node.Names))
+	}
+
+	for i, name := range node.Names {
+		env.Set(name.Value, array.Elements[i])
+	}
+
+	return nil
+}
+
 // evalIdentifier uses the environment to get the identifier object otherwise returns an error
 func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
 	if key, ok := env.Get(node.Value); ok {
@@ -326,25 +971,75 @@ func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object
 		return builtin
 	}
 
-	return newError("identifier not found: %s", node.Value)
+	return newTypedError(object.NAME_ERROR, "identifier not found: %s", node.Value)
 }
 
 // evalExpressions is a helper function that helps evaluate a list of expressions
-// the expressions are evaluated from left to right
+// the expressions are evaluated from left to right, a spread expression adds every element of its array to the list
+// an error or a return value stops the evaluation and is returned on its own so it is never stored as an element
 func evalExpressions(expressions []ast.Expression, env *object.Environment) []object.Object {
-	var evaluated []object.Object
+	evaluated := make([]object.Object, 0, len(expressions))
 
 	for _, expression := range expressions {
+		spread, isSpread := expression.(*ast.SpreadExpression)
+		if isSpread {
+			expression = spread.Value
+		}
+
 		result := Eval(expression, env)
-		if isError(result) {
+		if isErrorOrReturn(result) {
 			return []object.Object{result}
 		}
-		evaluated = append(evaluated, result)
+
+		if !isSpread {
+			evaluated = append(evaluated, result)
+			continue
+		}
+
+		array, ok := result.(*object.Array)
+		if !ok {
+			return []object.Object{newTypedError(object.TYPE_ERROR, "cannot spread %s, only an ARRAY can be spread", result.Type())}
+		}
+		evaluated = append(evaluated, array.Elements...)
 	}
 
 	return evaluated
 }
 
+// evalCallee evaluates the function of a call expression.
+// A function that is called straight out of a hash, as in obj["method"](), gets self bound to the hash
+// so it can reach the other pairs of the hash
+func evalCallee(callee ast.Expression, env *object.Environment) object.Object {
+	index, ok := callee.(*ast.IndexExpression)
+	if !ok {
+		return Eval(callee, env)
+	}
+
+	left := Eval(index.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	key := Eval(index.Index, env)
+	if isError(key) {
+		return key
+	}
+
+	value := evalIndexExpression(left, key)
+
+	hash, isHash := left.(*object.Hash)
+	function, isFunction := value.(*object.Function)
+	if !isHash || !isFunction {
+		return value
+	}
+
+	method := *function
+	method.Env = object.NewEnclosedEnvironment(function.Env)
+	method.Env.Set("self", hash)
+
+	return &method
+}
+
 // applyFunctions is a helper function that helps evaluate a function considering its scope
 // it supports higher order functions (functions that return other functions or pass them as arguments)
 // and closures (function that close over the environment they were defined in).
@@ -353,35 +1048,117 @@ func applyFunctions(fn object.Object, args []object.Object) object.Object {
 	switch function := fn.(type) {
 
 	case *object.Function:
-		extendedEnv := extendFunctionEnv(function, args)
-		evaluated := Eval(function.Body, extendedEnv)
+		// the depth is counted per evaluation so programs evaluated at the same time in other environments do not add to it
+		evaluation := function.Env.Evaluation()
+		if evaluation.CallDepth >= maxCallDepth {
+			return newError("maximum recursion depth exceeded")
+		}
+
+		if err := checkCancelled(function.Env); err != nil {
+			return err
+		}
+
+		extendedEnv, err := extendFunctionEnv(function, args)
+		if err != nil {
+			return err
+		}
+
+		if function.Generator {
+			return newGenerator(function, extendedEnv)
+		}
+
+		evaluation.CallDepth++
+		evaluated := evalFunctionBody(function, extendedEnv)
+		evaluation.CallDepth--
+
 		return unwrapReturnValue(evaluated)
 
 	case *object.Builtin:
 		return function.Function(args...)
 
 	default:
-		return newError("not a function: %s", fn.Type())
+		return newTypedError(object.TYPE_ERROR, "not a function: %s", fn.Type())
 
 	}
 }
 
+// newGenerator returns an iterator over the values a generator function yields.
+// The body runs in its own goroutine which is paused at every yield until the iterator is asked for the next value,
+// so only one of the body and its caller runs at a time. An error in the body is the last value of the iterator.
+// A generator that is dropped before it finishes leaves its goroutine waiting at a yield
+func newGenerator(fn *object.Function, env *object.Environment) *object.Iterator {
+	values := make(chan object.Object)
+	resume := make(chan struct{})
+	started, finished := false, false
+
+	env.Set("yield", &object.Builtin{Function: func(args ...object.Object) object.Object {
+		values <- args[0]
+		<-resume
+		return NULL
+	}})
+
+	return object.NewIterator(func() (object.Object, bool) {
+		if finished {
+			return nil, false
+		}
+
+		if started {
+			resume <- struct{}{}
+		} else {
+			started = true
+			go func() {
+				defer close(values)
+
+				if result := Eval(fn.Body, env); isError(result) {
+					values <- result
+				}
+			}()
+		}
+
+		value, ok := <-values
+		if !ok || isError(value) {
+			finished = true
+		}
+
+		return value, ok
+	})
+}
+
 // extendFunctionEnv is a helper function that helps extend the environment of a function
-// by scoping the function environment in an enclosed hash
-func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Environment {
-	env := object.NewEnclosedEnvironment(fn.Env)
+// by scoping the function environment in an enclosed environment that holds the arguments.
+// Parameters the caller left out get their default value, which is evaluated in the environment the function was defined in
+func extendFunctionEnv(fn *object.Function, args []object.Object) (*object.Environment, object.Object) {
+	names := fn.ParameterNames()
+	values := make([]object.Object, len(names))
+
+	for i := range names {
+		if i < len(args) {
+			values[i] = args[i]
+			continue
+		}
+
+		if fn.Defaults == nil || fn.Defaults[i] == nil {
+			return nil, newTypedError(object.ARGUMENT_ERROR, "wrong number of arguments. got: %d want: %d", len(args), len(names))
+		}
 
-	for i, param := range fn.Parameters {
-		env.Set(param.Value, args[i])
+		value := Eval(fn.Defaults[i], fn.Env)
+		if isError(value) {
+			return nil, value
+		}
+		values[i] = unwrapReturnValue(value)
 	}
 
-	return env
+	return object.NewFunctionEnvironment(fn.Env, names, values), nil
 }
 
 // unwrapReturnValue is a helper function that helps give the value the function returns after executing
+// a function with an empty body gives the shared NULL object
 func unwrapReturnValue(result object.Object) object.Object {
 	if returnValue, ok := result.(*object.ReturnValue); ok {
-		return returnValue.Value
+		result = returnValue.Value
+	}
+	if result == nil {
+		return NULL
 	}
 	return result
 }
@@ -389,15 +1166,56 @@ func unwrapReturnValue(result object.Object) object.Object {
 // evalStringInfixExpression is a helper function that helps evaluate string concatenation
 func evalStringInfixExpression(operator string, left, right object.Object) object.Object {
 	if operator != "+" {
-		return newError("unknown operation: %s %s %s", left.Type(), operator, right.Type())
+		return newTypedError(object.TYPE_ERROR, "unknown operation: %s %s %s", left.Type(), operator, right.Type())
 	}
 
 	leftValue := left.(*object.String).Value
 	rightValue := right.(*object.String).Value
 
+	if err := checkStringLength(len(leftValue) + len(rightValue)); err != nil {
+		return err
+	}
+
 	return &object.String{Value: leftValue + rightValue}
 }
 
+// evalStringRepetition repeats a string count times e.g. "ab" * 3 is "ababab"
+func evalStringRepetition(str *object.String, count *object.Integer) object.Object {
+	if count.Value < 0 {
+		return newTypedError(object.VALUE_ERROR, "cannot repeat a string a negative number of times, got: %d", count.Value)
+	}
+
+	return repeatString(str.Value, count.Value)
+}
+
+// repeatString repeats a string count times as long as the result is not longer than the maximum string length
+// the length is checked before it is computed so a huge count can not overflow it
+func repeatString(value string, count int64) object.Object {
+	if len(value) > 0 && count > int64(maxStringLength/len(value)) {
+		return checkStringLength(maxStringLength + 1)
+	}
+
+	return &object.String{Value: strings.Repeat(value, int(count))}
+}
+
+// evalHashInfixExpression returns a new hash with the pairs of both hashes when the operator is +
+// pairs of the right hash override pairs of the left hash with the same key and keep the place of the left pair, neither operand is changed
+func evalHashInfixExpression(operator string, left, right object.Object) object.Object {
+	if operator != "+" {
+		return newTypedError(object.TYPE_ERROR, "unknown operation: %s %s %s", left.Type(), operator, right.Type())
+	}
+
+	hash := object.NewHash()
+
+	for _, operand := range []object.Object{left, right} {
+		for _, pair := range operand.(*object.Hash).OrderedPairs() {
+			hash.Set(pair.Key.(object.Hashable).HashKey(), pair)
+		}
+	}
+
+	return hash
+}
+
 // evalIndexExpression evaluates indices for a given expression
 func evalIndexExpression(left, index object.Object) object.Object {
 	switch {
@@ -409,7 +1227,7 @@ func evalIndexExpression(left, index object.Object) object.Object {
 		return evalHashIndexExpression(left, index)
 
 	default:
-		return newError("index operator not supported: %s", left.Type())
+		return newTypedError(object.TYPE_ERROR, "index operator not supported: %s", left.Type())
 	}
 }
 
@@ -427,33 +1245,30 @@ func evalArrayIndexExpression(array, index object.Object) object.Object {
 	return arrayObject.Elements[indexValue]
 }
 
-// evalHashLiteral evaluates jaba hash literals
+// evalHashLiteral evaluates jaba hash literals, the pairs are evaluated and set in the order they appear in the source
 func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
-	pairs := make(map[object.HashKey]object.HashPair)
+	hash := object.NewHash()
 
-	for keyNode, valueNode := range node.Pairs {
+	for _, keyNode := range node.Keys {
 		key := Eval(keyNode, env)
-		if isError(key) {
+		if isErrorOrReturn(key) {
 			return key
 		}
 
 		hashKey, ok := key.(object.Hashable)
 		if !ok {
-			return newError("unable to hash key:  %s", key.Type())
+			return newTypedError(object.TYPE_ERROR, "unable to hash key:  %s", key.Type())
 		}
 
-		value := Eval(valueNode, env)
-		if isError(value) {
+		value := Eval(node.Pairs[keyNode], env)
+		if isErrorOrReturn(value) {
 			return value
 		}
 
-		hashed := hashKey.HashKey()
-
-		pairs[hashed] = object.HashPair{Key: key, Value: value}
-
+		hash.Set(hashKey.HashKey(), object.HashPair{Key: key, Value: value})
 	}
 
-	return &object.Hash{Pairs: pairs}
+	return hash
 }
 
 // evalHashIndexExpression evaluates indices for a hash expression
@@ -462,7 +1277,7 @@ func evalHashIndexExpression(hash, index object.Object) object.Object {
 
 	key, ok := index.(object.Hashable)
 	if !ok {
-		return newError("unusable as hash key: %s", index.Type())
+		return newTypedError(object.TYPE_ERROR, "unusable as hash key: %s", index.Type())
 	}
 
 	pair, ok := hashObject.Pairs[key.HashKey()]
@@ -474,3 +1289,80 @@ func evalHashIndexExpression(hash, index object.Object) object.Object {
 	return pair.Value
 
 }
+
+// evalAssignExpression assigns a value to an existing binding or to an index of an array or hash
+// it returns the assigned value which allows chaining assignments e.g. a = b = 5
+func evalAssignExpression(node *ast.AssignExpression, env *object.Environment) object.Object {
+	value := Eval(node.Value, env)
+	if isErrorOrReturn(value) {
+		return value
+	}
+
+	switch target := node.Target.(type) {
+	case *ast.Identifier:
+		if !env.Assign(target.Value, value) {
+			return newTypedError(object.NAME_ERROR, "identifier not found: %s", target.Value)
+		}
+
+	case *ast.IndexExpression:
+		left := Eval(target.Left, env)
+		if isErrorOrReturn(left) {
+			return left
+		}
+
+		index := Eval(target.Index, env)
+		if isErrorOrReturn(index) {
+			return index
+		}
+
+		if result := evalIndexAssignment(left, index, value); isError(result) {
+			return result
+		}
+
+	default:
+		return newTypedError(object.TYPE_ERROR, "invalid assignment target: %s", node.Target.String())
+	}
+
+	return value
+}
+
+// evalIndexAssignment replaces the element of an array or sets the value of a hash key in place
+func evalIndexAssignment(left, index, value object.Object) object.Object {
+	switch left := left.(type) {
+	case *object.Array:
+		if left.Frozen {
+			return newTypedError(object.VALUE_ERROR, "cannot modify frozen value")
+		}
+
+		integer, ok := index.(*object.Integer)
+		if !ok {
+			return newTypedError(object.TYPE_ERROR, "array index must be an integer, got: %s", index.Type())
+		}
+
+		if integer.Value < 0 || integer.Value >= int64(len(left.Elements)) {
+			return newTypedError(object.VALUE_ERROR, "index out of range: %d", integer.Value)
+		}
+
+		left.Elements[integer.Value] = value
+
+	case *object.Hash:
+		if left.Frozen {
+			return newTypedError(object.VALUE_ERROR, "cannot modify frozen value")
+		}
+
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return newTypedError(object.TYPE_ERROR, "unusable as hash key: %s", index.Type())
+		}
+
+		left.Set(key.HashKey(), object.HashPair{Key: index, Value: value})
+
+	case *object.String:
+		return newTypedError(object.TYPE_ERROR, "strings are immutable")
+
+	default:
+		return newTypedError(object.TYPE_ERROR, "index assignment not supported: %s", left.Type())
+	}
+
+	return value
+}
@@ -1,8 +1,17 @@
 package evaluator
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/maxwellgithinji/jaba/pkg/ast"
 	"github.com/maxwellgithinji/jaba/pkg/lexer"
 	"github.com/maxwellgithinji/jaba/pkg/object"
 	"github.com/maxwellgithinji/jaba/pkg/parser"
@@ -61,6 +70,9 @@ func TestEvalIntegerExpression(t *testing.T) {
 		{"10", 10},
 		{"-5", -5},
 		{"-10", -10},
+		{"--5", 5},
+		{"---5", -5},
+		{"5 - -5", 10},
 		{"5 + 5 + 5 + 5 -10", 10},
 		{"2 * 2 * 2 * 2 * 2", 32},
 		{"5 * 2 + 10", 20},
@@ -102,6 +114,33 @@ func TestEvalBooleanExpression(t *testing.T) {
 	}
 }
 
+func TestNullEquality(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"let n = if (false) { 1 }; n == n", true},
+		{"let n = if (false) { 1 }; n == if (false) { 2 }", true},
+		{"let n = if (false) { 1 }; n != n", false},
+		{"let n = if (false) { 1 }; n == 0", false},
+		{"let n = if (false) { 1 }; 0 == n", false},
+		{"let n = if (false) { 1 }; n != 0", true},
+		{"let n = if (false) { 1 }; n == false", false},
+		{"let n = if (false) { 1 }; false != n", true},
+		{`let n = if (false) { 1 }; n == ""`, false},
+		{"let n = if (false) { 1 }; n == []", false},
+		{"let n = if (false) { 1 }; n == {}", false},
+		{"let n = if (false) { 1 }; n == 9223372036854775807 + 1", false},
+		{"let n = if (false) { 1 }; n == fn() {}", false},
+		{"let n = if (false) { 1 }; n == puts", false},
+		{`let h = {"a": 1}; let get = fn(key) { h[key] }; get("b") == get("c")`, true},
+	}
+
+	for _, tt := range tests {
+		testBooleanObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
 func TestNopeOperator(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -144,12 +183,15 @@ func TestIfElseExpression(t *testing.T) {
 		{"if (1 < 2) {10} else {20};", 10},
 		{
 			`
-			if (10 > 1) {
-				if (10 > 1){
-					return 10;
+			let f = fn() {
+				if (10 > 1) {
+					if (10 > 1){
+						return 10;
+					}
+					return 1;
 				}
-				return 1;
-			}
+			};
+			f();
 			`,
 			10,
 		},
@@ -167,16 +209,40 @@ func TestIfElseExpression(t *testing.T) {
 	}
 }
 
+func TestElseIfExpression(t *testing.T) {
+	input := `
+	let classify = fn(x) {
+		if (x < 0) {
+			"negative"
+		} else if (x == 0) {
+			"zero"
+		} else {
+			"positive"
+		}
+	};
+	[classify(-5), classify(0), classify(5)]
+	`
+
+	evaluated := testEval(input)
+	if evaluated.Inspect() != "[negative, zero, positive]" {
+		t.Errorf("wrong result, got: %s", evaluated.Inspect())
+	}
+
+	testNullObject(t, testEval("if (false) { 1 } else if (false) { 2 }"))
+	testIntegerObject(t, testEval("if (false) { 1 } else if (false) { 2 } else if (true) { 3 } else { 4 }"), 3)
+}
+
 func TestReturnStatements(t *testing.T) {
 	tests := []struct {
 		input    string
 		expected int64
 	}{
-		{"return 10;", 10},
-		{"return 10; 9;", 10},
-		{"9; return 10;", 10},
-		{"return 2 * 5; 9;", 10},
-		{"9; 9; return 2 * 5; 9;", 10},
+		{"fn() { return 10; }()", 10},
+		{"fn() { return 10; 9; }()", 10},
+		{"fn() { 9; return 10; }()", 10},
+		{"fn() { return 2 * 5; 9; }()", 10},
+		{"fn() { 9; 9; return 2 * 5; 9; }()", 10},
+		{"let f = fn() { for (x in [1, 2, 3]) { if (x == 2) { return x * 5; } } }; f();", 10},
 	}
 
 	for _, tt := range tests {
@@ -186,6 +252,30 @@ func TestReturnStatements(t *testing.T) {
 	}
 }
 
+func TestReturnOutsideFunction(t *testing.T) {
+	tests := []string{
+		"return 10;",
+		"9; return 10; 11;",
+		"if (true) { return 10; }",
+		"for (x in [1, 2]) { return x; }",
+		`fn() { eval("return 10") }()`,
+	}
+
+	for _, input := range tests {
+		evaluated := testEval(input)
+
+		errorObject, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q, got: %T (%+v)", input, evaluated, evaluated)
+			continue
+		}
+
+		if errorObject.Message != "return outside function" {
+			t.Errorf("wrong error message. expected: %q, got: %q", "return outside function", errorObject.Message)
+		}
+	}
+}
+
 func TestErrorHandling(t *testing.T) {
 	test := []struct {
 		input    string
@@ -310,6 +400,45 @@ func TestFunctionApplication(t *testing.T) {
 	}
 }
 
+func TestFunctionParameterDefaults(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let add = fn(x, y = 10) { x + y }; add(5)", 15},
+		{"let add = fn(x, y = 10) { x + y }; add(5, 1)", 6},
+		{"let f = fn(x = 1, y = 2) { x * 10 + y }; f()", 12},
+		{"let f = fn(x = 1, y = 2) { x * 10 + y }; f(3)", 32},
+		{"let base = 100; let f = fn(x = base) { x }; let base = 7; f()", 7},
+		{"let make = fn(n) { fn(x = n * 2) { x } }; make(4)()", 8},
+		{"let count = 0; let next = fn() { count = count + 1 }; let f = fn(x = next()) { x }; f(); f(); f(50); count", 2},
+		{"let f = fn(x, y = 1) { x + y }; f()", errorMessage("wrong number of arguments. got: 0 want: 2")},
+		{"let f = fn(x, y) { x + y }; f(1)", errorMessage("wrong number of arguments. got: 1 want: 2")},
+		{"let f = fn(x = missing) { x }; f()", errorMessage("identifier not found: missing")},
+		{"let f = fn(x = missing) { x }; f(1)", 1},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+
+		case errorMessage:
+			errorObject, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q, got: %T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+
+			if errorObject.Message != string(expected) {
+				t.Errorf("wrong error message. expected: %q, got: %q", expected, errorObject.Message)
+			}
+		}
+	}
+}
+
 func TestClosures(t *testing.T) {
 	input := `
 	let newAdder = fn(x) {
@@ -338,6 +467,21 @@ func TestStringLiteral(t *testing.T) {
 	}
 }
 
+func TestUnicodeIdentifiersAndStrings(t *testing.T) {
+	input := `let grüße = "héllo "; let wörld = "🌍"; grüße + wörld;`
+
+	evaluated := testEval(input)
+
+	stringObject, ok := evaluated.(*object.String)
+	if !ok {
+		t.Fatalf("evaluated is not *object.String, got: %T(%+v)", evaluated, evaluated)
+	}
+
+	if stringObject.Value != "héllo 🌍" {
+		t.Fatalf("stringObject.Value is not %q, got %q", "héllo 🌍", stringObject.Value)
+	}
+}
+
 func TestStringConcatenation(t *testing.T) {
 
 	input := `"hello" + " " + "world";`
@@ -364,19 +508,58 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`len("four");`, 4},
 		{`len("hello world")`, 11},
 		{`len(1);`, "argument to len not supported, got: INTEGER"},
-		{`len("one", "two")`, "wrong number of arguments. got: 2 want: 1"},
+		{`len("one", "two")`, "len: wrong number of arguments. got: 2 want: 1"},
 		{`len([1, 2, 3]);`, 3},
 		{`len([]);`, 0},
+		{`len({"a": 1, "b": 2})`, 2},
+		{`len({})`, 0},
+		{`len("héllo")`, 5},
+		{`len("🌍")`, 1},
+		{`byte_len("héllo")`, 6},
+		{`byte_len("🌍")`, 4},
+		{`byte_len("")`, 0},
+		{`byte_len([1])`, "argument to byte_len must be a string, got: ARRAY"},
+		{`byte_len("a", "b")`, "byte_len: wrong number of arguments. got: 2 want: 1"},
 		{`first([1, 2, 3])`, 1},
-		{`first(1)`, "argument to first must be an array, got: INTEGER"},
+		{`first(1)`, "argument to first must be an array or string, got: INTEGER"},
 		{`first([])`, nil},
 		{`last([1, 2, 3])`, 3},
 		{`last([])`, nil},
-		{`last(1)`, "argument to last must be an array, got: INTEGER"},
+		{`last(1)`, "argument to last must be an array or string, got: INTEGER"},
 		{`rest([1, 2, 3])`, []int{2, 3}},
 		{`rest([])`, nil},
+		{`rest(1)`, "argument to rest must be an array or string, got: INTEGER"},
 		{`push([], 1)`, []int{1}},
-		{`push(1, 1)`, "argument to push must be an array, got: INTEGER"},
+		{`push([1], 2)`, []int{1, 2}},
+		{`push(1, 1)`, "argument to push must be an array or string, got: INTEGER"},
+		{`push("ab", 1)`, "second argument to push must be a string when pushing onto a string, got: INTEGER"},
+		{`get_or([1, 2, 3], 1, 0)`, 2},
+		{`get_or([1, 2, 3], 3, 0)`, 0},
+		{`get_or([], 0, 7)`, 7},
+		{`get_or([1, 2, 3], -1, 0)`, 3},
+		{`get_or([1, 2, 3], -3, 0)`, 1},
+		{`get_or([1, 2, 3], -4, 0)`, 0},
+		{`get_or(1, 0, 0)`, "first argument to get_or must be an array, got: INTEGER"},
+		{`get_or([1], "0", 0)`, "second argument to get_or must be an integer, got: STRING"},
+		{`get_or([1], 0)`, "get_or: wrong number of arguments. got: 2 want: 3"},
+		{`assert(1 < 2)`, nil},
+		{`assert(true, "never shown")`, nil},
+		{`assert(1 > 2)`, "assertion failed"},
+		{`assert(false, "numbers are broken")`, "numbers are broken"},
+		{`assert(if (false) { 1 }, "null is falsy")`, "null is falsy"},
+		{`assert(false, 1)`, "second argument to assert must be a string, got: INTEGER"},
+		{`assert()`, "assert: wrong number of arguments. got: 0 want: 1 or 2"},
+		{`iterate(fn(x) { x * 2 }, 1, 3)`, 8},
+		{`iterate(fn(x) { x * 2 }, 5, 0)`, 5},
+		{`iterate(len, "four", 1)`, 4},
+		{`iterate(fn(x) { x + 1 }, "a", 1)`, "type mismatch: STRING + INTEGER"},
+		{`iterate(1, 1, 1)`, "first argument to iterate must be a function, got: INTEGER"},
+		{`iterate(fn(x) { x }, 1, "3")`, "third argument to iterate must be an integer, got: STRING"},
+		{`iterate(fn(x) { x }, 1, -1)`, "count for iterate must not be negative, got: -1"},
+		{`len(repeat("ab", 3))`, 6},
+		{`repeat(1, 3)`, "first argument to repeat must be a string, got: INTEGER"},
+		{`repeat("a", "3")`, "second argument to repeat must be an integer, got: STRING"},
+		{`repeat("a", -1)`, "count for repeat must not be negative, got: -1"},
 	}
 
 	for _, tt := range tests {
@@ -418,6 +601,49 @@ func TestBuiltinFunctions(t *testing.T) {
 	}
 }
 
+func TestSequenceBuiltinsOnStrings(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`first("hello")`, "h"},
+		{`last("hello")`, "o"},
+		{`first("a")`, "a"},
+		{`last("a")`, "a"},
+		{`first("héllo")`, "h"},
+		{`last("olé")`, "é"},
+		{`first("")`, nil},
+		{`last("")`, nil},
+		{`rest("hello")`, "ello"},
+		{`rest("h")`, ""},
+		{`rest("héllo")`, "éllo"},
+		{`rest("")`, nil},
+		{`push("ab", "c")`, "abc"},
+		{`push("", "c")`, "c"},
+		{`let s = "ab"; push(s, "c"); s`, "ab"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		expected, ok := tt.expected.(string)
+		if !ok {
+			testNullObject(t, evaluated)
+			continue
+		}
+
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("evaluated %q is not *object.String, got: %T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+
+		if str.Value != expected {
+			t.Errorf("wrong value for %q. expected: %q, got: %q", tt.input, expected, str.Value)
+		}
+	}
+}
+
 func TestArrayLiterals(t *testing.T) {
 	input := `[1, 2 * 2, 3 + 3]`
 
@@ -467,6 +693,24 @@ func TestArrayIndexExpressions(t *testing.T) {
 	}
 }
 
+func TestChainedCallAndIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let makeArray = fn() { [1, 2, 3] }; makeArray()[1]", 2},
+		{"let arr = [fn(x) { x * 2 }]; arr[0](5)", 10},
+		{`let hash = {"fn": fn(x) { x + 1 }}; hash["fn"](2)`, 3},
+		{"let getFns = fn() { [fn() { 7 }] }; getFns()[0]()", 7},
+		{"let adder = fn(a) { fn(b) { [a + b, a - b] } }; adder(5)(3)[1]", 2},
+		{"let grid = fn() { [[1, 2], [3, 4]] }; grid()[1][0] + grid()[0][1]", 5},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
 func TestHashLiterals(t *testing.T) {
 	input := `let two = "two";
 	{
@@ -509,6 +753,137 @@ func TestHashLiterals(t *testing.T) {
 
 }
 
+func TestHashLiteralComputedKeys(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let k = "name"; {k: "jaba"}["name"]`, "jaba"},
+		{`{1 + 1: "two"}[2]`, "two"},
+		{`let key = fn() { true }; {key(): "yes"}[true]`, "yes"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		stringObject, ok := evaluated.(*object.String)
+		if !ok {
+			t.Fatalf("evaluated is not *object.String, got: %T(%+v)", evaluated, evaluated)
+		}
+
+		if stringObject.Value != tt.expected {
+			t.Errorf("stringObject.Value is not %q, got %q", tt.expected, stringObject.Value)
+		}
+	}
+
+	evaluated := testEval(`let k = [1]; {k: 1}`)
+	errorObject, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned, got: %T (%+v)", evaluated, evaluated)
+	}
+
+	if errorObject.Message != "unable to hash key:  ARRAY" {
+		t.Errorf("wrong error message, got: %q", errorObject.Message)
+	}
+}
+
+func TestStringRepetition(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`"ab" * 3`, "ababab"},
+		{`3 * "ab"`, "ababab"},
+		{`"x" * 0`, ""},
+		{`"" * 5`, ""},
+		{`"-" * 2 + "+"`, "--+"},
+		{`"x" * -1`, errorMessage("cannot repeat a string a negative number of times, got: -1")},
+		{`-2 * "x"`, errorMessage("cannot repeat a string a negative number of times, got: -2")},
+		{`"x" - 1`, errorMessage("type mismatch: STRING - INTEGER")},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case string:
+			stringObject, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("evaluated is not *object.String, got: %T(%+v)", evaluated, evaluated)
+				continue
+			}
+
+			if stringObject.Value != expected {
+				t.Errorf("stringObject.Value is not %q, got %q", expected, stringObject.Value)
+			}
+
+		case errorMessage:
+			errorObject, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned, got: %T (%+v)", evaluated, evaluated)
+				continue
+			}
+
+			if errorObject.Message != string(expected) {
+				t.Errorf("wrong error message. expected: %q, got: %q", expected, errorObject.Message)
+			}
+		}
+	}
+}
+
+// errorMessage marks an expected value in a test table as the message of an error object
+type errorMessage string
+
+// caughtError marks an expected value in a test table as the message of a caught error
+type caughtError string
+
+func TestHashMerge(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected map[string]int64
+	}{
+		{`{"a": 1} + {"b": 2}`, map[string]int64{"a": 1, "b": 2}},
+		{`{"a": 1} + {"a": 2}`, map[string]int64{"a": 2}},
+		{`{"a": 1, "b": 2} + {}`, map[string]int64{"a": 1, "b": 2}},
+		{`let l = {"a": 1}; let r = {"a": 2, "c": 3}; let m = l + r; l`, map[string]int64{"a": 1}},
+		{`let l = {"a": 1}; let r = {"a": 2, "c": 3}; let m = l + r; r`, map[string]int64{"a": 2, "c": 3}},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		hash, ok := evaluated.(*object.Hash)
+		if !ok {
+			t.Fatalf("evaluated is not *object.Hash, got: %T(%+v)", evaluated, evaluated)
+		}
+
+		if len(hash.Pairs) != len(tt.expected) {
+			t.Errorf("len(hash.Pairs) is not %d, got: %d", len(tt.expected), len(hash.Pairs))
+			continue
+		}
+
+		for key, value := range tt.expected {
+			pair, ok := hash.Pairs[(&object.String{Value: key}).HashKey()]
+			if !ok {
+				t.Errorf("no pair for key %q in %s", key, hash.Inspect())
+				continue
+			}
+
+			testIntegerObject(t, pair.Value, value)
+		}
+	}
+
+	evaluated := testEval(`{"a": 1} - {"a": 1}`)
+	errorObject, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned, got: %T (%+v)", evaluated, evaluated)
+	}
+
+	if errorObject.Message != "unknown operation: HASH - HASH" {
+		t.Errorf("wrong error message, got: %q", errorObject.Message)
+	}
+}
+
 func TestHashIndexExpressions(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -555,3 +930,2962 @@ func TestHashIndexExpressions(t *testing.T) {
 		}
 	}
 }
+
+func TestHashKeysOfDifferentTypesDoNotCollide(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{1: "a", "1": "b"}[1]`, "a"},
+		{`{1: "a", "1": "b"}["1"]`, "b"},
+		{`{"1": "b", 1: "a"}[1]`, "a"},
+		{`{1: "a", true: "b"}[true]`, "b"},
+		{`{1: "a", true: "b"}[1]`, "a"},
+		{`let h = {1: "a"}; h["1"] = "b"; h[1] + h["1"]`, "ab"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("%s: object is not String, got: %T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+
+		if str.Value != tt.expected {
+			t.Errorf("%s: wrong value. expected: %q, got: %q", tt.input, tt.expected, str.Value)
+		}
+	}
+
+	hash, ok := testEval(`{1: "a", "1": "b", true: "c"}`).(*object.Hash)
+	if !ok {
+		t.Fatalf("evaluated is not a hash")
+	}
+
+	if len(hash.Pairs) != 3 {
+		t.Errorf("hash has wrong number of pairs. expected: 3, got: %d", len(hash.Pairs))
+	}
+}
+
+func TestLogicalOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"true && true", true},
+		{"true && false", false},
+		{"false && true", false},
+		{"false || true", true},
+		{"false || false", false},
+		{"1 < 2 && 2 < 3", true},
+		{"false && foobar", false}, // short-circuits before evaluating the unknown identifier
+		{"true || foobar", true},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testBooleanObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestLogicalOperatorsRequireBooleans(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 && 0", "invalid operand for &&: INTEGER"},
+		{"true && 1", "invalid operand for &&: INTEGER"},
+		{"false || 0", "invalid operand for ||: INTEGER"},
+		{`"a" || true`, "invalid operand for ||: STRING"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errorObject, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Fatalf("evaluated is not *object.Error, got: %T(%+v)", evaluated, evaluated)
+		}
+
+		if errorObject.Message != tt.expected {
+			t.Errorf("errorObject.Message is not %s, got %s", tt.expected, errorObject.Message)
+		}
+	}
+}
+
+func TestCLikeLogicalOperators(t *testing.T) {
+	SetCLikeLogic(true)
+	defer SetCLikeLogic(false)
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"1 && 0", 0},
+		{"1 && 5", 1},
+		{"0 || 0", 0},
+		{"0 || 7", 1},
+		{"0 && foobar", 0},
+		{"true && 1", 1},
+		{"true && false", false},
+		{"false || true", true},
+		{"(9223372036854775807 + 1) && 1", 1},
+		{"0 || (9223372036854775807 + 1)", 1},
+		{"(9223372036854775807 + 1) && 0", 0},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		}
+	}
+}
+
+func TestZfill(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`zfill(42, 5)`, "00042"},
+		{`zfill(0, 3)`, "000"},
+		{`zfill(-42, 5)`, "-0042"},
+		{`zfill(12345, 3)`, "12345"},
+		{`zfill(-12345, 3)`, "-12345"},
+		{`zfill(7, 1)`, "7"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		stringObject, ok := evaluated.(*object.String)
+		if !ok {
+			t.Fatalf("evaluated is not *object.String, got: %T(%+v)", evaluated, evaluated)
+		}
+
+		if stringObject.Value != tt.expected {
+			t.Errorf("stringObject.Value is not %q, got %q", tt.expected, stringObject.Value)
+		}
+	}
+}
+
+func TestZfillErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`zfill(1)`, "zfill: wrong number of arguments. got: 1 want: 2"},
+		{`zfill("1", 3)`, "first argument to zfill must be an integer, got: STRING"},
+		{`zfill(1, "3")`, "second argument to zfill must be an integer, got: STRING"},
+		{`zfill(1, 0)`, "width for zfill must be positive, got: 0"},
+		{`zfill(1, -2)`, "width for zfill must be positive, got: -2"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errorObject, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Fatalf("evaluated is not *object.Error, got: %T(%+v)", evaluated, evaluated)
+		}
+
+		if errorObject.Message != tt.expected {
+			t.Errorf("errorObject.Message is not %s, got %s", tt.expected, errorObject.Message)
+		}
+	}
+}
+
+func TestReturnInsideContainerLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let f = fn() { let a = [1, if (true) { return 2; }, 3]; 4 }; f();", 2},
+		{`let f = fn() { let h = {"a": if (true) { return 5; }}; 6 }; f();`, 5},
+		{"let g = fn(x) { x }; let f = fn() { g(if (true) { return 7; }); 8 }; f();", 7},
+		{"let f = fn() { let a = [1, if (false) { return 2; }, 3]; len(a) }; f();", 3},
+		{"let x = 1; let f = fn() { x = if (true) { return 5; }; 1 }; f();", 5},
+		{"let x = 1; let f = fn() { x = if (true) { return 5; }; 1 }; f(); x", 1},
+		{"let a = [0]; let f = fn() { a[0] = if (true) { return 5; }; 1 }; f();", 5},
+		{"let a = [0]; let f = fn() { a[0] = if (true) { return 5; }; 1 }; f(); a[0]", 0},
+		{"let a = [0]; let f = fn() { a[if (true) { return 6; }] = 1; 1 }; f();", 6},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestDoWhileExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let i = 0; do { i = i + 1 } while (i < 5); i;", 5},
+		{"let i = 0; do { i = i + 1 } while (false); i;", 1},
+		{"let i = 10; do { i = i + 1 } while (i < 5); i;", 11},
+		{"let f = fn() { let i = 0; do { i = i + 1; if (i == 3) { return i; } } while (true); }; f();", 3},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+
+	testNullObject(t, testEval("do { 1 } while (false)"))
+}
+
+func TestTrace(t *testing.T) {
+	var out bytes.Buffer
+	SetTrace(&out)
+	defer SetTrace(nil)
+
+	testIntegerObject(t, testEval("1 + 2"), 3)
+
+	expected := `BEGIN Program
+	BEGIN ExpressionStatement
+		BEGIN InfixExpression
+			BEGIN IntegerLiteral
+			END IntegerLiteral -> 1
+			BEGIN IntegerLiteral
+			END IntegerLiteral -> 2
+		END InfixExpression -> 3
+	END ExpressionStatement -> 3
+END Program -> 3
+`
+
+	if out.String() != expected {
+		t.Errorf("wrong trace. expected:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
+
+func TestStrictConditions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"if (5) { 1 }", "condition must be boolean, got: INTEGER"},
+		{`if ("") { 1 } else { 2 }`, "condition must be boolean, got: STRING"},
+		{"let i = 0; do { i = i + 1 } while (i - 1)", "condition must be boolean, got: INTEGER"},
+	}
+
+	SetStrict(true)
+	defer SetStrict(false)
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errorObject, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned, got: %T (%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if errorObject.Message != tt.expected {
+			t.Errorf("wrong error message. expected: %q, got: %q", tt.expected, errorObject.Message)
+		}
+	}
+
+	testIntegerObject(t, testEval("if (5 > 1) { 1 }"), 1)
+	testIntegerObject(t, testEval("if (bool(5)) { 1 }"), 1)
+	testIntegerObject(t, testEval("if (bool(if (false) { 1 })) { 1 } else { 2 }"), 2)
+}
+
+func TestLenientConditions(t *testing.T) {
+	testIntegerObject(t, testEval("if (5) { 1 }"), 1)
+	testIntegerObject(t, testEval(`if ("") { 1 } else { 2 }`), 1)
+	testIntegerObject(t, testEval("let i = 0; do { i = i + 1 } while (if (i < 3) { i }); i"), 3)
+}
+
+func TestPutsWritesToOutput(t *testing.T) {
+	var out bytes.Buffer
+	SetOutput(&out)
+	defer SetOutput(os.Stdout)
+
+	testNullObject(t, testEval(`puts("hi"); puts(1, [2, 3])`))
+
+	expected := "hi\n1\n[2, 3]\n"
+	if out.String() != expected {
+		t.Errorf("wrong output. expected: %q, got: %q", expected, out.String())
+	}
+}
+
+func TestPprint(t *testing.T) {
+	var out bytes.Buffer
+	SetOutput(&out)
+	defer SetOutput(os.Stdout)
+
+	testNullObject(t, testEval(`pprint({"a": [1, 2], "b": {"c": 3}}); pprint(); pprint(1, [], {})`))
+
+	expected := `{
+  a: [
+    1,
+    2
+  ],
+  b: {
+    c: 3
+  }
+}
+1
+[]
+{}
+`
+	if out.String() != expected {
+		t.Errorf("wrong output. expected: %q, got: %q", expected, out.String())
+	}
+}
+
+func TestTap(t *testing.T) {
+	var out bytes.Buffer
+	SetOutput(&out)
+	defer SetOutput(os.Stdout)
+
+	testIntegerObject(t, testEval(`let x = tap(2 * 3) + 1; x`), 7)
+
+	input := &object.Array{Elements: []object.Object{intObject(1), &object.String{Value: "two"}}}
+	env := object.NewEnvironment()
+	env.Set("input", input)
+
+	program := parser.New(lexer.New("tap(input)")).ParseProgram()
+	if evaluated := Eval(program, env); evaluated != input {
+		t.Errorf("tap did not return its argument, got: %T(%+v)", evaluated, evaluated)
+	}
+
+	expected := "6\n[1, two]\n"
+	if out.String() != expected {
+		t.Errorf("wrong output. expected: %q, got: %q", expected, out.String())
+	}
+
+	evaluated := testEval("tap(1, 2)")
+	errorObject, ok := evaluated.(*object.Error)
+	if !ok || errorObject.Message != "tap: wrong number of arguments. got: 2 want: 1" {
+		t.Errorf("wrong error for tap(1, 2), got: %T(%+v)", evaluated, evaluated)
+	}
+}
+
+func TestMaximumRecursionDepth(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let f = fn() { f() }; f()", "maximum recursion depth exceeded"},
+		{"let f = fn(x) { x + f(x + 1) }; f(0)", "maximum recursion depth exceeded"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errorObject, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned, got: %T (%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if errorObject.Message != tt.expected {
+			t.Errorf("wrong error message. expected: %q, got: %q", tt.expected, errorObject.Message)
+		}
+	}
+
+	// the depth is released after an error so later calls are not affected
+	testIntegerObject(t, testEval("let f = fn(x) { if (x == 0) { 0 } else { f(x - 1) } }; f(100)"), 0)
+
+	SetMaxCallDepth(10)
+	defer SetMaxCallDepth(10000)
+
+	testIntegerObject(t, testEval("let f = fn(x) { if (x == 0) { 0 } else { f(x - 1) } }; f(9)"), 0)
+
+	evaluated := testEval("let f = fn(x) { if (x == 0) { 0 } else { f(x - 1) } }; f(10)")
+	if _, ok := evaluated.(*object.Error); !ok {
+		t.Errorf("no error object returned, got: %T (%+v)", evaluated, evaluated)
+	}
+}
+
+func TestRecursionDepthIsCountedPerEvaluation(t *testing.T) {
+	SetMaxCallDepth(100)
+	defer SetMaxCallDepth(10000)
+
+	// together the programs go deeper than the maximum, each one on its own stays below it
+	program := parser.New(lexer.New("let f = fn(x) { if (x == 0) { 0 } else { 1 + f(x - 1) } }; f(90)")).ParseProgram()
+
+	results := make(chan object.Object, 4)
+	for i := 0; i < cap(results); i++ {
+		go func() { results <- Eval(program, object.NewEnvironment()) }()
+	}
+
+	for i := 0; i < cap(results); i++ {
+		testIntegerObject(t, <-results, 90)
+	}
+}
+
+func TestEvalString(t *testing.T) {
+	env := object.NewEnvironment()
+
+	testIntegerObject(t, EvalString("let x = 2; x * 3", env), 6)
+	testIntegerObject(t, EvalString("x + 1", env), 3)
+
+	evaluated := EvalString("let = 5; let y 6;", env)
+	errorObject, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned, got: %T (%+v)", evaluated, evaluated)
+	}
+
+	if errorObject.Message != "parser errors: expected next token to be IDENTIFIER, got =; expected next token to be =, got INTEGER(\"6\")" {
+		t.Errorf("wrong error message, got: %q", errorObject.Message)
+	}
+}
+
+func TestPureProgramsAreDeterministic(t *testing.T) {
+	programs := []string{
+		"let fib = fn(x) { if (x < 2) { x } else { fib(x - 1) + fib(x - 2) } }; fib(15)",
+		`zfill(42, 6) + "-" + zfill(-7, 3)`,
+		"windows(transpose([[1, 2, 3], [4, 5, 6]]), 2)",
+		"let a = [[1, 2], [3]]; let b = copy(a); b[0][0] = 9; [a, b, len(a)]",
+		"iterate(fn(xs) { push(xs, len(xs)) }, [], 5)",
+		"[dot([1, 2, 3], [4, 5, 6]), vadd([1, 2], [3, 4]), get_or([1, 2], -1, 0)]",
+		"let i = 0; do { i = i + 2 } while (i < 7); [i, bool(i), 1 < 2 && 3 > 4]",
+	}
+
+	for _, program := range programs {
+		first := EvalString(program, object.NewEnvironment())
+		second := EvalString(program, object.NewEnvironment())
+
+		if first == nil || second == nil {
+			t.Fatalf("program %q evaluated to nil", program)
+		}
+
+		if isError(first) {
+			t.Errorf("program %q returned an error: %s", program, first.Inspect())
+			continue
+		}
+
+		if first.Inspect() != second.Inspect() {
+			t.Errorf("program %q is not deterministic. first: %s, second: %s", program, first.Inspect(), second.Inspect())
+		}
+	}
+}
+
+func TestDestructuringLetStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let a, b = [1, 2]; a;", 1},
+		{"let a, b = [1, 2]; b;", 2},
+		{"let divmod = fn(x, y) { [x / y, x - (x / y) * y] }; let q, r = divmod(17, 5); q * 10 + r;", 32},
+		{"let a, b = [1, 2]; let a, b = [b, a]; a - b;", 1},
+		{"let a, b = [1]", "wrong number of values to destructure. got: 1 want: 2"},
+		{"let a, b = [1, 2, 3]", "wrong number of values to destructure. got: 3 want: 2"},
+		{"let a, b = 5", "cannot destructure INTEGER, want: ARRAY"},
+		{"let a, b = [1, c]", "identifier not found: c"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+
+		case string:
+			errorObject, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned, got: %T (%+v)", evaluated, evaluated)
+				continue
+			}
+
+			if errorObject.Message != expected {
+				t.Errorf("wrong error message. expected: %q, got: %q", expected, errorObject.Message)
+			}
+		}
+	}
+}
+
+func TestForInExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let sum = 0; for (x in [1, 2, 3]) { sum = sum + x }; sum", 6},
+		{"let sum = 0; for (x in []) { sum = sum + 1 }; sum", 0},
+		{"let sum = 0; for (x in lazy_range(1, 5)) { sum = sum + x }; sum", 10},
+		{"let f = fn(xs) { for (x in xs) { if (x > 1) { return x } } }; f([1, 5, 9])", 5},
+		{"let r = lazy_range(0, 5); let f = fn() { for (x in r) { if (x == 2) { return 0 } } }; f(); next(r)", 3},
+		{"for (x in [1]) { x }", nil},
+		{"for (x in 5) { x }", "cannot loop over INTEGER"},
+		{"for (x in [1, 2]) { x + true }", "type mismatch: INTEGER + BOOLEAN"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+
+		case string:
+			errorObject, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned, got: %T (%+v)", evaluated, evaluated)
+				continue
+			}
+
+			if errorObject.Message != expected {
+				t.Errorf("wrong error message. expected: %q, got: %q", expected, errorObject.Message)
+			}
+
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestForInOverLargeLazyRange(t *testing.T) {
+	// a million integers are summed one at a time, the range is never turned into an array
+	input := "let sum = 0; for (x in lazy_range(0, 1000000)) { sum = sum + x }; sum"
+
+	testIntegerObject(t, testEval(input), 499999500000)
+}
+
+func TestEmptyProgram(t *testing.T) {
+	evaluated := Eval(&ast.Program{}, object.NewEnvironment())
+	if evaluated != NULL {
+		t.Errorf("empty program is not NULL, got: %T (%+v)", evaluated, evaluated)
+	}
+
+	testNullObject(t, testEval(""))
+	testNullObject(t, testEval("  \n\t  "))
+}
+
+func TestWindows(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`windows([1, 2, 3, 4], 2)`, "[[1, 2], [2, 3], [3, 4]]"},
+		{`windows([1, 2, 3], 3)`, "[[1, 2, 3]]"},
+		{`windows([1, 2, 3], 1)`, "[[1], [2], [3]]"},
+		{`windows([1, 2], 3)`, "[]"},
+		{`windows([], 1)`, "[]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		array, ok := evaluated.(*object.Array)
+		if !ok {
+			t.Fatalf("evaluated is not *object.Array, got: %T(%+v)", evaluated, evaluated)
+		}
+
+		if array.Inspect() != tt.expected {
+			t.Errorf("windows returned %s, want %s", array.Inspect(), tt.expected)
+		}
+	}
+}
+
+func TestWindowsErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`windows([1, 2])`, "windows: wrong number of arguments. got: 1 want: 2"},
+		{`windows("12", 1)`, "first argument to windows must be an array, got: STRING"},
+		{`windows([1, 2], "1")`, "second argument to windows must be an integer, got: STRING"},
+		{`windows([1, 2], 0)`, "size for windows must be positive, got: 0"},
+		{`windows([1, 2], -1)`, "size for windows must be positive, got: -1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errorObject, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned, got: %T (%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if errorObject.Message != tt.expected {
+			t.Errorf("wrong error message. expected: %q, got: %q", tt.expected, errorObject.Message)
+		}
+	}
+}
+
+func TestTranspose(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`transpose([[1, 2, 3], [4, 5, 6]])`, "[[1, 4], [2, 5], [3, 6]]"},
+		{`transpose([[1, 4], [2, 5], [3, 6]])`, "[[1, 2, 3], [4, 5, 6]]"},
+		{`transpose([[1, 2]])`, "[[1], [2]]"},
+		{`transpose([])`, "[]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		array, ok := evaluated.(*object.Array)
+		if !ok {
+			t.Fatalf("evaluated is not *object.Array, got: %T(%+v)", evaluated, evaluated)
+		}
+
+		if array.Inspect() != tt.expected {
+			t.Errorf("transpose returned %s, want %s", array.Inspect(), tt.expected)
+		}
+	}
+}
+
+func TestTransposeErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`transpose()`, "transpose: wrong number of arguments. got: 0 want: 1"},
+		{`transpose(1)`, "argument to transpose must be an array, got: INTEGER"},
+		{`transpose([[1, 2], 3])`, "rows of transpose must be arrays, got: INTEGER"},
+		{`transpose([[1, 2], [3]])`, "rows of transpose must have equal lengths, row 1 has 1 elements want 2"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errorObject, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned, got: %T (%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if errorObject.Message != tt.expected {
+			t.Errorf("wrong error message. expected: %q, got: %q", tt.expected, errorObject.Message)
+		}
+	}
+}
+
+func TestVectorBuiltins(t *testing.T) {
+	testIntegerObject(t, testEval("dot([1, 2, 3], [4, 5, 6])"), 32)
+	testIntegerObject(t, testEval("dot([], [])"), 0)
+
+	evaluated := testEval("vadd([1, 2, 3], [4, 5, 6])")
+	array, ok := evaluated.(*object.Array)
+	if !ok {
+		t.Fatalf("evaluated is not *object.Array, got: %T(%+v)", evaluated, evaluated)
+	}
+
+	if array.Inspect() != "[5, 7, 9]" {
+		t.Errorf("vadd returned %s, want [5, 7, 9]", array.Inspect())
+	}
+}
+
+func TestVectorBuiltinsPromoteToBigIntegers(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"dot([9223372036854775807], [2])", "18446744073709551614"},
+		{"dot([9223372036854775807, 1], [1, 1])", "9223372036854775808"},
+		{"dot([9223372036854775807, 1, -2], [1, 1, 1])", "9223372036854775806"},
+		{"dot([-9223372036854775807 - 1], [-1])", "9223372036854775808"},
+		{"vadd([9223372036854775807, 1], [1, 1])", "[9223372036854775808, 2]"},
+		{"vadd([-9223372036854775807], [-2])", "[-9223372036854775809]"},
+		{"vadd([9223372036854775807], [1]) == [9223372036854775807 + 1]", "true"},
+		{"dot(vadd([9223372036854775807], [1]), [2])", "18446744073709551616"},
+		{"dot([pow(2, 64), 1], [1, -1])", "18446744073709551615"},
+		{"dot([pow(2, 64)], [0])", "0"},
+		{"vadd(vadd([9223372036854775807], [1]), [-1])", "[9223372036854775807]"},
+		{"vadd([pow(2, 64)], [pow(2, 64)])", "[36893488147419103232]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected: %s, got: %s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestVectorBuiltinsErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`dot([1, 2, 3], [1, 2])`, "arguments to dot must have the same length, got: 3 and 2"},
+		{`vadd([1], [1, 2, 3])`, "arguments to vadd must have the same length, got: 1 and 3"},
+		{`dot([1, 2])`, "dot: wrong number of arguments. got: 1 want: 2"},
+		{`dot(1, [1])`, "arguments to dot must be arrays, got: INTEGER"},
+		{`vadd([1, "2"], [1, 2])`, "elements of vadd arguments must be integers, got: STRING"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errorObject, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned, got: %T (%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if errorObject.Message != tt.expected {
+			t.Errorf("wrong error message. expected: %q, got: %q", tt.expected, errorObject.Message)
+		}
+	}
+}
+
+func TestCopy(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let a = [[1, 2], 3]; let b = copy(a); b[0][0] = 9; a[0][0];", 1},
+		{"let a = [[1, 2], 3]; let b = copy(a); b[0][0] = 9; b[0][0];", 9},
+		{`let h = {"xs": [1, 2]}; let c = copy(h); c["xs"][1] = 7; h["xs"][1];`, 2},
+		{`let h = {"x": 1}; let c = copy(h); c["x"] = 5; h["x"];`, 1},
+		{"copy(5)", 5},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+
+	evaluated := testEval("copy(fn(x) { x })")
+	errorObject, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned, got: %T (%+v)", evaluated, evaluated)
+	}
+
+	if errorObject.Message != "argument to copy not supported, got: FUNCTION_OBJECT" {
+		t.Errorf("wrong error message, got: %q", errorObject.Message)
+	}
+}
+
+func TestPathBuiltins(t *testing.T) {
+	evaluated := testEval("cwd()")
+	directory, ok := evaluated.(*object.String)
+	if !ok {
+		t.Fatalf("evaluated is not *object.String, got: %T(%+v)", evaluated, evaluated)
+	}
+
+	if directory.Value == "" {
+		t.Errorf("cwd returned an empty string")
+	}
+
+	evaluated = testEval(`abs_path(".")`)
+	absolute, ok := evaluated.(*object.String)
+	if !ok {
+		t.Fatalf("evaluated is not *object.String, got: %T(%+v)", evaluated, evaluated)
+	}
+
+	if absolute.Value != directory.Value {
+		t.Errorf("abs_path(\".\") is not %q, got %q", directory.Value, absolute.Value)
+	}
+
+	evaluated = testEval(`abs_path(1)`)
+	errorObject, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned, got: %T (%+v)", evaluated, evaluated)
+	}
+
+	if errorObject.Message != "argument to abs_path must be a string, got: INTEGER" {
+		t.Errorf("wrong error message, got: %q", errorObject.Message)
+	}
+}
+
+func TestCodePoints(t *testing.T) {
+	evaluated := testEval(`codes("héllo, 世界")`)
+	array, ok := evaluated.(*object.Array)
+	if !ok {
+		t.Fatalf("evaluated is not *object.Array, got: %T(%+v)", evaluated, evaluated)
+	}
+
+	expected := "[104, 233, 108, 108, 111, 44, 32, 19990, 30028]"
+	if array.Inspect() != expected {
+		t.Errorf("codes returned %s, want %s", array.Inspect(), expected)
+	}
+
+	tests := []string{"héllo, 世界", "", "jaba 🚀"}
+
+	for _, input := range tests {
+		evaluated := testEval(`from_codes(codes("` + input + `"))`)
+
+		stringObject, ok := evaluated.(*object.String)
+		if !ok {
+			t.Fatalf("evaluated is not *object.String, got: %T(%+v)", evaluated, evaluated)
+		}
+
+		if stringObject.Value != input {
+			t.Errorf("round trip of %q returned %q", input, stringObject.Value)
+		}
+	}
+}
+
+func TestCodePointsErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`codes(1)`, "argument to codes must be a string, got: INTEGER"},
+		{`from_codes("abc")`, "argument to from_codes must be an array, got: STRING"},
+		{`from_codes([97, "b"])`, "elements of from_codes argument must be integers, got: STRING"},
+		{`from_codes([-1])`, "invalid code point for from_codes: -1"},
+		{`from_codes([1114112])`, "invalid code point for from_codes: 1114112"},
+		{`from_codes([55296])`, "invalid code point for from_codes: 55296"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errorObject, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned, got: %T (%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if errorObject.Message != tt.expected {
+			t.Errorf("wrong error message. expected: %q, got: %q", tt.expected, errorObject.Message)
+		}
+	}
+}
+
+func TestEvalBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`eval("1 + 2")`, 3},
+		{`let x = 5; eval("x * 2")`, 10},
+		{`eval("let y = 4"); y`, 4},
+		{`let f = fn(a) { eval("a + 1") }; f(41)`, 42},
+		{`let inner = "2 * 3"; eval("eval(inner)")`, 6},
+		{`eval("return 7; 8")`, "return outside function"},
+		{`iterate(eval, "1 + 1", 1)`, 2},
+		{`eval("")`, nil},
+		{`eval("let = 1")`, "parser errors: expected next token to be IDENTIFIER, got ="},
+		{`eval("z")`, "identifier not found: z"},
+		{`eval(1)`, "argument to eval must be a string, got: INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+
+		case string:
+			errorObject, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned, got: %T (%+v)", evaluated, evaluated)
+				continue
+			}
+
+			if errorObject.Message != expected {
+				t.Errorf("wrong error message. expected: %q, got: %q", expected, errorObject.Message)
+			}
+
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestMaxStringLength(t *testing.T) {
+	SetMaxStringLength(10)
+	defer SetMaxStringLength(1 << 24)
+
+	passing := []struct {
+		input    string
+		expected string
+	}{
+		{`repeat("ab", 5)`, "ababababab"},
+		{`repeat("ab", 0)`, ""},
+		{`"hello" + "jaba!"`, "hellojaba!"},
+		{`zfill(7, 10)`, "0000000007"},
+		{`from_codes(codes("0123456789"))`, "0123456789"},
+		{`push("012345678", "9")`, "0123456789"},
+	}
+
+	for _, tt := range passing {
+		evaluated := testEval(tt.input)
+
+		stringObject, ok := evaluated.(*object.String)
+		if !ok {
+			t.Fatalf("evaluated is not *object.String, got: %T(%+v)", evaluated, evaluated)
+		}
+
+		if stringObject.Value != tt.expected {
+			t.Errorf("stringObject.Value is not %q, got %q", tt.expected, stringObject.Value)
+		}
+	}
+
+	failing := []string{
+		`repeat("ab", 6)`,
+		`push("0123456789", "a")`,
+		`"ab" * 6`,
+		`repeat("ab", 9223372036854775807)`,
+		`"hello" + "jaba!!"`,
+		`zfill(7, 11)`,
+		`from_codes(codes("0123456789a"))`,
+	}
+
+	for _, input := range failing {
+		evaluated := testEval(input)
+
+		errorObject, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %s, got: %T (%+v)", input, evaluated, evaluated)
+			continue
+		}
+
+		if errorObject.Message != "string length exceeds the maximum of 10" {
+			t.Errorf("wrong error message, got: %q", errorObject.Message)
+		}
+	}
+}
+
+func TestLazyRange(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let r = lazy_range(3, 6); next(r)", 3},
+		{"let r = lazy_range(3, 6); next(r); next(r); next(r)", 5},
+		{"let r = lazy_range(3, 6); next(r); next(r); next(r); next(r)", nil},
+		{"let r = lazy_range(3, 6); next(r); next(r); next(r); next(r); next(r)", nil},
+		{"next(lazy_range(5, 5))", nil},
+		{"next(lazy_range(5, 1))", nil},
+		{"let r = lazy_range(0, 1000000000000); next(r); next(r)", 1},
+		{`let sum = 0; let r = lazy_range(1, 11); let x = next(r); do { sum = sum + x; x = next(r) } while (x); sum`, 55},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		if expected, ok := tt.expected.(int); ok {
+			testIntegerObject(t, evaluated, int64(expected))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+
+	if inspected := testEval("lazy_range(1, 2)").Inspect(); inspected != "iterator" {
+		t.Errorf("lazy_range inspected as %q", inspected)
+	}
+}
+
+func TestLazyRangeErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`lazy_range(1)`, "lazy_range: wrong number of arguments. got: 1 want: 2"},
+		{`lazy_range("1", 2)`, "first argument to lazy_range must be an integer, got: STRING"},
+		{`lazy_range(1, "2")`, "second argument to lazy_range must be an integer, got: STRING"},
+		{`next([1, 2])`, "argument to next must be an iterator, got: ARRAY"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errorObject, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned, got: %T (%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if errorObject.Message != tt.expected {
+			t.Errorf("wrong error message. expected: %q, got: %q", tt.expected, errorObject.Message)
+		}
+	}
+}
+
+func TestUnset(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let x = 1; unset("x")`, true},
+		{`unset("x")`, false},
+		{`let x = 1; unset("x"); x`, "identifier not found: x"},
+		{`let x = 1; let f = fn(x) { unset("x"); x }; f(2)`, 1},
+		{`let x = 1; let f = fn() { unset("x") }; f(); x`, "identifier not found: x"},
+		{`unset(1)`, "argument to unset must be a string, got: INTEGER"},
+		{`iterate(unset, "x", 1)`, "unset must be called directly"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+
+		case string:
+			errorObject, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned, got: %T (%+v)", evaluated, evaluated)
+				continue
+			}
+
+			if errorObject.Message != expected {
+				t.Errorf("wrong error message. expected: %q, got: %q", expected, errorObject.Message)
+			}
+		}
+	}
+}
+
+func TestFunctionEnvironmentKeepsArgumentsPerCall(t *testing.T) {
+	input := `
+	let sum = fn(x) {
+		if (x == 0) {
+			0
+		} else {
+			let rest = sum(x - 1);
+			x + rest
+		}
+	};
+	sum(10);
+	`
+
+	testIntegerObject(t, testEval(input), 55)
+}
+
+func TestAssignExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let a = 1; a = 2; a;", 2},
+		{"let a = 1; let b = 1; a = b = 3; a + b;", 6},
+		{"let a = 1; let f = fn() { a = 5 }; f(); a;", 5},
+		{"let a = [1, 2, 3]; a[1] = 7; a[1];", 7},
+		{`let h = {"x": 1}; h["y"] = 2; h["x"] + h["y"];`, 3},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestChainedIndexAssignment(t *testing.T) {
+	input := `
+	let a = [1, 2];
+	let b = [3, 4];
+	a[0] = b[0] = 5;
+	[a[0], b[0]];
+	`
+
+	evaluated := testEval(input)
+	result, ok := evaluated.(*object.Array)
+	if !ok {
+		t.Fatalf("object is not an Array, got: %T (%+v)", evaluated, evaluated)
+	}
+
+	if len(result.Elements) != 2 {
+		t.Fatalf("array has wrong number of elements, got: %d", len(result.Elements))
+	}
+
+	testIntegerObject(t, result.Elements[0], 5)
+	testIntegerObject(t, result.Elements[1], 5)
+}
+
+func TestAssignExpressionErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x = 5", "identifier not found: x"},
+		{"let a = [1]; a[1] = 5", "index out of range: 1"},
+		{`let a = [1]; a["0"] = 5`, "array index must be an integer, got: STRING"},
+		{"let h = {}; h[fn(x) { x }] = 5", "unusable as hash key: FUNCTION_OBJECT"},
+		{`let s = "jaba"; s[0] = "J"`, "strings are immutable"},
+		{`let s = "jaba"; s[10] = "J"`, "strings are immutable"},
+		{"let x = 5; x[0] = 1", "index assignment not supported: INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errorObject, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned, got: %T (%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if errorObject.Message != tt.expected {
+			t.Errorf("wrong error message. expected: %q, got: %q", tt.expected, errorObject.Message)
+		}
+	}
+}
+
+func TestMathBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"pow(2, 8)", 256},
+		{"pow(-3, 3)", -27},
+		{"pow(5, 0)", 1},
+		{"pow(2, 62)", 4611686018427387904},
+		{"pow(-2, 63)", math.MinInt64},
+		{"pow(3, 39) / pow(3, 38)", 3},
+		{"pow(1, 9223372036854775807)", 1},
+		{"pow(-1, 9223372036854775807)", -1},
+		{"pow(0, 9223372036854775807)", 0},
+		{"sqrt(16)", 4},
+		{"sqrt(17)", 4},
+		{"sqrt(0)", 0},
+		{"sqrt(9223372036854775807)", 3037000499},
+		{"floor(7)", 7},
+		{"ceil(-7)", -7},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestPowPromotesToBigIntegers(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"pow(2, 63)", "9223372036854775808"},
+		{"pow(2, 64)", "18446744073709551616"},
+		{"pow(-3, 41)", "-36472996377170786403"},
+		{"pow(2, 64) == 9223372036854775807 * 2 + 2", "true"},
+		{"pow(2, 64) / pow(2, 2)", "4611686018427387904"},
+		{"sqrt(pow(2, 64))", "4294967296"},
+		{"sqrt(pow(2, 64) - 1)", "4294967295"},
+		{"sqrt(pow(10, 40))", "100000000000000000000"},
+		{"floor(pow(2, 64))", "18446744073709551616"},
+		{"ceil(-pow(2, 64))", "-18446744073709551616"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected: %s, got: %s (%s)", tt.input, tt.expected, evaluated.Inspect(), evaluated.Type())
+		}
+	}
+
+	if evaluated := testEval("pow(2, 64)"); evaluated.Type() != object.BIGINT_OBJECT {
+		t.Errorf("pow(2, 64) is not a big integer, got: %s", evaluated.Type())
+	}
+}
+
+func TestMathBuiltinsErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"pow(2)", "pow: wrong number of arguments. got: 1 want: 2"},
+		{`pow("2", 8)`, "first argument to pow must be an integer, got: STRING"},
+		{"pow(2, true)", "second argument to pow must be an integer, got: BOOLEAN"},
+		{"pow(2, -1)", "second argument to pow must not be negative, got: -1"},
+		{"pow(2, 9223372036854775807)", "result of pow is too large, got exponent: 9223372036854775807"},
+		{"sqrt(1, 2)", "sqrt: wrong number of arguments. got: 2 want: 1"},
+		{"sqrt([16])", "argument to sqrt must be an integer, got: ARRAY"},
+		{"sqrt(-4)", "argument to sqrt must not be negative, got: -4"},
+		{"sqrt(-pow(2, 64))", "argument to sqrt must not be negative, got: -18446744073709551616"},
+		{`floor("1")`, "argument to floor must be an integer, got: STRING"},
+		{"ceil()", "ceil: wrong number of arguments. got: 0 want: 1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errorObject, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned, got: %T (%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if errorObject.Message != tt.expected {
+			t.Errorf("wrong error message. expected: %q, got: %q", tt.expected, errorObject.Message)
+		}
+	}
+}
+
+func TestSeededRand(t *testing.T) {
+	input := `
+	seed(42);
+	let first = [rand(100), rand(100), rand(100), rand(100), rand(100)];
+	seed(42);
+	let second = [rand(100), rand(100), rand(100), rand(100), rand(100)];
+	[first, second]
+	`
+
+	evaluated := testEval(input)
+	if evaluated.Inspect() != "[[75, 11, 60, 9, 57], [75, 11, 60, 9, 57]]" {
+		t.Errorf("seeded rand returned %s, want [[75, 11, 60, 9, 57], [75, 11, 60, 9, 57]]", evaluated.Inspect())
+	}
+
+	for i := 0; i < 100; i++ {
+		value, ok := testEval("rand(3)").(*object.Integer)
+		if !ok || value.Value < 0 || value.Value >= 3 {
+			t.Fatalf("rand(3) is not in [0, 3), got: %v", value)
+		}
+	}
+}
+
+func TestRandErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"rand(0)", "argument to rand must be positive, got: 0"},
+		{"rand(-5)", "argument to rand must be positive, got: -5"},
+		{`rand("5")`, "argument to rand must be an integer, got: STRING"},
+		{"rand()", "rand: wrong number of arguments. got: 0 want: 1"},
+		{"seed(true)", "argument to seed must be an integer, got: BOOLEAN"},
+		{"seed(1, 2)", "seed: wrong number of arguments. got: 2 want: 1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errorObject, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned, got: %T (%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if errorObject.Message != tt.expected {
+			t.Errorf("wrong error message. expected: %q, got: %q", tt.expected, errorObject.Message)
+		}
+	}
+}
+
+func TestEach(t *testing.T) {
+	input := `
+	let visited = [];
+	let total = 0;
+	let result = each({"a": 1, "b": 2, "c": 3}, fn(k, v) {
+		visited = push(visited, k);
+		total = total + v;
+	});
+	[result, len(visited), total]
+	`
+
+	evaluated := testEval(input)
+	if evaluated.Inspect() != "[null, 3, 6]" {
+		t.Errorf("each returned %s, want [null, 3, 6]", evaluated.Inspect())
+	}
+
+	testNullObject(t, testEval("each({}, fn(k, v) { 1 / 0 })"))
+
+	withDefault := testEval(`let seen = []; each({"a": 1}, fn(k, v, sep = ":") { seen = push(seen, k + sep) }); seen`)
+	if withDefault.Inspect() != "[a:]" {
+		t.Errorf("each with a default parameter gave %s, want [a:]", withDefault.Inspect())
+	}
+}
+
+func TestEachErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`each({"a": 1})`, "each: wrong number of arguments. got: 1 want: 2"},
+		{"each([1, 2], fn(k, v) { k })", "first argument to each must be a hash, got: ARRAY"},
+		{`each({"a": 1}, 5)`, "second argument to each must be a function, got: INTEGER"},
+		{`each({"a": 1}, fn(k) { k })`, "function passed to each must take 2 parameters, got: 1"},
+		{`each({"a": 1}, fn(k = 1) { k })`, "function passed to each must take 2 parameters, got: 1"},
+		{`each({"a": 1}, fn(k, v, w) { k })`, "function passed to each must take 2 parameters, got: 3"},
+		{`each({"a": 1}, fn(k, v) { k + v })`, "type mismatch: STRING + INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errorObject, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned, got: %T (%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if errorObject.Message != tt.expected {
+			t.Errorf("wrong error message. expected: %q, got: %q", tt.expected, errorObject.Message)
+		}
+	}
+}
+
+func TestZip(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`zip([1, 2, 3], ["a", "b", "c"])`, "[[1, a], [2, b], [3, c]]"},
+		{`zip([1, 2, 3], ["a", "b"])`, "[[1, a], [2, b]]"},
+		{`zip([1], [true, false])`, "[[1, true]]"},
+		{`zip([], [1, 2])`, "[]"},
+		{`zip([], [])`, "[]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		array, ok := evaluated.(*object.Array)
+		if !ok {
+			t.Errorf("evaluated is not *object.Array, got: %T(%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if array.Inspect() != tt.expected {
+			t.Errorf("zip returned %s, want %s", array.Inspect(), tt.expected)
+		}
+	}
+}
+
+func TestZipErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"zip([1])", "zip: wrong number of arguments. got: 1 want: 2"},
+		{`zip("ab", [1, 2])`, "first argument to zip must be an array, got: STRING"},
+		{"zip([1, 2], 3)", "second argument to zip must be an array, got: INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errorObject, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned, got: %T (%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if errorObject.Message != tt.expected {
+			t.Errorf("wrong error message. expected: %q, got: %q", tt.expected, errorObject.Message)
+		}
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"flatten([1, [2, 3], 4])", "[1, 2, 3, 4]"},
+		{"flatten([1, [2, [3, [4]]], 5])", "[1, 2, [3, [4]], 5]"},
+		{"flatten([1, [2, [3, [4]]], 5], 2)", "[1, 2, 3, [4], 5]"},
+		{"flatten([1, [2, [3, [4]]], 5], 10)", "[1, 2, 3, 4, 5]"},
+		{"flatten([1, [2]], 0)", "[1, [2]]"},
+		{"flatten([[], [[]]])", "[[]]"},
+		{"flatten([])", "[]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		array, ok := evaluated.(*object.Array)
+		if !ok {
+			t.Errorf("evaluated is not *object.Array, got: %T(%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if array.Inspect() != tt.expected {
+			t.Errorf("flatten returned %s, want %s", array.Inspect(), tt.expected)
+		}
+	}
+}
+
+func TestFlattenErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"flatten()", "flatten: wrong number of arguments. got: 0 want: 1 or 2"},
+		{`flatten("abc")`, "first argument to flatten must be an array, got: STRING"},
+		{`flatten([1], "2")`, "second argument to flatten must be an integer, got: STRING"},
+		{"flatten([1], -1)", "depth for flatten must not be negative, got: -1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errorObject, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned, got: %T (%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if errorObject.Message != tt.expected {
+			t.Errorf("wrong error message. expected: %q, got: %q", tt.expected, errorObject.Message)
+		}
+	}
+}
+
+func TestIntegerOverflowPromotesToBigInt(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"9223372036854775807 + 1", "9223372036854775808"},
+		{"9223372036854775806 + 1", 9223372036854775807},
+		{"-9223372036854775807 + -2", "-9223372036854775809"},
+		{"-9223372036854775807 - 1", -9223372036854775807 - 1},
+		{"-9223372036854775807 - 2", "-9223372036854775809"},
+		{"9223372036854775807 - -1", "9223372036854775808"},
+		{"0 - 9223372036854775807", -9223372036854775807},
+		{"4611686018427387904 * 2", "9223372036854775808"},
+		{"4611686018427387903 * 2", 9223372036854775806},
+		{"-4611686018427387904 * 2", -9223372036854775807 - 1},
+		{"3037000500 * 3037000500", "9223372037000250000"},
+		{"let min = -9223372036854775807 - 1; min * -1", "9223372036854775808"},
+		{"let min = -9223372036854775807 - 1; -1 * min", "9223372036854775808"},
+		{"let min = -9223372036854775807 - 1; min / -1", "9223372036854775808"},
+		{"let min = -9223372036854775807 - 1; -min", "9223372036854775808"},
+		{"let min = -9223372036854775807 - 1; --min", -9223372036854775807 - 1},
+		{"let min = -9223372036854775807 - 1; min * 1", -9223372036854775807 - 1},
+		{"let big = 9223372036854775807 + 1; big - 1", 9223372036854775807},
+		{"let big = 9223372036854775807 * 4; big / 2", "18446744073709551614"},
+		{"let big = 9223372036854775807 * 4; big / 4", 9223372036854775807},
+		{"let big = 9223372036854775807 * 4; big * big", "1361129467683753853558350524547720019984"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+
+		case string:
+			bigInt, ok := evaluated.(*object.BigInt)
+			if !ok {
+				t.Errorf("evaluated %q is not *object.BigInt, got: %T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+
+			if bigInt.Inspect() != expected {
+				t.Errorf("wrong value for %q. expected: %s, got: %s", tt.input, expected, bigInt.Inspect())
+			}
+		}
+	}
+}
+
+func TestBigIntFactorial(t *testing.T) {
+	input := `
+	let fact = fn(n) { if (n < 2) { 1 } else { n * fact(n - 1) } };
+	fact(25)
+	`
+
+	evaluated := testEval(input)
+
+	bigInt, ok := evaluated.(*object.BigInt)
+	if !ok {
+		t.Fatalf("evaluated is not *object.BigInt, got: %T (%+v)", evaluated, evaluated)
+	}
+
+	if bigInt.Inspect() != "15511210043330985984000000" {
+		t.Errorf("fact(25) is wrong, got: %s", bigInt.Inspect())
+	}
+}
+
+func TestBigIntComparisons(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"let big = 9223372036854775807 + 1; big > 9223372036854775807", true},
+		{"let big = 9223372036854775807 + 1; 1 < big", true},
+		{"let big = 9223372036854775807 + 1; -big < 0", true},
+		{"let big = 9223372036854775807 + 1; big == 9223372036854775807 + 1", true},
+		{"let big = 9223372036854775807 + 1; big != big + 1", true},
+		{"let big = 9223372036854775807 + 1; big == 5", false},
+		{`let big = 9223372036854775807 + 1; let h = {big: 1}; h[9223372036854775806 + 2] == 1`, true},
+	}
+
+	for _, tt := range tests {
+		testBooleanObject(t, testEval(tt.input), tt.expected)
+	}
+
+	evaluated := testEval("let big = 9223372036854775807 + 1; big / 0")
+	errorObject, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned, got: %T (%+v)", evaluated, evaluated)
+	}
+
+	if errorObject.Message != "division by zero" {
+		t.Errorf("wrong error message, got: %q", errorObject.Message)
+	}
+}
+
+func TestPop(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"pop([1, 2, 3])", "[3, [1, 2]]"},
+		{"pop([1])", "[1, []]"},
+		{"let stack = [1, 2, 3]; pop(stack); stack", "[1, 2, 3]"},
+		{"let stack = push([], 1); let stack = push(stack, 2); let top, stack = pop(stack); [top, stack]", "[2, [1]]"},
+		{"pop([])", "null"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected: %s, got: %s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+
+	testNullObject(t, testEval("pop([])"))
+}
+
+func TestPopErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"pop()", "pop: wrong number of arguments. got: 0 want: 1"},
+		{"pop([1], [2])", "pop: wrong number of arguments. got: 2 want: 1"},
+		{`pop("abc")`, "argument to pop must be an array, got: STRING"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errorObject, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned, got: %T (%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if errorObject.Message != tt.expected {
+			t.Errorf("wrong error message. expected: %q, got: %q", tt.expected, errorObject.Message)
+		}
+	}
+}
+
+func TestPartial(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let addThree = partial(fn(a, b, c) { a + b + c }, 1, 2); addThree(3)", 6},
+		{"let addThree = partial(fn(a, b, c) { a + b + c }, 1, 2); addThree(3) + addThree(10)", 19},
+		{"let sub = partial(fn(a, b) { a - b }, 10); sub(3)", 7},
+		{"let same = partial(fn(a, b) { a * b }); same(3, 4)", 12},
+		{"let all = partial(fn(a, b) { a * b }, 3, 4); all()", 12},
+		{"let firstOf = partial(first, [3, 2, 1]); firstOf()", 3},
+		{"let inc = partial(fn(a, b = 1) { a + b }, 5); inc()", 6},
+		{"let f = partial(partial(fn(a, b, c) { a * 100 + b * 10 + c }, 1), 2); f(3)", 123},
+		{"partial()", errorMessage("partial: wrong number of arguments. got: 0 want: at least 1")},
+		{"partial(1, 2)", errorMessage("first argument to partial must be a function, got: INTEGER")},
+		{"let f = partial(fn(a, b) { a + b }, 1); f()", errorMessage("wrong number of arguments. got: 1 want: 2")},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+
+		case errorMessage:
+			errorObject, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q, got: %T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+
+			if errorObject.Message != string(expected) {
+				t.Errorf("wrong error message. expected: %q, got: %q", expected, errorObject.Message)
+			}
+		}
+	}
+}
+
+func TestGenerators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let counter = fn() { let i = 0; do { yield i; i = i + 1; } while (i < 3); }; let gen = counter(); next(gen);", 0},
+		{"let counter = fn() { let i = 0; do { yield i; i = i + 1; } while (i < 3); }; let gen = counter(); next(gen); next(gen);", 1},
+		{"let counter = fn() { let i = 0; do { yield i; i = i + 1; } while (i < 3); }; let gen = counter(); next(gen); next(gen); next(gen);", 2},
+		{"let counter = fn() { let i = 0; do { yield i; i = i + 1; } while (i < 3); }; let gen = counter(); next(gen); next(gen); next(gen); next(gen);", nil},
+		{"let counter = fn() { let i = 0; do { yield i; i = i + 1; } while (i < 3); }; let gen = counter(); next(gen); next(gen); next(gen); next(gen); next(gen);", nil},
+		// each call starts a new generator
+		{"let counter = fn() { yield 1; yield 2; }; next(counter()); next(counter());", 1},
+		{"let from = fn(start) { yield start; yield start + 1; }; let gen = from(10); next(gen) + next(gen);", 21},
+		{"let g = fn() { yield 1; return 5; yield 2; }; let gen = g(); next(gen); next(gen);", nil},
+		{"let g = fn() { 1 }; g();", 1},
+		{"let squares = fn(n) { for (x in lazy_range(0, n)) { yield x * x; } }; let total = 0; for (s in squares(4)) { total = total + s; }; total;", 14},
+		{"let g = fn() { yield 1; yield 1 + true; }; let gen = g(); next(gen); next(gen);", errorMessage("type mismatch: INTEGER + BOOLEAN")},
+		{"let g = fn() { yield 1; yield 1 + true; }; let total = 0; for (x in g()) { total = total + x; }; total;", errorMessage("type mismatch: INTEGER + BOOLEAN")},
+		{"yield 1;", errorMessage("yield outside generator")},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+
+		case errorMessage:
+			errorObject, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("%s: no error object returned, got: %T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+
+			if errorObject.Message != string(expected) {
+				t.Errorf("wrong error message. expected: %q, got: %q", expected, errorObject.Message)
+			}
+
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestTryCatch(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`try { 10 / 0 } catch (e) { e }`, caughtError("division by zero")},
+		{`try { 10 / 2 } catch (e) { e }`, 5},
+		{`let x = 0; try { x = 1; 1 / 0; x = 2; } catch (e) { x = x + 10 }; x`, 11},
+		{`try { missing } catch (err) { "caught: " + err }`, "caught: identifier not found: missing"},
+		{`let divide = fn(a, b) { a / b }; try { divide(1, 0) } catch (e) { -1 }`, -1},
+		{`let safe = fn(a, b) { try { return a / b; } catch (e) { return 0; } }; safe(8, 2) + safe(1, 0)`, 4},
+		{`try { try { 1 / 0 } catch (e) { e + 1 } } catch (outer) { outer }`, caughtError("type mismatch: STRING + INTEGER")},
+		{`try { 1 / 0 } catch (e) { error_message(e) + "!" }`, "division by zero!"},
+		{`try { 1 / 0 } catch (e) { len(e) }`, errorMessage("argument to len not supported, got: CAUGHT_ERROR")},
+		{`try { 1 / 0 } catch (e) { 1 + true }`, errorMessage("type mismatch: INTEGER + BOOLEAN")},
+		{`1 / 0`, errorMessage("division by zero")},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("%s: object is not String, got: %T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+
+			if str.Value != expected {
+				t.Errorf("%s: wrong value. expected: %q, got: %q", tt.input, expected, str.Value)
+			}
+
+		case caughtError:
+			caught, ok := evaluated.(*object.CaughtError)
+			if !ok {
+				t.Errorf("%s: object is not CaughtError, got: %T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+
+			if caught.Message != string(expected) {
+				t.Errorf("%s: wrong message. expected: %q, got: %q", tt.input, expected, caught.Message)
+			}
+
+		case errorMessage:
+			errorObject, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("%s: no error object returned, got: %T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+
+			if errorObject.Message != string(expected) {
+				t.Errorf("wrong error message. expected: %q, got: %q", expected, errorObject.Message)
+			}
+		}
+	}
+}
+
+func TestThrow(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`throw "not found"`, errorMessage("not found")},
+		{`throw 404; 1`, errorMessage("404")},
+		{`let check = fn(x) { if (x < 0) { throw x } x }; check(-1)`, errorMessage("-1")},
+		{`let f = fn() { throw [1, 2]; }; f(); 5`, errorMessage("[1, 2]")},
+		{`throw missing`, errorMessage("identifier not found: missing")},
+		{`try { throw "boom" } catch (e) { "caught " + e }`, "caught boom"},
+		{`let check = fn(x) { if (x < 0) { throw "negative" } x }; try { check(-1) } catch (e) { e }`, caughtError("negative")},
+		{`try { throw "inner" } catch (e) { throw "outer " + e }`, errorMessage("outer inner")},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case caughtError:
+			caught, ok := evaluated.(*object.CaughtError)
+			if !ok {
+				t.Errorf("%s: object is not CaughtError, got: %T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+
+			if caught.Message != string(expected) {
+				t.Errorf("%s: wrong message. expected: %q, got: %q", tt.input, expected, caught.Message)
+			}
+
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("%s: object is not String, got: %T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+
+			if str.Value != expected {
+				t.Errorf("%s: wrong value. expected: %q, got: %q", tt.input, expected, str.Value)
+			}
+
+		case errorMessage:
+			errorObject, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("%s: no error object returned, got: %T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+
+			if errorObject.Message != string(expected) {
+				t.Errorf("wrong error message. expected: %q, got: %q", expected, errorObject.Message)
+			}
+		}
+	}
+}
+
+func TestErrorKinds(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedKind object.ErrorKind
+	}{
+		{"1 + true", object.TYPE_ERROR},
+		{`-"a"`, object.TYPE_ERROR},
+		{"5(1)", object.TYPE_ERROR},
+		{"missing", object.NAME_ERROR},
+		{"1 / 0", object.DIVISION_ERROR},
+		{"len(1, 2)", object.ARGUMENT_ERROR},
+		{"let f = fn(x) { x }; f()", object.ARGUMENT_ERROR},
+		{"rand(-1)", object.VALUE_ERROR},
+		{"let a = freeze([1]); a[0] = 2", object.VALUE_ERROR},
+		{`let h = freeze({"a": 1}); h["a"] = 2`, object.VALUE_ERROR},
+		{`throw "custom"`, object.GENERIC_ERROR},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errorObject, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("%s: no error object returned, got: %T(%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+
+		if errorObject.Kind != tt.expectedKind {
+			t.Errorf("%s: wrong error kind. expected: %s, got: %s", tt.input, tt.expectedKind, errorObject.Kind)
+		}
+	}
+}
+
+func TestErrorKindBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`try { 1 + true } catch (e) { error_kind(e) }`, "TypeError"},
+		{`try { missing } catch (e) { error_kind(e) }`, "NameError"},
+		{`try { 1 / 0 } catch (e) { error_kind(e) }`, "DivisionError"},
+		{`try { throw "x" } catch (e) { error_kind(e) }`, "Error"},
+		{`try { missing } catch (e) { {"NameError": "fallback"}[error_kind(e)] }`, "fallback"},
+		{`try { try { missing } catch (e) { throw e } } catch (e) { error_kind(e) }`, "NameError"},
+		{`try { try { missing } catch (e) { throw "wrapped: " + e } } catch (e) { error_kind(e) }`, "Error"},
+		{`try { 1 / 0 } catch (e) { error_kind(e + "") }`, errorMessage("argument to error_kind must be a caught error, got: STRING")},
+		{`error_kind("not caught")`, errorMessage("argument to error_kind must be a caught error, got: STRING")},
+		{`error_kind(1)`, errorMessage("argument to error_kind must be a caught error, got: INTEGER")},
+		{`error_kind()`, errorMessage("error_kind: wrong number of arguments. got: 0 want: 1")},
+		{`try { 1 + true } catch (e) { error_message(e) }`, "type mismatch: INTEGER + BOOLEAN"},
+		{`try { throw "x" } catch (e) { repeat(error_message(e), 2) }`, "xx"},
+		{`error_message("not caught")`, errorMessage("argument to error_message must be a caught error, got: STRING")},
+		{`error_message()`, errorMessage("error_message: wrong number of arguments. got: 0 want: 1")},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("%s: object is not String, got: %T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+
+			if str.Value != expected {
+				t.Errorf("%s: wrong value. expected: %q, got: %q", tt.input, expected, str.Value)
+			}
+
+		case errorMessage:
+			errorObject, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("%s: no error object returned, got: %T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+
+			if errorObject.Message != string(expected) {
+				t.Errorf("wrong error message. expected: %q, got: %q", expected, errorObject.Message)
+			}
+		}
+	}
+}
+
+func TestCollectExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let i = 0; collect do { let value = i; i = i + 1; value } while (i < 3)", []int64{0, 1, 2}},
+		{"collect for (x in lazy_range(0, 3)) { x }", []int64{0, 1, 2}},
+		{"collect for (x in [1, 2, 3]) { x * x }", []int64{1, 4, 9}},
+		{"collect for (x in []) { x }", []int64{}},
+		{"let i = 0; collect do { i = i + 10 } while (false)", []int64{10}},
+		{"len(collect for (x in [1, 2]) { let y = x; })", 2},
+		{"let f = fn() { collect for (x in [1, 2]) { return x; } }; f()", 1},
+		{"collect for (x in [1, true]) { x + 1 }", errorMessage("type mismatch: BOOLEAN + INTEGER")},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+
+		case []int64:
+			array, ok := evaluated.(*object.Array)
+			if !ok {
+				t.Errorf("%s: object is not Array, got: %T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+
+			if len(array.Elements) != len(expected) {
+				t.Errorf("%s: wrong number of elements. expected: %d, got: %d", tt.input, len(expected), len(array.Elements))
+				continue
+			}
+
+			for i, element := range expected {
+				testIntegerObject(t, array.Elements[i], element)
+			}
+
+		case errorMessage:
+			errorObject, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("%s: no error object returned, got: %T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+
+			if errorObject.Message != string(expected) {
+				t.Errorf("wrong error message. expected: %q, got: %q", expected, errorObject.Message)
+			}
+		}
+	}
+
+	array := testEval("collect for (x in [1]) { let y = x; }").(*object.Array)
+	testNullObject(t, array.Elements[0])
+}
+
+func TestOperatorOverloading(t *testing.T) {
+	vector := `let vector = fn(x, y) {
+		{
+			"x": x,
+			"y": y,
+			"__add__": fn(a, b) { vector(a["x"] + b["x"], a["y"] + b["y"]) },
+			"__eq__": fn(a, b) { a["x"] == b["x"] && a["y"] == b["y"] }
+		}
+	};
+	`
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{vector + `let v = vector(1, 2) + vector(3, 4); v["x"]`, 4},
+		{vector + `let v = vector(1, 2) + vector(3, 4); v["y"]`, 6},
+		{vector + `let v = vector(1, 2) + vector(3, 4) + vector(5, 6); v["x"] * 100 + v["y"]`, 912},
+		{vector + `vector(1, 2) == vector(1, 2)`, true},
+		{vector + `vector(1, 2) == vector(2, 1)`, false},
+		// the right operand is used when the left one does not overload the operator
+		{`let n = {"__add__": fn(a, b) { a + b["n"] }, "n": 5}; 1 + n`, 6},
+		// hashes without the key keep the default behaviour
+		{`let h = {"a": 1} + {"b": 2}; h["b"]`, 2},
+		{`let h = {"a": 1}; h == h`, true},
+		{`let h = {"__add__": 1}; h + h`, errorMessage("not a function: INTEGER")},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+
+		case errorMessage:
+			errorObject, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("%s: no error object returned, got: %T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+
+			if errorObject.Message != string(expected) {
+				t.Errorf("wrong error message. expected: %q, got: %q", expected, errorObject.Message)
+			}
+		}
+	}
+}
+
+func TestSelfBinding(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`let point = {"x": 3, "getX": fn() { self["x"] }}; point["getX"]()`, 3},
+		{`let point = {"x": 3, "y": 4, "sum": fn(z) { self["x"] + self["y"] + z }}; point["sum"](5)`, 12},
+		{`let point = {"x": 3, "scale": fn(by = self["x"]) { self["x"] * by }}; point["scale"]()`, 9},
+		// the hash the function was read from is self, not the hash it was defined in
+		{`let a = {"x": 1, "getX": fn() { self["x"] }}; let b = a + {"x": 2}; b["getX"]()`, 2},
+		{`let counter = {"n": 0, "inc": fn() { self["n"] = self["n"] + 1 }}; counter["inc"](); counter["inc"](); counter["n"]`, 2},
+		{`let obj = {"self": fn() { self }}; obj["self"]()["self"] == obj["self"]`, true},
+		// self is only bound for calls made straight out of a hash
+		{`let getX = {"x": 1, "getX": fn() { self["x"] }}["getX"]; getX()`, errorMessage("identifier not found: self")},
+		{`let self = 5; let f = fn() { self }; [f][0]()`, 5},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+
+		case errorMessage:
+			errorObject, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("%s: no error object returned, got: %T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+
+			if errorObject.Message != string(expected) {
+				t.Errorf("wrong error message. expected: %q, got: %q", expected, errorObject.Message)
+			}
+		}
+	}
+}
+
+func TestSpread(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let add = fn(a, b) { a + b }; add(...[1, 2])", 3},
+		{"let add = fn(a, b, c) { a + b + c }; add(1, ...[2, 3])", 6},
+		{"let add = fn(a, b, c) { a + b + c }; let args = [1, 2]; add(...args, 10)", 13},
+		{"let add = fn(a, b = 5) { a + b }; add(...[1])", 6},
+		{"let f = fn() { 1 }; f(...[])", 1},
+		{"let a = [1, 2]; [...a, 3]", []int64{1, 2, 3}},
+		{"let a = [1, 2]; [0, ...a, ...a]", []int64{0, 1, 2, 1, 2}},
+		{"[...[]]", []int64{}},
+		// spreading copies the elements so the array spread is left unchanged
+		{"let a = [1]; let b = [...a, 2]; b[0] = 5; a", []int64{1}},
+		{"let add = fn(a, b) { a + b }; add(...[1, 2], ...[3])", 3},
+		{"[...5]", errorMessage("cannot spread INTEGER, only an ARRAY can be spread")},
+		{`len(..."abc")`, errorMessage("cannot spread STRING, only an ARRAY can be spread")},
+		{"[...missing]", errorMessage("identifier not found: missing")},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+
+		case []int64:
+			array, ok := evaluated.(*object.Array)
+			if !ok {
+				t.Errorf("%s: object is not Array, got: %T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+
+			if len(array.Elements) != len(expected) {
+				t.Errorf("%s: wrong number of elements. expected: %d, got: %d", tt.input, len(expected), len(array.Elements))
+				continue
+			}
+
+			for i, element := range expected {
+				testIntegerObject(t, array.Elements[i], element)
+			}
+
+		case errorMessage:
+			errorObject, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("%s: no error object returned, got: %T(%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+
+			if errorObject.Message != string(expected) {
+				t.Errorf("wrong error message. expected: %q, got: %q", expected, errorObject.Message)
+			}
+		}
+	}
+}
+
+func TestHashesKeepInsertionOrder(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`keys({"c": 1, "a": 2, "b": 3})`, "[c, a, b]"},
+		{`values({"c": 1, "a": 2, "b": 3})`, "[1, 2, 3]"},
+		{`keys({})`, "[]"},
+		{`let h = {"z": 1}; h["y"] = 2; h["x"] = 3; keys(h)`, "[z, y, x]"},
+		// setting an existing key keeps its place
+		{`let h = {"a": 1, "b": 2}; h["a"] = 3; keys(h)`, "[a, b]"},
+		{`let h = {"a": 1, "b": 2}; h["a"] = 3; values(h)`, "[3, 2]"},
+		{`let h = {"a": 1, "a": 2}; values(h)`, "[2]"},
+		{`keys({3: "c", true: "t", "s": "s", 1: "a"})`, "[3, true, s, 1]"},
+		{`{"b": 1, "a": 2, "c": 3}`, "{b: 1, a: 2, c: 3}"},
+		{`{"b": 1, "a": 2} + {"c": 3, "b": 4}`, "{b: 4, a: 2, c: 3}"},
+		{`copy({"b": [1], "a": 2})`, "{b: [1], a: 2}"},
+		{`keys_sorted({"b": 1, "a": 2})`, "[a, b]"},
+		{`let h = {"b": 1}; h["c"] = 3; h["a"] = 2; keys_sorted(h)`, "[a, b, c]"},
+		{`let seen = []; each({"q": 1, "p": 2, "r": 3}, fn(k, v) { seen = push(seen, k) }); seen`, "[q, p, r]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: expected %s, got: %s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestKeysAndValuesErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"keys([1])", "argument to keys must be a hash, got: ARRAY"},
+		{"values(1)", "argument to values must be a hash, got: INTEGER"},
+		{"keys({}, {})", "keys: wrong number of arguments. got: 2 want: 1"},
+		{"values()", "values: wrong number of arguments. got: 0 want: 1"},
+		{`keys_sorted("ab")`, "argument to keys_sorted must be a hash, got: STRING"},
+	}
+
+	for _, tt := range tests {
+		errorObject, ok := testEval(tt.input).(*object.Error)
+		if !ok {
+			t.Errorf("%s: no error object returned", tt.input)
+			continue
+		}
+
+		if errorObject.Message != tt.expected {
+			t.Errorf("wrong error message. expected: %q, got: %q", tt.expected, errorObject.Message)
+		}
+	}
+}
+
+func TestToArrayAndFromArray(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`to_array({"a": 1, "b": [2]})`, `[[a, 1], [b, [2]]]`},
+		{`to_array({})`, `[]`},
+		{`from_array([["a", 1], [2, true]])`, `{a: 1, 2: true}`},
+		{`from_array([])`, `{}`},
+		{`from_array([["a", 1], ["b", 2], ["a", 3]])`, `{a: 3, b: 2}`},
+		// a hash survives the round trip with its order
+		{`let h = {"z": 1, "a": 2, "m": 3}; from_array(to_array(h)) == h`, true},
+		{`let h = {"z": 1, "a": 2, "m": 3}; keys(from_array(to_array(h)))`, `[z, a, m]`},
+		{`to_array(from_array([[1, "one"], [2, "two"]]))`, `[[1, one], [2, two]]`},
+		{`to_array([1])`, errorMessage("argument to to_array must be a hash, got: ARRAY")},
+		{`from_array({})`, errorMessage("argument to from_array must be an array, got: HASH")},
+		{`from_array([["a", 1], ["b"]])`, errorMessage("elements of from_array must be [key, value] arrays, got: [b]")},
+		{`from_array([1])`, errorMessage("elements of from_array must be [key, value] arrays, got: 1")},
+		{`from_array([[[1], 2]])`, errorMessage("unusable as hash key: ARRAY")},
+		{`to_array()`, errorMessage("to_array: wrong number of arguments. got: 0 want: 1")},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case string:
+			if evaluated.Inspect() != expected {
+				t.Errorf("wrong result for %q. expected: %s, got: %s", tt.input, expected, evaluated.Inspect())
+			}
+
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+
+		case errorMessage:
+			errorObject, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q, got: %T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+
+			if errorObject.Message != string(expected) {
+				t.Errorf("wrong error message. expected: %q, got: %q", expected, errorObject.Message)
+			}
+		}
+	}
+}
+
+func TestJSONEncode(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`json_encode({"a": [1, 2]})`, `{"a":[1,2]}`},
+		{`json_encode(1)`, `1`},
+		{`json_encode(-9223372036854775807 * 4)`, `-36893488147419103228`},
+		{`json_encode(true)`, `true`},
+		{`json_encode(first([]))`, `null`},
+		{`json_encode("hi")`, `"hi"`},
+		{"json_encode(\"a\\b\nc\td\")", `"a\\b\nc\td"`},
+		{`json_encode([])`, `[]`},
+		{`json_encode({})`, `{}`},
+		{`json_encode({"z": {"b": [true, [first([])]], "a": {}}, "y": "x"})`, `{"z":{"b":[true,[null]],"a":{}},"y":"x"}`},
+		// the same array can appear twice as long as it does not contain itself
+		{`let a = [1]; json_encode([a, a])`, `[[1],[1]]`},
+		{`json_encode(fn(x) { x })`, errorMessage("json_encode: cannot encode FUNCTION_OBJECT")},
+		{`json_encode({"f": [len]})`, errorMessage("json_encode: cannot encode BUILTIN")},
+		{`json_encode({1: 2})`, errorMessage("json_encode: hash keys must be strings, got: INTEGER")},
+		{`let a = [1]; a[0] = a; json_encode(a)`, errorMessage("json_encode: cannot encode an array that contains itself")},
+		{`json_encode(1, 2)`, errorMessage("json_encode: wrong number of arguments. got: 2 want: 1")},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("evaluated %q is not *object.String, got: %T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+
+			if str.Value != expected {
+				t.Errorf("wrong value for %q. expected: %q, got: %q", tt.input, expected, str.Value)
+			}
+
+		case errorMessage:
+			errorObject, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q, got: %T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+
+			if errorObject.Message != string(expected) {
+				t.Errorf("wrong error message. expected: %q, got: %q", expected, errorObject.Message)
+			}
+		}
+	}
+}
+
+func TestJSONDecode(t *testing.T) {
+	// jaba strings can not hold double quotes, the JSON text is bound to text instead
+	tests := []struct {
+		text     string
+		input    string
+		expected interface{}
+	}{
+		{`{"b": [1, -2, true, null], "a": "x"}`, "json_decode(text)", "{b: [1, -2, true, null], a: x}"},
+		{`{"a": {"b": {}}, "c": []}`, `json_decode(text)["a"]`, "{b: {}}"},
+		{`123456789012345678901234`, "json_decode(text) - 1", "123456789012345678901233"},
+		{`"line\nbreak"`, "len(json_decode(text))", "10"},
+		{`{"a": 1, "a": 2}`, "json_decode(text)", "{a: 2}"},
+		// decoding what was encoded gives the same text and an equal value
+		{`{"z":[1,{"y":null}],"a":"b"}`, "json_encode(json_decode(text))", `{"z":[1,{"y":null}],"a":"b"}`},
+		{`[1,[true,false],{}]`, "json_encode(json_decode(text))", `[1,[true,false],{}]`},
+		{"", `let h = {"a": [1, {"b": true}], "c": -3}; json_decode(json_encode(h)) == h`, "true"},
+		{`1.5`, "json_decode(text)", errorMessage("json_decode: invalid JSON: 1.5 is not an integer")},
+		{`[1,`, "json_decode(text)", errorMessage("json_decode: invalid JSON: unexpected end of JSON input")},
+		{`1 2`, "json_decode(text)", errorMessage("json_decode: invalid JSON: unexpected data after the value")},
+		{`{1: 2}`, "json_decode(text)", errorMessage("json_decode: invalid JSON: object member name must be a string")},
+		{``, "json_decode(text)", errorMessage("json_decode: invalid JSON: unexpected end of JSON input")},
+		{``, "json_decode(1)", errorMessage("argument to json_decode must be a string, got: INTEGER")},
+		{``, "json_decode()", errorMessage("json_decode: wrong number of arguments. got: 0 want: 1")},
+	}
+
+	for _, tt := range tests {
+		env := object.NewEnvironment()
+		env.Set("text", &object.String{Value: tt.text})
+		evaluated := EvalString(tt.input, env)
+
+		switch expected := tt.expected.(type) {
+		case string:
+			if evaluated.Inspect() != expected {
+				t.Errorf("wrong result for %q with text %s. expected: %s, got: %s", tt.input, tt.text, expected, evaluated.Inspect())
+			}
+
+		case errorMessage:
+			errorObject, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q, got: %T (%+v)", tt.text, evaluated, evaluated)
+				continue
+			}
+
+			if errorObject.Message != string(expected) {
+				t.Errorf("wrong error message. expected: %q, got: %q", expected, errorObject.Message)
+			}
+		}
+	}
+}
+
+func TestBuiltinErrorsNameTheBuiltin(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`len(first([1], 2))`, "first: wrong number of arguments. got: 2 want: 1"},
+		{`let f = fn(xs) { rest(xs, xs) }; push([], f([1]))`, "rest: wrong number of arguments. got: 2 want: 1"},
+		{`try { pop() } catch (e) { e }`, "pop: wrong number of arguments. got: 0 want: 1"},
+		{`vadd([1])`, "vadd: wrong number of arguments. got: 1 want: 2"},
+		{`eval("1", "2")`, "eval: wrong number of arguments. got: 2 want: 1"},
+		{`let f = eval; f()`, "eval: wrong number of arguments. got: 0 want: 1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		var message string
+		switch evaluated := evaluated.(type) {
+		case *object.Error:
+			message = evaluated.Message
+		case *object.CaughtError:
+			message = evaluated.Message
+		}
+
+		if message != tt.expected {
+			t.Errorf("%s: expected error %q, got: %T(%+v)", tt.input, tt.expected, evaluated, evaluated)
+		}
+	}
+
+	for name, builtin := range builtins {
+		if builtin.Name != name {
+			t.Errorf("builtin %s has the name %q", name, builtin.Name)
+		}
+	}
+}
+
+func TestHelp(t *testing.T) {
+	names, ok := testEval("help()").(*object.Array)
+	if !ok {
+		t.Fatalf("help() is not an array")
+	}
+
+	if len(names.Elements) != len(builtins) {
+		t.Errorf("help() lists %d builtins, want: %d", len(names.Elements), len(builtins))
+	}
+
+	found := false
+	for i, element := range names.Elements {
+		name := element.(*object.String).Value
+		if name == "len" {
+			found = true
+		}
+		if i > 0 && names.Elements[i-1].(*object.String).Value >= name {
+			t.Errorf("help() is not sorted, %s comes after %s", name, names.Elements[i-1].Inspect())
+		}
+	}
+	if !found {
+		t.Errorf("help() does not list len")
+	}
+
+	for name := range builtins {
+		doc, ok := testEval(`help("` + name + `")`).(*object.String)
+		if !ok || doc.Value == "" {
+			t.Errorf("help(%q) has no description", name)
+		}
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`help("nope")`, "help: no builtin named nope"},
+		{`help(1)`, "argument to help must be a string, got: INTEGER"},
+		{`help("len", "len")`, "help: wrong number of arguments. got: 2 want: 0 or 1"},
+	}
+
+	for _, tt := range tests {
+		errorObject, ok := testEval(tt.input).(*object.Error)
+		if !ok {
+			t.Errorf("%s: no error object returned", tt.input)
+			continue
+		}
+
+		if errorObject.Message != tt.expected {
+			t.Errorf("wrong error message. expected: %q, got: %q", tt.expected, errorObject.Message)
+		}
+	}
+}
+
+func TestEnvironmentBuiltins(t *testing.T) {
+	env := object.NewEnvironment()
+
+	env.SetBuiltin("double", func(args ...object.Object) object.Object {
+		return &object.Integer{Value: args[0].(*object.Integer).Value * 2}
+	})
+	env.SetBuiltin("len", func(args ...object.Object) object.Object {
+		return &object.String{Value: "host len"}
+	})
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"double(21)", 42},
+		{"let f = fn(x) { double(x) + 1 }; f(5)", 11},
+		{"iterate(double, 1, 4)", 16},
+		{`len([1, 2])`, "host len"},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		evaluated := Eval(p.ParseProgram(), env)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok || str.Value != expected {
+				t.Errorf("%s: expected %q, got: %T(%+v)", tt.input, expected, evaluated, evaluated)
+			}
+		}
+	}
+
+	// other environments only see the global builtins
+	testIntegerObject(t, testEval("len([1, 2])"), 2)
+
+	if _, ok := testEval("double(1)").(*object.Error); !ok {
+		t.Errorf("double is bound outside the environment it was set in")
+	}
+}
+
+func TestEvalWithContext(t *testing.T) {
+	tests := []string{
+		"do { 1 } while (true)",
+		"let loop = fn() { for (x in lazy_range(0, 1000000000)) { x } }; loop()",
+		// the error can not be caught so the outer loop stops too
+		"do { try { do { 1 } while (true) } catch (e) { e } } while (true)",
+	}
+
+	for _, input := range tests {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+
+		program := parser.New(lexer.New(input)).ParseProgram()
+		evaluated := EvalWithContext(ctx, program, object.NewEnvironment())
+		cancel()
+
+		errorObject, ok := evaluated.(*object.Error)
+		if !ok || errorObject.Message != "evaluation cancelled" {
+			t.Errorf("%s: expected the evaluation to be cancelled, got: %T(%+v)", input, evaluated, evaluated)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	program := parser.New(lexer.New("let f = fn(x) { x * 2 }; f(21)")).ParseProgram()
+	testIntegerObject(t, EvalWithContext(ctx, program, object.NewEnvironment()), 42)
+
+	cancel()
+	if _, ok := EvalWithContext(ctx, program, object.NewEnvironment()).(*object.Error); !ok {
+		t.Errorf("a cancelled context does not stop the evaluation before it starts")
+	}
+
+	// evaluation without a context is never cancelled
+	testIntegerObject(t, testEval("let f = fn(x) { x * 2 }; f(21)"), 42)
+}
+
+func TestEvalWithContextConcurrently(t *testing.T) {
+	endless := parser.New(lexer.New("do { 1 } while (true)")).ParseProgram()
+	counting := parser.New(lexer.New("let i = 0; do { i = i + 1 } while (i < 200000); i")).ParseProgram()
+
+	cancelled, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	// the endless program is cancelled while the other one runs, which must not stop or cancel the other one
+	results := make(chan object.Object, 2)
+	go func() { results <- EvalWithContext(cancelled, endless, object.NewEnvironment()) }()
+	go func() { results <- EvalWithContext(context.Background(), counting, object.NewEnvironment()) }()
+
+	var errors, integers int
+	for i := 0; i < 2; i++ {
+		switch result := (<-results).(type) {
+		case *object.Error:
+			if result.Message != "evaluation cancelled" {
+				t.Errorf("wrong error message. got: %q", result.Message)
+			}
+			errors++
+
+		case *object.Integer:
+			if result.Value != 200000 {
+				t.Errorf("wrong result of the counting program. got: %d", result.Value)
+			}
+			integers++
+
+		default:
+			t.Errorf("unexpected result: %T(%+v)", result, result)
+		}
+	}
+
+	if errors != 1 || integers != 1 {
+		t.Errorf("expected one cancelled and one finished program, got: %d cancelled and %d finished", errors, integers)
+	}
+}
+
+func TestEvalWithHook(t *testing.T) {
+	program := parser.New(lexer.New("let x = 1 + 2; let f = fn(y) { y }; f(x)")).ParseProgram()
+	env := object.NewEnvironment()
+
+	var visited []string
+	evaluated := EvalWithHook(program, env, func(node ast.Node, hookEnv *object.Environment) {
+		visited = append(visited, fmt.Sprintf("%T", node))
+	})
+	testIntegerObject(t, evaluated, 3)
+
+	expected := []string{
+		"*ast.Program",
+		"*ast.LetStatement",
+		"*ast.InfixExpression",
+		"*ast.IntegerLiteral",
+		"*ast.IntegerLiteral",
+		"*ast.LetStatement",
+		"*ast.FunctionLiteral",
+		"*ast.ExpressionStatement",
+		"*ast.CallExpression",
+		"*ast.Identifier",
+		"*ast.Identifier",
+		"*ast.BlockStatement",
+		"*ast.ExpressionStatement",
+		"*ast.Identifier",
+	}
+
+	if strings.Join(visited, " ") != strings.Join(expected, " ") {
+		t.Errorf("wrong nodes visited.\nexpected: %v\ngot: %v", expected, visited)
+	}
+
+	// the hook is only used for the evaluation it was passed to
+	visited = nil
+	testIntegerObject(t, Eval(program, env), 3)
+	if len(visited) != 0 {
+		t.Errorf("the hook is still called after EvalWithHook returned, visited: %v", visited)
+	}
+}
+
+func TestEvalWithHookConcurrently(t *testing.T) {
+	hooked := parser.New(lexer.New("let i = 0; do { i = i + 1 } while (i < 1000); i")).ParseProgram()
+	other := parser.New(lexer.New("let j = 0; do { j = j + 1 } while (j < 100000); j")).ParseProgram()
+
+	var visited int
+	done := make(chan object.Object)
+	go func() {
+		done <- EvalWithHook(hooked, object.NewEnvironment(), func(node ast.Node, env *object.Environment) {
+			visited++
+		})
+	}()
+
+	// the other program runs at the same time without a hook, it must not call the hook of the first one
+	testIntegerObject(t, Eval(other, object.NewEnvironment()), 100000)
+	testIntegerObject(t, <-done, 1000)
+
+	if visited == 0 || visited > 20000 {
+		t.Errorf("the hook visited %d nodes, expected only the nodes of the hooked program", visited)
+	}
+}
+
+func TestDeepEquality(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"[1, 2] == [1, 2]", true},
+		{"[1, 2] != [1, 2]", false},
+		{"[1, 2] == [2, 1]", false},
+		{"[1, 2] == [1, 2, 3]", false},
+		{"[] == []", true},
+		{"[[1, [2]], [3]] == [[1, [2]], [3]]", true},
+		{"[[1, [2]], [3]] == [[1, [4]], [3]]", false},
+		{"[1, first([]), true] == [1, first([]), true]", true},
+		{"[9223372036854775807 * 2] == [9223372036854775807 * 2]", true},
+		{"[1] == {1: 1}", false},
+		{"[1] == 1", false},
+		{`{"a": 1, "b": [1, 2]} == {"b": [1, 2], "a": 1}`, true},
+		{`{"a": 1, "b": [1, 2]} == {"a": 1, "b": [2, 1]}`, false},
+		{`{"a": {"b": {"c": 1}}} == {"a": {"b": {"c": 1}}}`, true},
+		{`{"a": {"b": {"c": 1}}} != {"a": {"b": {"c": 2}}}`, true},
+		{`{"a": 1} == {"a": 1, "b": 2}`, false},
+		{`{"a": 1} == {"b": 1}`, false},
+		{`let a = "a"; [a] == [a]`, true},
+		{"let f = fn() {}; [f] == [f]", true},
+		{"[fn() {}] == [fn() {}]", false},
+		// structures that contain themselves are compared without recursing forever
+		{"let a = [1]; a[0] = a; a == a", true},
+		{"let a = [1]; a[0] = a; let b = [1]; b[0] = b; a == b", true},
+		{`let a = {"n": 1}; a["self"] = a; let b = {"n": 2}; b["self"] = b; a == b`, false},
+	}
+
+	for _, tt := range tests {
+		testBooleanObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestFreeze(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let a = freeze([1, 2, 3]); a[0] = 5", errorMessage("cannot modify frozen value")},
+		{"let a = freeze([1, 2, 3]); a[1] + a[2]", 5},
+		{"let a = freeze([1, 2, 3]); len(a)", 3},
+		{"let a = freeze([1, 2, 3]); let b = push(a, 4); b[0] = 5; b[0]", 5},
+		{"let a = [1, 2, 3]; freeze(a); a[0] = 5", errorMessage("cannot modify frozen value")},
+		{"let a = [1, 2, 3]; let b = freeze(a); a == b", true},
+		{"let a = freeze([1, 2, 3]); try { a[0] = 5 } catch (e) { a[0] }", 1},
+		{`let h = freeze({"a": 1}); h["b"] = 2`, errorMessage("cannot modify frozen value")},
+		{`let h = freeze({"a": 1}); h["a"] = 2`, errorMessage("cannot modify frozen value")},
+		{`let h = freeze({"a": 1}); h["a"]`, 1},
+		// a copy of a frozen value can be changed
+		{"let a = copy(freeze([1, 2, 3])); a[0] = 5; a[0]", 5},
+		// freezing is shallow, arrays inside a frozen array can still change
+		{"let a = freeze([[1]]); a[0][0] = 5; a[0][0]", 5},
+		{"freeze(1)", errorMessage("argument to freeze must be an array or hash, got: INTEGER")},
+		{"freeze()", errorMessage("freeze: wrong number of arguments. got: 0 want: 1")},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+
+		case errorMessage:
+			errorObject, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("no error object returned for %q, got: %T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+
+			if errorObject.Message != string(expected) {
+				t.Errorf("wrong error message. expected: %q, got: %q", expected, errorObject.Message)
+			}
+		}
+	}
+}
+
+func TestReadAndWriteFile(t *testing.T) {
+	SetFileAccess(true)
+	defer SetFileAccess(false)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.txt")
+
+	evaluated := testEval(`write_file("` + path + `", "first line
+second line")`)
+	testNullObject(t, evaluated)
+
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("file was not written: %s", err)
+	}
+	if string(contents) != "first line\nsecond line" {
+		t.Errorf("wrong contents written. got: %q", contents)
+	}
+
+	evaluated = testEval(`let text = read_file("` + path + `"); [text, len(text)]`)
+	if evaluated.Inspect() != "[first line\nsecond line, 22]" {
+		t.Errorf("wrong contents read. got: %s", evaluated.Inspect())
+	}
+
+	// writing again replaces the contents
+	evaluated = testEval(`write_file("` + path + `", "new"); read_file("` + path + `")`)
+	if evaluated.Inspect() != "new" {
+		t.Errorf("wrong contents after writing again. got: %s", evaluated.Inspect())
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`read_file("` + filepath.Join(dir, "missing.txt") + `")`, "read_file: open " + filepath.Join(dir, "missing.txt") + ": no such file or directory"},
+		{`write_file("` + filepath.Join(dir, "missing", "a.txt") + `", "")`, "write_file: open " + filepath.Join(dir, "missing", "a.txt") + ": no such file or directory"},
+		{`read_file(1)`, "argument to read_file must be a string, got: INTEGER"},
+		{`write_file("` + path + `", 1)`, "second argument to write_file must be a string, got: INTEGER"},
+		{`write_file("` + path + `")`, "write_file: wrong number of arguments. got: 1 want: 2"},
+	}
+
+	for _, tt := range tests {
+		errorObject, ok := testEval(tt.input).(*object.Error)
+		if !ok {
+			t.Errorf("%s: no error object returned", tt.input)
+			continue
+		}
+
+		if errorObject.Message != tt.expected {
+			t.Errorf("wrong error message. expected: %q, got: %q", tt.expected, errorObject.Message)
+		}
+	}
+}
+
+func TestFileAccessIsOffByDefault(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.txt")
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`write_file("` + path + `", "x")`, "write_file: file access is turned off"},
+		{`read_file("` + path + `")`, "read_file: file access is turned off"},
+	}
+
+	for _, tt := range tests {
+		errorObject, ok := testEval(tt.input).(*object.Error)
+		if !ok {
+			t.Errorf("%s: no error object returned", tt.input)
+			continue
+		}
+
+		if errorObject.Message != tt.expected {
+			t.Errorf("wrong error message. expected: %q, got: %q", tt.expected, errorObject.Message)
+		}
+	}
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("write_file created the file while file access is turned off")
+	}
+}
+
+func TestInput(t *testing.T) {
+	var out bytes.Buffer
+	SetOutput(&out)
+	defer SetOutput(os.Stdout)
+
+	SetInput(strings.NewReader("Ada\r\nLovelace\nlast line"))
+	defer SetInput(os.Stdin)
+
+	evaluated := testEval(`let first = input("first name: "); let last = input(); [first, last, input(), input()]`)
+	if evaluated.Inspect() != "[Ada, Lovelace, last line, null]" {
+		t.Errorf("wrong lines read. got: %s", evaluated.Inspect())
+	}
+
+	if out.String() != "first name: " {
+		t.Errorf("wrong prompt written. got: %q", out.String())
+	}
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`input(1)`, "argument to input must be a string, got: INTEGER"},
+		{`input("a", "b")`, "input: wrong number of arguments. got: 2 want: 0 or 1"},
+	}
+
+	for _, tt := range tests {
+		errorObject, ok := testEval(tt.input).(*object.Error)
+		if !ok {
+			t.Errorf("%s: no error object returned", tt.input)
+			continue
+		}
+
+		if errorObject.Message != tt.expected {
+			t.Errorf("wrong error message. expected: %q, got: %q", tt.expected, errorObject.Message)
+		}
+	}
+}
+
+func TestContainersThatHoldThemselves(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let a = [0]; a[0] = a; a", "[[...]]"},
+		{`let h = {"n": 1}; h["self"] = h; h`, "{n: 1, self: {...}}"},
+		// the copy holds itself rather than the original
+		{"let a = [0, 1]; a[0] = a; let b = copy(a); b[1] = 2; [a[1], b[0][1]]", "[1, 2]"},
+		{`let h = {"n": 1}; h["self"] = h; let c = copy(h); c["n"] = 2; c["self"]["n"]`, 2},
+		{"let a = [0]; a[0] = a; let b = copy(a); b == a", true},
+		// an array inside itself is kept instead of being spliced again
+		{"let a = [1, 0]; a[1] = a; let f = flatten(a, 100000); [len(f), f[0], f[1] == a]", "[2, 1, true]"},
+		{"let a = [1, 0]; a[1] = a; len(flatten([a, a], 2))", 4},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+
+		case string:
+			if evaluated.Inspect() != expected {
+				t.Errorf("wrong result for %q. expected: %s, got: %s", tt.input, expected, evaluated.Inspect())
+			}
+		}
+	}
+
+	var out bytes.Buffer
+	SetOutput(&out)
+	defer SetOutput(os.Stdout)
+
+	testEval("let a = [0]; a[0] = a; puts(a); pprint(a)")
+	if out.String() != "[[...]]\n[\n  [...]\n]\n" {
+		t.Errorf("wrong output. got: %q", out.String())
+	}
+}
+
+func TestCompiledFunctionBodiesMatchEval(t *testing.T) {
+	tests := []string{
+		"let f = fn(x) { if (x > 1) { return x * 2; } x - 1 }; [f(5), f(0)]",
+		"let f = fn(x) { let y = x + 1; let z = -y; [y, z, !true] }; f(2)",
+		"let add = fn(x) { fn(y) { x + y } }; let inc = add(1); [inc(1), add(10)(5)]",
+		"let f = fn(a, b = 3) { a * b }; let args = [2, 4]; [f(2), f(...args)]",
+		`let f = fn(h) { h["get"]() }; f({"v": 7, "get": fn() { self["v"] }})`,
+		"let f = fn(xs) { xs[1] + len(xs) }; f([1, 2, 3])",
+		"let f = fn(x) { x > 0 && 10 / x > 1 }; [f(0), f(2), f(20)]",
+		"let f = fn() { eval(\"let hidden = 4\"); hidden }; f()",
+		"let f = fn(x) { if (x == 1) { 1 } else if (x == 2) { 2 } }; [f(1), f(2), f(3)]",
+		"let f = fn(x) { x + true }; f(1)",
+		"let f = fn() { missing }; f()",
+		"let f = fn(x) { return x; 1 / 0 }; f(3)",
+		"let f = fn(n) { let total = 0; for (i in [1, 2, 3]) { total = total + i * n } total }; f(2)",
+		"let fib = fn(x) { if (x < 2) { x } else { fib(x - 1) + fib(x - 2) } }; fib(15)",
+	}
+
+	for _, input := range tests {
+		program := parser.New(lexer.New(input)).ParseProgram()
+
+		// a hook makes the evaluator walk every function body node by node
+		expected := EvalWithHook(program, object.NewEnvironment(), func(node ast.Node, env *object.Environment) {})
+		evaluated := Eval(program, object.NewEnvironment())
+
+		if evaluated.Type() != expected.Type() || evaluated.Inspect() != expected.Inspect() {
+			t.Errorf("%s: the compiled body gives %s %s, walking the body gives %s %s", input, evaluated.Type(), evaluated.Inspect(), expected.Type(), expected.Inspect())
+		}
+	}
+}
+
+func TestFunctionBodyIsCompiledOnTheFirstCall(t *testing.T) {
+	env := object.NewEnvironment()
+	testIntegerObject(t, Eval(parser.New(lexer.New("let called = fn(x) { x }; let idle = fn(x) { x }; called(1)")).ParseProgram(), env), 1)
+
+	called, _ := env.Get("called")
+	if called.(*object.Function).Compiled == nil {
+		t.Errorf("the body of a called function is not compiled")
+	}
+
+	idle, _ := env.Get("idle")
+	if idle.(*object.Function).Compiled != nil {
+		t.Errorf("the body of a function that was never called is compiled")
+	}
+}
+
+func TestSmallIntegersAreCached(t *testing.T) {
+	tests := []struct {
+		input  string
+		cached bool
+	}{
+		{"0", true},
+		{"1 + 1", true},
+		{"-128", true},
+		{"200 + 56", true},
+		{"len([1, 2, 3])", true},
+		{"-129", false},
+		{"200 + 57", false},
+		{"1000 * 1000", false},
+	}
+
+	for _, tt := range tests {
+		first, ok := testEval(tt.input).(*object.Integer)
+		if !ok {
+			t.Fatalf("%s did not evaluate to an integer", tt.input)
+		}
+		second := testEval(tt.input).(*object.Integer)
+
+		if (first == second) != tt.cached {
+			t.Errorf("%s: cached is not %t, the two evaluations gave %p and %p", tt.input, tt.cached, first, second)
+		}
+
+		if first.Value != second.Value {
+			t.Errorf("%s: evaluations differ, got: %d and %d", tt.input, first.Value, second.Value)
+		}
+	}
+}
+
+func TestBooleansAndNullAreShared(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected object.Object
+	}{
+		{"true", TRUE},
+		{"1 < 2", TRUE},
+		{"!false", TRUE},
+		{"!!1", TRUE},
+		{`"a" == "a" || true`, TRUE},
+		{"bool(1)", TRUE},
+		{"[1] == [2]", FALSE},
+		{"!true", FALSE},
+		{"1 > 2", FALSE},
+		{"bool(first([]))", FALSE},
+		{"if (false) { 1 }", NULL},
+		{"first([])", NULL},
+		{"let f = fn() {}; f()", NULL},
+	}
+
+	for _, tt := range tests {
+		first := testEval(tt.input)
+		second := testEval(tt.input)
+
+		if first != tt.expected || second != tt.expected {
+			t.Errorf("%s does not evaluate to the shared %s object, got: %p and %p want: %p", tt.input, tt.expected.Inspect(), first, second, tt.expected)
+		}
+	}
+}
+
+// benchmarkEval evaluates the input b.N times, the program is parsed once and not optimized so every operation is measured
+func benchmarkEval(b *testing.B, input string) {
+	b.Helper()
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		b.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	if result, ok := Eval(program, object.NewEnvironment()).(*object.Error); ok {
+		b.Fatalf("benchmark program failed: %s", result.Message)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		Eval(program, object.NewEnvironment())
+	}
+}
+
+func BenchmarkSmallIntegerSum(b *testing.B) {
+	benchmarkEval(b, `
+	let total = 0;
+	let i = 0;
+	do {
+		total = total + 1;
+		i = i + 1;
+	} while (i < 200);
+	total;
+	`)
+}
+
+func BenchmarkArithmeticLoop(b *testing.B) {
+	benchmarkEval(b, `
+	let total = 0;
+	let i = 0;
+	do {
+		total = total + i * i - i / 3;
+		i = i + 1;
+	} while (i < 1000);
+	total;
+	`)
+}
+
+func BenchmarkFib(b *testing.B) {
+	benchmarkEval(b, `
+	let fib = fn(x) {
+		if (x < 2) {
+			x
+		} else {
+			fib(x - 1) + fib(x - 2)
+		}
+	};
+	fib(30);
+	`)
+}
+
+func BenchmarkArrayMap(b *testing.B) {
+	benchmarkEval(b, `
+	let map = fn(arr, f) {
+		let result = [];
+		for (x in arr) {
+			result = push(result, f(x));
+		}
+		result
+	};
+	let numbers = [];
+	for (i in lazy_range(0, 500)) {
+		numbers = push(numbers, i);
+	}
+	map(numbers, fn(x) { x * 2 });
+	`)
+}
+
+func BenchmarkArrayFilter(b *testing.B) {
+	benchmarkEval(b, `
+	let filter = fn(arr, keep) {
+		let result = [];
+		for (x in arr) {
+			if (keep(x)) {
+				result = push(result, x);
+			}
+		}
+		result
+	};
+	let numbers = [];
+	for (i in lazy_range(0, 500)) {
+		numbers = push(numbers, i);
+	}
+	filter(numbers, fn(x) { x / 2 * 2 == x });
+	`)
+}
+
+func BenchmarkArrayReduce(b *testing.B) {
+	benchmarkEval(b, `
+	let reduce = fn(arr, initial, f) {
+		if (len(arr) == 0) {
+			return initial;
+		}
+		reduce(rest(arr), f(initial, first(arr)), f)
+	};
+	let numbers = [];
+	for (i in lazy_range(0, 200)) {
+		numbers = push(numbers, i);
+	}
+	reduce(numbers, 0, fn(total, x) { total + x });
+	`)
+}
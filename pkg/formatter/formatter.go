@@ -0,0 +1,337 @@
+/*
+* Package formatter turns an abstract syntax tree back into jaba source code in one canonical layout.
+* Every statement is written on its own line, blocks are indented by two spaces and
+* parentheses are only kept where the precedence of the operators needs them.
+* Formatting the output again gives the same output.
+*
+* Example:
+* user input:
+* let add=fn(a,b){return (a+b)*2}
+* output
+* let add = fn(a, b) {
+*   return (a + b) * 2;
+* };
+ */
+package formatter
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/maxwellgithinji/jaba/pkg/ast"
+	"github.com/maxwellgithinji/jaba/pkg/token"
+)
+
+// indentation is written once for every level of nesting
+const indentation = "  "
+
+// the precedences mirror the ones the parser uses to build the tree
+const (
+	_ int = iota
+	lowest
+	assign
+	logicalOr
+	logicalAnd
+	equals
+	lessGreater
+	sum
+	product
+	prefix
+	call
+	primary
+)
+
+// precedences maps infix operators to their precedence
+var precedences = map[string]int{
+	"||": logicalOr,
+	"&&": logicalAnd,
+	"==": equals,
+	"!=": equals,
+	"<":  lessGreater,
+	">":  lessGreater,
+	"+":  sum,
+	"-":  sum,
+	"*":  product,
+	"/":  product,
+}
+
+// Format returns the canonical source code of a program, ending with a newline unless the program is empty
+func Format(program *ast.Program) string {
+	lines := formatStatements(program.Statements, 0)
+	if len(lines) == 0 {
+		return ""
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// formatStatements formats a list of statements at the given depth of nesting, one line per statement
+// a statement can span several lines when it holds a block
+func formatStatements(statements []ast.Statement, depth int) []string {
+	formatted := make([]string, len(statements))
+	for i, statement := range statements {
+		formatted[i] = formatStatement(statement, depth)
+	}
+
+	lines := make([]string, len(statements))
+	for i, statement := range statements {
+		line := formatted[i]
+
+		// block like expressions end with } and only need a semicolon when the next statement would otherwise continue them
+		if statement, ok := statement.(*ast.ExpressionStatement); ok {
+			if !isBlockLike(statement.Value) || (i+1 < len(formatted) && continuesExpression(formatted[i+1])) {
+				line += ";"
+			}
+		}
+
+		lines[i] = strings.Repeat(indentation, depth) + line
+	}
+
+	return lines
+}
+
+// formatStatement formats a statement without the indentation of its first line
+func formatStatement(statement ast.Statement, depth int) string {
+	switch statement := statement.(type) {
+	case *ast.LetStatement:
+		return "let " + statement.Name.Value + " = " + formatExpression(statement.Value, depth) + ";"
+
+	case *ast.DestructuringLetStatement:
+		names := make([]string, len(statement.Names))
+		for i, name := range statement.Names {
+			names[i] = name.Value
+		}
+		return "let " + strings.Join(names, ", ") + " = " + formatExpression(statement.Value, depth) + ";"
+
+	case *ast.ReturnStatement:
+		return "return " + formatExpression(statement.Value, depth) + ";"
+
+	case *ast.YieldStatement:
+		return "yield " + formatExpression(statement.Value, depth) + ";"
+
+	case *ast.ThrowStatement:
+		return "throw " + formatExpression(statement.Value, depth) + ";"
+
+	case *ast.ExpressionStatement:
+		// the semicolon is added by formatStatements which knows the next statement
+		return formatExpression(statement.Value, depth)
+
+	case *ast.BlockStatement:
+		return formatBlock(statement, depth)
+	}
+
+	return statement.String()
+}
+
+// formatBlock formats a block in braces, its statements are nested one level deeper than depth
+func formatBlock(block *ast.BlockStatement, depth int) string {
+	if block == nil || len(block.Statements) == 0 {
+		return "{}"
+	}
+
+	lines := formatStatements(block.Statements, depth+1)
+
+	return "{\n" + strings.Join(lines, "\n") + "\n" + strings.Repeat(indentation, depth) + "}"
+}
+
+// formatExpression formats an expression, depth is the nesting of the statement the expression belongs to
+func formatExpression(expression ast.Expression, depth int) string {
+	switch expression := expression.(type) {
+	case nil:
+		return ""
+
+	case *ast.Identifier:
+		return expression.Value
+
+	case *ast.IntegerLiteral:
+		return strconv.FormatInt(expression.Value, 10)
+
+	case *ast.Boolean:
+		return strconv.FormatBool(expression.Value)
+
+	case *ast.StringLiteral:
+		return `"` + expression.Value + `"`
+
+	case *ast.PrefixExpression:
+		return expression.Operator + formatOperand(expression.Right, prefix, depth)
+
+	case *ast.InfixExpression:
+		precedence := precedences[expression.Operator]
+		// operators are left associative so only the right operand needs parentheses at the same precedence
+		left := formatOperand(expression.Left, precedence, depth)
+		right := formatOperand(expression.Right, precedence+1, depth)
+		return left + " " + expression.Operator + " " + right
+
+	case *ast.AssignExpression:
+		return formatExpression(expression.Target, depth) + " = " + formatExpression(expression.Value, depth)
+
+	case *ast.CallExpression:
+		return formatOperand(expression.Function, call, depth) + "(" + formatList(expression.Arguments, depth) + ")"
+
+	case *ast.IndexExpression:
+		return formatOperand(expression.Left, call, depth) + "[" + formatExpression(expression.Index, depth) + "]"
+
+	case *ast.ArrayLiteral:
+		return "[" + formatList(expression.Elements, depth) + "]"
+
+	case *ast.SpreadExpression:
+		return "..." + formatExpression(expression.Value, depth)
+
+	case *ast.HashLiteral:
+		return formatHashLiteral(expression, depth)
+
+	case *ast.FunctionLiteral:
+		params := make([]string, len(expression.Parameters))
+		for i, param := range expression.Parameters {
+			params[i] = param.Value
+			if expression.Defaults != nil && expression.Defaults[i] != nil {
+				params[i] += " = " + formatExpression(expression.Defaults[i], depth)
+			}
+		}
+		return "fn(" + strings.Join(params, ", ") + ") " + formatBlock(expression.Body, depth)
+
+	case *ast.IfExpression:
+		out := "if (" + formatExpression(expression.Condition, depth) + ") " + formatBlock(expression.Consequence, depth)
+		if expression.ElseIf != nil {
+			out += " else " + formatExpression(expression.ElseIf, depth)
+		}
+		if expression.Alternative != nil {
+			out += " else " + formatBlock(expression.Alternative, depth)
+		}
+		return out
+
+	case *ast.DoWhileExpression:
+		return "do " + formatBlock(expression.Body, depth) + " while (" + formatExpression(expression.Condition, depth) + ")"
+
+	case *ast.ForInExpression:
+		return "for (" + expression.Variable.Value + " in " + formatExpression(expression.Iterable, depth) + ") " + formatBlock(expression.Body, depth)
+
+	case *ast.CollectExpression:
+		return "collect " + formatExpression(expression.Loop, depth)
+
+	case *ast.TryExpression:
+		return "try " + formatBlock(expression.Block, depth) + " catch (" + expression.Parameter.Value + ") " + formatBlock(expression.Catch, depth)
+	}
+
+	return expression.String()
+}
+
+// formatOperand formats an expression and wraps it in parentheses when it binds less tightly than minimum
+func formatOperand(expression ast.Expression, minimum int, depth int) string {
+	formatted := formatExpression(expression, depth)
+	if precedence(expression) < minimum {
+		return "(" + formatted + ")"
+	}
+	return formatted
+}
+
+// precedence returns how tightly an expression binds, literals and expressions that end with a block bind the tightest
+func precedence(expression ast.Expression) int {
+	switch expression := expression.(type) {
+	case *ast.AssignExpression:
+		return assign
+
+	case *ast.InfixExpression:
+		return precedences[expression.Operator]
+
+	case *ast.PrefixExpression:
+		return prefix
+
+	case *ast.CallExpression, *ast.IndexExpression:
+		return call
+	}
+
+	return primary
+}
+
+// formatList formats the expressions of a call or an array literal separated by commas
+func formatList(expressions []ast.Expression, depth int) string {
+	formatted := make([]string, len(expressions))
+	for i, expression := range expressions {
+		formatted[i] = formatExpression(expression, depth)
+	}
+	return strings.Join(formatted, ", ")
+}
+
+// formatHashLiteral formats the pairs of a hash literal in the order they appear in the source
+func formatHashLiteral(hash *ast.HashLiteral, depth int) string {
+	keys := make([]ast.Expression, 0, len(hash.Pairs))
+	for key := range hash.Pairs {
+		keys = append(keys, key)
+	}
+
+	// the pairs are stored in a map, the position of the token of every key restores the source order
+	sort.Slice(keys, func(i, j int) bool {
+		left, right := tokenOf(keys[i]), tokenOf(keys[j])
+		if left.Line != right.Line {
+			return left.Line < right.Line
+		}
+		if left.Column != right.Column {
+			return left.Column < right.Column
+		}
+		return keys[i].String() < keys[j].String()
+	})
+
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = formatExpression(key, depth) + ": " + formatExpression(hash.Pairs[key], depth)
+	}
+
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+// tokenOf returns the token of an expression, it lies inside the source of the expression so it orders expressions by position
+func tokenOf(expression ast.Expression) token.Token {
+	switch expression := expression.(type) {
+	case *ast.Identifier:
+		return expression.Token
+	case *ast.IntegerLiteral:
+		return expression.Token
+	case *ast.Boolean:
+		return expression.Token
+	case *ast.StringLiteral:
+		return expression.Token
+	case *ast.PrefixExpression:
+		return expression.Token
+	case *ast.InfixExpression:
+		return expression.Token
+	case *ast.AssignExpression:
+		return expression.Token
+	case *ast.CallExpression:
+		return expression.Token
+	case *ast.IndexExpression:
+		return expression.Token
+	case *ast.ArrayLiteral:
+		return expression.Token
+	case *ast.HashLiteral:
+		return expression.Token
+	case *ast.FunctionLiteral:
+		return expression.Token
+	case *ast.IfExpression:
+		return expression.Token
+	case *ast.DoWhileExpression:
+		return expression.Token
+	case *ast.ForInExpression:
+		return expression.Token
+	case *ast.CollectExpression:
+		return expression.Token
+	case *ast.TryExpression:
+		return expression.Token
+	}
+	return token.Token{}
+}
+
+// isBlockLike reports whether an expression ends with the closing brace of a block when it is a statement of its own
+func isBlockLike(expression ast.Expression) bool {
+	switch expression.(type) {
+	case *ast.IfExpression, *ast.DoWhileExpression, *ast.ForInExpression, *ast.CollectExpression, *ast.TryExpression:
+		return true
+	}
+	return false
+}
+
+// continuesExpression reports whether a statement starts with a token the parser would read as part of the statement before it
+func continuesExpression(statement string) bool {
+	return strings.HasPrefix(statement, "(") || strings.HasPrefix(statement, "[") || strings.HasPrefix(statement, "-")
+}
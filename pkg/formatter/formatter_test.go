@@ -0,0 +1,108 @@
+package formatter
+
+import (
+	"testing"
+
+	"github.com/maxwellgithinji/jaba/pkg/lexer"
+	"github.com/maxwellgithinji/jaba/pkg/parser"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"", ""},
+		{"let   x=5", "let x = 5;\n"},
+		{"1+2*3; (1+2)*3", "1 + 2 * 3;\n(1 + 2) * 3;\n"},
+		{"a-(b-c); (a-b)-c; -(a+b); --a; !(a==b)", "a - (b - c);\na - b - c;\n-(a + b);\n--a;\n!(a == b);\n"},
+		{"x=y=1; (x=1)+2", "x = y = 1;\n(x = 1) + 2;\n"},
+		{`let a,b=[1,"two",true]; {"k":a, 1:[b]}[1]`, "let a, b = [1, \"two\", true];\n{\"k\": a, 1: [b]}[1];\n"},
+		{`{"z": 1, "a": 2, "m": 3}`, "{\"z\": 1, \"a\": 2, \"m\": 3};\n"},
+		{"f(g(1),h[2])(3)", "f(g(1), h[2])(3);\n"},
+		{"f(...args,1); [...a,...[b]]", "f(...args, 1);\n[...a, ...[b]];\n"},
+		{"fn(){}; fn(x,y=2){x+y}", "fn() {};\nfn(x, y = 2) {\n  x + y;\n};\n"},
+		{
+			"if(x>1){x}else if(x<0){0-x}else{0}",
+			"if (x > 1) {\n  x;\n} else if (x < 0) {\n  0 - x;\n} else {\n  0;\n}\n",
+		},
+		{
+			"do{i=i+1}while(i<10) for(x in xs){puts(x)}",
+			"do {\n  i = i + 1;\n} while (i < 10)\nfor (x in xs) {\n  puts(x);\n}\n",
+		},
+		{
+			`try{throw "boom"}catch(e){e}`,
+			"try {\n  throw \"boom\";\n} catch (e) {\n  e;\n}\n",
+		},
+		{
+			"let squares=collect for(x in xs){x*x}",
+			"let squares = collect for (x in xs) {\n  x * x;\n};\n",
+		},
+		// the semicolon keeps the array from being read as an index into the if expression
+		{"if (x) { 1 }; [1, 2]", "if (x) {\n  1;\n};\n[1, 2];\n"},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("%q has parser errors: %v", tt.input, p.Errors())
+		}
+
+		formatted := Format(program)
+		if formatted != tt.expected {
+			t.Errorf("wrong format of %q.\nexpected:\n%s\ngot:\n%s", tt.input, tt.expected, formatted)
+		}
+	}
+}
+
+func TestFormatNestedFunction(t *testing.T) {
+	input := `let counter=fn(start){let count=start;return fn(step=1){if(step<0){throw "negative step"};count=count+step;let double=fn(x){x*2};double(count)}};`
+
+	expected := `let counter = fn(start) {
+  let count = start;
+  return fn(step = 1) {
+    if (step < 0) {
+      throw "negative step";
+    }
+    count = count + step;
+    let double = fn(x) {
+      x * 2;
+    };
+    double(count);
+  };
+};
+`
+
+	p := parser.New(lexer.New(input))
+	formatted := Format(p.ParseProgram())
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	if formatted != expected {
+		t.Errorf("wrong format.\nexpected:\n%s\ngot:\n%s", expected, formatted)
+	}
+}
+
+func TestFormatIsStable(t *testing.T) {
+	inputs := []string{
+		"let fib=fn(x){if(x<2){x}else{fib(x-1)+fib(x-2)}}; fib(10)",
+		`let m={"a":fn(x){x},"b":[1,2*(3-4)]}; m["a"](m["b"])`,
+		"let g=fn(){let i=0;do{yield i;i=i+1}while(i<3)}; for(x in g()){puts(x)} -1",
+	}
+
+	for _, input := range inputs {
+		first := Format(parser.New(lexer.New(input)).ParseProgram())
+
+		p := parser.New(lexer.New(first))
+		second := Format(p.ParseProgram())
+		if len(p.Errors()) != 0 {
+			t.Fatalf("formatted source has parser errors: %v\n%s", p.Errors(), first)
+		}
+
+		if first != second {
+			t.Errorf("formatting is not stable.\nfirst:\n%s\nsecond:\n%s", first, second)
+		}
+	}
+}
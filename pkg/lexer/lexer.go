@@ -22,46 +22,64 @@
 
 package lexer
 
-import "github.com/maxwellgithinji/jaba/pkg/token"
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/maxwellgithinji/jaba/pkg/token"
+)
 
 // Lexer defines properties required to turn source code into tokens
 type Lexer struct {
 	// input represent the source code to be tokenized.
 	input string
 
-	// position represents the current position in the source code. it points to the to the index of the current character being read.
+	// position represents the current position in the source code. it points to the to the byte index of the current character being read.
 	position int
 
-	// readPosition represents the next position in the source code. it points to the index of the next character after the position.
+	// readPosition represents the next position in the source code. it points to the byte index of the next character after the position.
 	readPosition int
 
-	// ch represents the current character being examined. (Currently only ASCII characters are supported)
-	ch byte // TODO: change to rune to support unicode characters
+	// ch represents the current character being examined. it is a rune so multibyte utf-8 characters are read whole.
+	ch rune
+
+	// line and column represent the position of the current character, both count from 1.
+	line   int
+	column int
 }
 
 // New returns a new lexer for the input.
 // It also reads the first character of the input and advances the read position to the next character.
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	l := &Lexer{input: input, line: 1}
 
 	l.readChar()
 
 	return l
 }
 
-// readChar reads the next character and advances the read position in the input string (source code).
+// readChar decodes the next utf-8 character and advances the read position in the input string (source code) past all of its bytes.
 func (l *Lexer) readChar() {
+	if l.ch == '\n' {
+		l.line += 1
+		l.column = 0
+	}
+	l.column += 1
+
+	// the end of the input has no width so the positions never go past it
+	width := 0
 	if l.readPosition >= len(l.input) {
 		l.ch = 0 // 0 is an Ascii code for null
 	} else {
-		l.ch = l.input[l.readPosition]
+		l.ch, width = utf8.DecodeRuneInString(l.input[l.readPosition:])
 	}
 
 	l.position = l.readPosition
-	l.readPosition += 1
+	l.readPosition += width
 }
 
-// NextToken returns the next token in the input.
+// NextToken returns the next token in the input, stamped with the line and column it starts at.
 // it converts the input character to a token
 // It then advanced the read position so the next call to NextToken will return the next token in the input.
 // finally, it returns the token
@@ -70,6 +88,8 @@ func (l *Lexer) NextToken() token.Token {
 
 	l.skipWhitespace()
 
+	line, column, offset := l.line, l.column, l.position
+
 	switch l.ch {
 	case '=':
 		if l.peekChar() == '=' {
@@ -104,6 +124,32 @@ func (l *Lexer) NextToken() token.Token {
 			tok = newToken(token.NOPE, l.ch)
 		}
 
+	case '&':
+		if l.peekChar() == '&' {
+			ch := l.ch
+			l.readChar()
+			literal := string(ch) + string(l.ch)
+			tok = token.Token{
+				Type:    token.AND,
+				Literal: literal,
+			}
+		} else {
+			tok = newToken(token.ILLEGAL, l.ch)
+		}
+
+	case '|':
+		if l.peekChar() == '|' {
+			ch := l.ch
+			l.readChar()
+			literal := string(ch) + string(l.ch)
+			tok = token.Token{
+				Type:    token.OR,
+				Literal: literal,
+			}
+		} else {
+			tok = newToken(token.ILLEGAL, l.ch)
+		}
+
 	case '*':
 		tok = newToken(token.ASTERISK, l.ch)
 
@@ -136,6 +182,18 @@ func (l *Lexer) NextToken() token.Token {
 	case ':':
 		tok = newToken(token.COLON, l.ch)
 
+	case '@':
+		tok = newToken(token.AT, l.ch)
+
+	case '.':
+		if strings.HasPrefix(l.input[l.position:], "...") {
+			l.readChar()
+			l.readChar()
+			tok = token.Token{Type: token.ELLIPSIS, Literal: "..."}
+		} else {
+			tok = newToken(token.ILLEGAL, l.ch)
+		}
+
 	case '"':
 		tok.Type = token.STRING
 		tok.Literal = l.readString()
@@ -147,17 +205,20 @@ func (l *Lexer) NextToken() token.Token {
 		tok = newToken(token.RBRACKET, l.ch)
 
 	case 0:
-		tok.Literal = "" // EOF literal is an empty string
-		tok = newToken(token.EOF, l.ch)
+		tok = token.Token{Type: token.EOF, Literal: ""} // EOF literal is an empty string
 
 	default:
 		if isLetter(l.ch) {
 			tok.Literal = l.readIdentifier()
 			tok.Type = token.LookupIdentifier(tok.Literal)
+			tok.Line, tok.Column = line, column
+			tok.Offset, tok.End = offset, l.position
 			return tok
 		} else if isDigit(l.ch) {
 			tok.Literal = l.readNumber()
 			tok.Type = token.INTEGER
+			tok.Line, tok.Column = line, column
+			tok.Offset, tok.End = offset, l.position
 			return tok
 		} else {
 			tok = newToken(token.ILLEGAL, l.ch)
@@ -166,11 +227,27 @@ func (l *Lexer) NextToken() token.Token {
 
 	l.readChar()
 
+	tok.Line, tok.Column = line, column
+	tok.Offset, tok.End = offset, l.position
 	return tok
 }
 
+// Tokens reads the rest of the input and returns its tokens, the last token is always EOF
+func (l *Lexer) Tokens() []token.Token {
+	tokens := []token.Token{}
+
+	for {
+		tok := l.NextToken()
+		tokens = append(tokens, tok)
+
+		if tok.Type == token.EOF {
+			return tokens
+		}
+	}
+}
+
 // newToken returns a new token with the given type and literal.
-func newToken(tokenType token.TokenType, ch byte) token.Token {
+func newToken(tokenType token.TokenType, ch rune) token.Token {
 	return token.Token{
 		Type:    tokenType,
 		Literal: string(ch),
@@ -188,10 +265,10 @@ func (l *Lexer) readIdentifier() string {
 	return l.input[position:l.position]
 }
 
-// isLetter returns true if the given character is a letter.
+// isLetter returns true if the given character is a letter in any script e.g. a, é or π.
 // we also include the underscore character as a letter.
-func isLetter(ch byte) bool {
-	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+func isLetter(ch rune) bool {
+	return unicode.IsLetter(ch) || ch == '_'
 }
 
 // skipWhitespace skips over all the whitespace characters in the input.
@@ -214,18 +291,19 @@ func (l *Lexer) readNumber() string {
 }
 
 // isDigit returns true if the given character is a digit.
-func isDigit(ch byte) bool {
+func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
 
 // peekChar returns the next character in the input without advancing the read position.
 // it has the same behavior as the readChar function except that it does not advance the read position.
-func (l *Lexer) peekChar() byte {
+func (l *Lexer) peekChar() rune {
 	if l.readPosition >= len(l.input) {
 		return 0 // 0 is an Ascii code for null
-	} else {
-		return l.input[l.readPosition]
 	}
+
+	ch, _ := utf8.DecodeRuneInString(l.input[l.readPosition:])
+	return ch
 }
 
 // readString loops until it encounters a closing quote or the end of the input and returns the string enclosed by the quotes
@@ -191,3 +191,302 @@ func TestNextTokenJabaProgram(t *testing.T) {
 	}
 
 }
+
+func TestNextTokenLogicalOperators(t *testing.T) {
+	input := `a && b || c & d | e`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENTIFIER, "a"},
+		{token.AND, "&&"},
+		{token.IDENTIFIER, "b"},
+		{token.OR, "||"},
+		{token.IDENTIFIER, "c"},
+		{token.ILLEGAL, "&"},
+		{token.IDENTIFIER, "d"},
+		{token.ILLEGAL, "|"},
+		{token.IDENTIFIER, "e"},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - wrong token type. expected = %q, got %q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong token literal. expected = %q, got %q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestNextTokenLoops(t *testing.T) {
+	input := `do { x } while (x < 10); for (x in xs) {}`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.DO, "do"},
+		{token.LBRACE, "{"},
+		{token.IDENTIFIER, "x"},
+		{token.RBRACE, "}"},
+		{token.WHILE, "while"},
+		{token.LPAREN, "("},
+		{token.IDENTIFIER, "x"},
+		{token.LT, "<"},
+		{token.INTEGER, "10"},
+		{token.RPAREN, ")"},
+		{token.SEMICOLON, ";"},
+		{token.FOR, "for"},
+		{token.LPAREN, "("},
+		{token.IDENTIFIER, "x"},
+		{token.IN, "in"},
+		{token.IDENTIFIER, "xs"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.RBRACE, "}"},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - wrong token type. expected = %q, got %q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong token literal. expected = %q, got %q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestNextTokenAnnotation(t *testing.T) {
+	input := `@memoize fn(x) { x }`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.AT, "@"},
+		{token.IDENTIFIER, "memoize"},
+		{token.FUNCTION, "fn"},
+		{token.LPAREN, "("},
+		{token.IDENTIFIER, "x"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.IDENTIFIER, "x"},
+		{token.RBRACE, "}"},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - wrong token type. expected = %q, got %q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong token literal. expected = %q, got %q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestNextTokenSpread(t *testing.T) {
+	input := `f(...args); [...a, b]; x..y`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENTIFIER, "f"},
+		{token.LPAREN, "("},
+		{token.ELLIPSIS, "..."},
+		{token.IDENTIFIER, "args"},
+		{token.RPAREN, ")"},
+		{token.SEMICOLON, ";"},
+		{token.LBRACKET, "["},
+		{token.ELLIPSIS, "..."},
+		{token.IDENTIFIER, "a"},
+		{token.COMMA, ","},
+		{token.IDENTIFIER, "b"},
+		{token.RBRACKET, "]"},
+		{token.SEMICOLON, ";"},
+		{token.IDENTIFIER, "x"},
+		{token.ILLEGAL, "."},
+		{token.ILLEGAL, "."},
+		{token.IDENTIFIER, "y"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - wrong token type. expected = %q, got %q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong token literal. expected = %q, got %q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestTokens(t *testing.T) {
+	expected := []token.Token{
+		{Type: token.LET, Literal: "let", Line: 1, Column: 1, Offset: 0, End: 3},
+		{Type: token.IDENTIFIER, Literal: "x", Line: 1, Column: 5, Offset: 4, End: 5},
+		{Type: token.ASSIGN, Literal: "=", Line: 1, Column: 7, Offset: 6, End: 7},
+		{Type: token.INTEGER, Literal: "1", Line: 1, Column: 9, Offset: 8, End: 9},
+		{Type: token.PLUS, Literal: "+", Line: 1, Column: 11, Offset: 10, End: 11},
+		{Type: token.STRING, Literal: "two", Line: 1, Column: 13, Offset: 12, End: 17},
+		{Type: token.SEMICOLON, Literal: ";", Line: 1, Column: 18, Offset: 17, End: 18},
+		{Type: token.EOF, Literal: "", Line: 1, Column: 19, Offset: 18, End: 18},
+	}
+
+	tokens := New(`let x = 1 + "two";`).Tokens()
+
+	if len(tokens) != len(expected) {
+		t.Fatalf("wrong number of tokens. expected: %d, got: %d %v", len(expected), len(tokens), tokens)
+	}
+
+	for i, tok := range tokens {
+		if tok != expected[i] {
+			t.Errorf("tokens[%d] is wrong. expected: %+v, got: %+v", i, expected[i], tok)
+		}
+	}
+
+	empty := New("").Tokens()
+	if len(empty) != 1 || empty[0].Type != token.EOF {
+		t.Errorf("tokens of an empty input are not [EOF], got: %v", empty)
+	}
+}
+
+func TestTokenPositions(t *testing.T) {
+	input := `let x = 5;
+  x == 10
+	"a
+b" @`
+
+	tests := []struct {
+		expectedType   token.TokenType
+		expectedLine   int
+		expectedColumn int
+	}{
+		{token.LET, 1, 1},
+		{token.IDENTIFIER, 1, 5},
+		{token.ASSIGN, 1, 7},
+		{token.INTEGER, 1, 9},
+		{token.SEMICOLON, 1, 10},
+		{token.IDENTIFIER, 2, 3},
+		{token.EQ, 2, 5},
+		{token.INTEGER, 2, 8},
+		{token.STRING, 3, 2},
+		{token.AT, 4, 4},
+		{token.EOF, 4, 5},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - wrong token type. expected = %q, got %q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Line != tt.expectedLine || tok.Column != tt.expectedColumn {
+			t.Errorf("tests[%d] - wrong position of %s. expected = %d:%d, got %d:%d", i, tok.Type, tt.expectedLine, tt.expectedColumn, tok.Line, tok.Column)
+		}
+	}
+}
+
+func TestNextTokenUnicode(t *testing.T) {
+	input := `let café = "héllo 🌍";
+π_ratio + naïve;
+"日本語" €`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LET, "let"},
+		{token.IDENTIFIER, "café"},
+		{token.ASSIGN, "="},
+		{token.STRING, "héllo 🌍"},
+		{token.SEMICOLON, ";"},
+		{token.IDENTIFIER, "π_ratio"},
+		{token.PLUS, "+"},
+		{token.IDENTIFIER, "naïve"},
+		{token.SEMICOLON, ";"},
+		{token.STRING, "日本語"},
+		{token.ILLEGAL, "€"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - wrong token type. expected = %q, got %q", i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong token literal. expected = %q, got %q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestTokenPositionsCountCharacters(t *testing.T) {
+	tokens := New(`"é🌍" café x`).Tokens()
+
+	expected := []struct {
+		literal string
+		column  int
+	}{
+		{"é🌍", 1},
+		{"café", 6},
+		{"x", 11},
+		{"", 12},
+	}
+
+	for i, tt := range expected {
+		if tokens[i].Literal != tt.literal || tokens[i].Column != tt.column {
+			t.Errorf("tokens[%d] is wrong. expected = %q at column %d, got %q at column %d", i, tt.literal, tt.column, tokens[i].Literal, tokens[i].Column)
+		}
+	}
+}
+
+func TestTokenOffsetsCountBytes(t *testing.T) {
+	input := "let café = \"☕\";\n!= ..."
+
+	expected := []string{"let", "café", "=", `"☕"`, ";", "!=", "...", ""}
+
+	l := New(input)
+
+	for i, source := range expected {
+		tok := l.NextToken()
+
+		if input[tok.Offset:tok.End] != source {
+			t.Errorf("tests[%d] - wrong source of %s. expected: %q, got: %q (%d:%d)", i, tok, source, input[tok.Offset:tok.End], tok.Offset, tok.End)
+		}
+	}
+
+	// asking for more tokens after the end keeps the offsets inside the input
+	if tok := l.NextToken(); tok.Offset != len(input) || tok.End != len(input) {
+		t.Errorf("EOF after the end is outside the input, got: %d:%d", tok.Offset, tok.End)
+	}
+}
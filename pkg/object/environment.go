@@ -5,21 +5,51 @@
  */
 package object
 
+import (
+	"context"
+
+	"github.com/maxwellgithinji/jaba/pkg/ast"
+)
+
 // Environment is a wrapper of the map implementation that helps associate a string key with an object
 type Environment struct {
 	// store is the hashmap that stores the objects
+	// function environments only create it when the body binds something other than a parameter
 	store map[string]Object
 
+	// names holds the parameter names of a function environment. it is shared between all calls of the same function
+	names []string
+
+	// values holds the arguments of a function environment in the same order as names
+	values []Object
+
 	// outer helps with scoping of the environment.
 	// its helpful when separating program and function variables
 	outer *Environment
+
+	// evaluation is shared by the environment and every environment enclosed in it
+	evaluation *Evaluation
+}
+
+// Evaluation holds the state the evaluator keeps while it runs the program of an environment.
+// Every environment enclosed in the same top level environment shares it,
+// so programs evaluated at the same time in different environments do not affect each other
+type Evaluation struct {
+	// Context stops the evaluation once it is done, the evaluation can not be cancelled when it is nil
+	Context context.Context
+
+	// CallDepth is the number of function calls currently being evaluated
+	CallDepth int
+
+	// Hook is called before every node the evaluation evaluates, nothing is called when it is nil
+	Hook func(node ast.Node, env *Environment)
 }
 
 // NewEnvironment creates a new instance of the environment
 func NewEnvironment() *Environment {
 	s := make(map[string]Object)
 
-	return &Environment{store: s, outer: nil}
+	return &Environment{store: s, outer: nil, evaluation: &Evaluation{}}
 }
 
 // NewEnclosedEnvironment creates a new instance of an scoped environment
@@ -28,13 +58,39 @@ func NewEnclosedEnvironment(outer *Environment) *Environment {
 	env := NewEnvironment()
 
 	env.outer = outer
+	env.evaluation = evaluationOf(outer)
 
 	return env
 }
 
+// NewFunctionEnvironment creates an enclosed environment for a function call.
+// The parameters are kept in slots instead of a hashmap which avoids allocating a map on every call
+func NewFunctionEnvironment(outer *Environment, names []string, values []Object) *Environment {
+	return &Environment{names: names, values: values, outer: outer, evaluation: evaluationOf(outer)}
+}
+
+// evaluationOf returns the evaluation an environment enclosed in outer shares, an environment without outer starts its own
+func evaluationOf(outer *Environment) *Evaluation {
+	if outer == nil {
+		return &Evaluation{}
+	}
+	return outer.evaluation
+}
+
+// Evaluation returns the state of the evaluation the environment belongs to
+func (e *Environment) Evaluation() *Evaluation {
+	return e.evaluation
+}
+
 // Get returns the object associated with the given key from the environment
 // it also checks for values both in the inner and outer scopes
 func (e *Environment) Get(key string) (Object, bool) {
+	for i, name := range e.names {
+		if name == key {
+			return e.values[i], true
+		}
+	}
+
 	obj, ok := e.store[key]
 
 	if !ok && e.outer != nil {
@@ -46,6 +102,107 @@ func (e *Environment) Get(key string) (Object, bool) {
 
 // Set creates an object in the environment hashmap and returns what was created
 func (e *Environment) Set(key string, value Object) Object {
+	for i, name := range e.names {
+		if name == key {
+			e.values[i] = value
+			return value
+		}
+	}
+
+	if e.store == nil {
+		e.store = make(map[string]Object)
+	}
+
 	e.store[key] = value
 	return value
 }
+
+// SetBuiltin binds a host function under name so programs evaluated in the environment can call it like any builtin.
+// It lets a Go program that embeds jaba add its own functions, a function with the name of a global builtin hides it
+func (e *Environment) SetBuiltin(name string, fn BuiltinFunction) *Builtin {
+	builtin := &Builtin{Name: name, Function: fn}
+	e.Set(name, builtin)
+
+	return builtin
+}
+
+// Assign updates an existing binding in the scope it was created in and reports whether the binding was found
+// unlike Set, it never creates a new binding
+func (e *Environment) Assign(key string, value Object) bool {
+	for i, name := range e.names {
+		if name == key {
+			e.values[i] = value
+			return true
+		}
+	}
+
+	if _, ok := e.store[key]; ok {
+		e.store[key] = value
+		return true
+	}
+
+	if e.outer != nil {
+		return e.outer.Assign(key, value)
+	}
+
+	return false
+}
+
+// Delete removes a binding from the innermost scope that has it and reports whether the binding was found
+func (e *Environment) Delete(key string) bool {
+	for i, name := range e.names {
+		if name == key {
+			// names is shared by every call of the function so both slices are copied without the slot
+			names := make([]string, 0, len(e.names)-1)
+			names = append(names, e.names[:i]...)
+			e.names = append(names, e.names[i+1:]...)
+
+			values := make([]Object, 0, len(e.values)-1)
+			values = append(values, e.values[:i]...)
+			e.values = append(values, e.values[i+1:]...)
+
+			return true
+		}
+	}
+
+	if _, ok := e.store[key]; ok {
+		delete(e.store, key)
+		return true
+	}
+
+	if e.outer != nil {
+		return e.outer.Delete(key)
+	}
+
+	return false
+}
+
+// Snapshot returns a shallow copy of the bindings of the environment that Restore can roll back to.
+// The objects themselves and the outer environment are shared, only the bindings of this scope are copied
+func (e *Environment) Snapshot() *Environment {
+	snapshot := &Environment{outer: e.outer, evaluation: e.evaluation}
+	snapshot.copyBindings(e)
+	return snapshot
+}
+
+// Restore replaces the bindings of the environment with the ones saved by Snapshot.
+// The snapshot is copied again so it can be restored more than once
+func (e *Environment) Restore(snapshot *Environment) {
+	e.copyBindings(snapshot)
+	e.outer = snapshot.outer
+}
+
+// copyBindings makes the bindings of e a copy of the bindings of from
+func (e *Environment) copyBindings(from *Environment) {
+	e.store = nil
+	if from.store != nil {
+		e.store = make(map[string]Object, len(from.store))
+		for key, value := range from.store {
+			e.store[key] = value
+		}
+	}
+
+	// names is never changed in place, Delete replaces it, so it can be shared
+	e.names = from.names
+	e.values = append([]Object(nil), from.values...)
+}
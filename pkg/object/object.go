@@ -9,9 +9,12 @@ import (
 	"bytes"
 	"fmt"
 	"hash/fnv"
+	"math/big"
+	"sort"
 	"strings"
 
 	"github.com/maxwellgithinji/jaba/pkg/ast"
+	"github.com/maxwellgithinji/jaba/pkg/code"
 )
 
 // ObjectType represents the category of the object
@@ -19,15 +22,20 @@ type ObjectType string
 
 const (
 	INTEGER_OBJECT      = "INTEGER"
+	BIGINT_OBJECT       = "BIGINT"
 	BOOLEAN_OBJECT      = "BOOLEAN"
 	NULL_OBJECT         = "NULL"
 	RETURN_VALUE_OBJECT = "RETURN_VALUE"
 	ERROR_OBJECT        = "ERROR"
+	CAUGHT_ERROR_OBJECT = "CAUGHT_ERROR"
 	FUNCTION_OBJECT     = "FUNCTION_OBJECT"
 	STRING_OBJECT       = "STRING"
 	BUILTIN_OBJECT      = "BUILTIN"
 	ARRAY_OBJECT        = "ARRAY"
 	HASH_OBJECT         = "HASH"
+	ITERATOR_OBJECT     = "ITERATOR"
+
+	COMPILED_FUNCTION_OBJECT = "COMPILED_FUNCTION"
 )
 
 // Object is an interface that helps represent the values encountered when evaluating the jaba program
@@ -55,6 +63,24 @@ func (i *Integer) Inspect() string {
 	return fmt.Sprintf("%d", i.Value)
 }
 
+// BigInt is a jaba data type that represents integers that do not fit in an Integer.
+// Arithmetic on integers that overflows produces a BigInt and results that fit again are turned back into an Integer,
+// so a BigInt always holds a value outside of the int64 range
+// It fulfills the object interface by implementing the Type() and Inspect() methods
+type BigInt struct {
+	Value *big.Int
+}
+
+// Type returns the type of the object
+func (b *BigInt) Type() ObjectType {
+	return BIGINT_OBJECT
+}
+
+// Inspect returns the string representation of the object value, big integer
+func (b *BigInt) Inspect() string {
+	return b.Value.String()
+}
+
 // Boolean is a jaba data type that represents true or false
 // It fulfills the object interface by implementing the Type() and Inspect() methods
 type Boolean struct {
@@ -103,10 +129,36 @@ func (r *ReturnValue) Inspect() string {
 	return r.Value.Inspect()
 }
 
+// ErrorKind is the category of an error e.g. TypeError, it lets a catch block tell errors apart
+type ErrorKind string
+
+const (
+	// GENERIC_ERROR is the kind of errors that fit no other category, including thrown errors
+	GENERIC_ERROR ErrorKind = "Error"
+
+	// TYPE_ERROR is the kind of errors caused by a value of the wrong type e.g. 1 + true
+	TYPE_ERROR ErrorKind = "TypeError"
+
+	// NAME_ERROR is the kind of errors caused by an identifier that is not bound
+	NAME_ERROR ErrorKind = "NameError"
+
+	// DIVISION_ERROR is the kind of errors caused by dividing by zero
+	DIVISION_ERROR ErrorKind = "DivisionError"
+
+	// ARGUMENT_ERROR is the kind of errors caused by calling a function with the wrong number of arguments
+	ARGUMENT_ERROR ErrorKind = "ArgumentError"
+
+	// VALUE_ERROR is the kind of errors caused by a value of the right type that is out of range e.g. a negative count
+	VALUE_ERROR ErrorKind = "ValueError"
+)
+
 // Error represents internal jaba error
 // it fulfills the Object interface by implementing the Type() and Inspect() methods
 type Error struct {
 	Message string
+
+	// Kind is the category of the error
+	Kind ErrorKind
 }
 
 // Type returns the type of the object, error
@@ -119,17 +171,74 @@ func (e *Error) Inspect() string {
 	return "ERROR: " + e.Message
 }
 
+// CaughtError represents the error a catch block binds to its name
+// it shows as its message and remembers the kind of the error so catch blocks can tell errors apart
+type CaughtError struct {
+	Message string
+
+	// Kind is the category of the error that was caught
+	Kind ErrorKind
+}
+
+// Type returns the type of the object, caught error
+func (c *CaughtError) Type() ObjectType {
+	return CAUGHT_ERROR_OBJECT
+}
+
+// Inspect returns the message of the caught error
+func (c *CaughtError) Inspect() string {
+	return c.Message
+}
+
 // Function represents a jaba function and may include parameters and some statements to be executed
 // it fulfills the Object interface by implementing the Type() and Inspect() methods
 type Function struct {
 	// Parameters is a list of identifiers that should be passed to the function call
 	Parameters []*ast.Identifier
 
+	// Defaults holds the default value of every parameter, see ast.FunctionLiteral
+	Defaults []ast.Expression
+
 	// Body contains a list of function statements to be evaluated
 	Body *ast.BlockStatement
 
 	// Env keeps track of variables during interpreter execution
 	Env *Environment
+
+	// Generator is set when the body yields, see ast.FunctionLiteral
+	Generator bool
+
+	// Compiled is the body turned into closures by the evaluator so a call does not walk the body node by node.
+	// It is nil until the function is first called, the body is evaluated node by node when it is nil
+	Compiled func(env *Environment) Object
+
+	// parameterNames caches the names of the parameters so every call can share them
+	parameterNames []string
+}
+
+// ParameterNames returns the names of the function parameters.
+// The names are computed on the first call and reused by every call after that
+func (f *Function) ParameterNames() []string {
+	if f.parameterNames == nil {
+		f.parameterNames = make([]string, len(f.Parameters))
+		for i, param := range f.Parameters {
+			f.parameterNames[i] = param.Value
+		}
+	}
+
+	return f.parameterNames
+}
+
+// RequiredParameters returns how many parameters a call has to pass, those without a default value
+func (f *Function) RequiredParameters() int {
+	required := 0
+	for i := range f.Parameters {
+		if f.Defaults == nil || f.Defaults[i] == nil {
+			required++
+		}
+	}
+
+	return required
 }
 
 // Type returns the type of the object, function
@@ -157,6 +266,58 @@ func (f *Function) Inspect() string {
 	return out.String()
 }
 
+// CompactInspect returns a single line representation of the function with the body elided e.g. fn(x, y) {...}
+func (f *Function) CompactInspect() string {
+	params := []string{}
+
+	for _, param := range f.Parameters {
+		params = append(params, param.String())
+	}
+
+	return "fn(" + strings.Join(params, ", ") + ") {...}"
+}
+
+// inspectElement returns the representation of an object stored in an array or a hash
+// functions are shown compactly so containers of functions stay on one line.
+// inspecting holds the arrays and hashes being inspected, one met again contains itself and is shown as [...] or {...}
+func inspectElement(obj Object, inspecting map[Object]bool) string {
+	switch obj := obj.(type) {
+	case *Function:
+		return obj.CompactInspect()
+
+	case *Array:
+		return obj.inspect(inspecting)
+
+	case *Hash:
+		return obj.inspect(inspecting)
+	}
+
+	return obj.Inspect()
+}
+
+// CompiledFunction represents a jaba function that has been compiled to bytecode for the virtual machine
+// it fulfills the Object interface by implementing the Type() and Inspect() methods
+type CompiledFunction struct {
+	// Instructions holds the bytecode of the function body
+	Instructions code.Instructions
+
+	// NumLocals is the number of local bindings, including parameters, the function needs on the stack
+	NumLocals int
+
+	// NumParameters is the number of arguments the function expects
+	NumParameters int
+}
+
+// Type returns the type of the object, compiled function
+func (c *CompiledFunction) Type() ObjectType {
+	return COMPILED_FUNCTION_OBJECT
+}
+
+// Inspect returns the string representation of the compiled function
+func (c *CompiledFunction) Inspect() string {
+	return fmt.Sprintf("CompiledFunction[%p]", c)
+}
+
 // String represents a jaba string which is an expression which evaluates to a value
 // it fulfills the Object interface by implementing the Type() and Inspect() methods
 type String struct {
@@ -181,6 +342,12 @@ type BuiltinFunction func(args ...Object) Object
 // Builtin is a wrapper around golang function which is the host language
 // it fulfills the Object interface by implementing the Type() and Inspect() methods
 type Builtin struct {
+	// Name is the name the builtin is registered under, builtins made on the fly e.g. by partial have no name
+	Name string
+
+	// Doc is a short description of what the builtin does, the help builtin shows it
+	Doc string
+
 	Function BuiltinFunction
 }
 
@@ -198,6 +365,9 @@ func (b *Builtin) Inspect() string {
 // it fulfills the Object interface by implementing the Type() and Inspect() methods
 type Array struct {
 	Elements []Object
+
+	// Frozen arrays can not be changed, assigning to one of their elements is an error
+	Frozen bool
 }
 
 // Type returns the type of the object, array
@@ -207,11 +377,22 @@ func (a *Array) Type() ObjectType {
 
 // Inspect returns the string representation of the object value, array
 func (a *Array) Inspect() string {
+	return a.inspect(map[Object]bool{})
+}
+
+// inspect returns the representation of the array, an array that is already being inspected is shown as [...]
+func (a *Array) inspect(inspecting map[Object]bool) string {
+	if inspecting[a] {
+		return "[...]"
+	}
+	inspecting[a] = true
+	defer delete(inspecting, a)
+
 	var out bytes.Buffer
 
 	elements := []string{}
 	for _, element := range a.Elements {
-		elements = append(elements, element.Inspect())
+		elements = append(elements, inspectElement(element, inspecting))
 	}
 
 	out.WriteString("[")
@@ -221,9 +402,51 @@ func (a *Array) Inspect() string {
 	return out.String()
 }
 
+// Iterator represents a jaba iterator which produces its values one at a time instead of holding them all in memory
+// it fulfills the Object interface by implementing the Type() and Inspect() methods
+type Iterator struct {
+	// next produces the next value, the boolean is false once the iterator is exhausted
+	next func() (Object, bool)
+
+	// done is set once next reports that there are no more values
+	done bool
+}
+
+// NewIterator creates an iterator that gets its values from next
+func NewIterator(next func() (Object, bool)) *Iterator {
+	return &Iterator{next: next}
+}
+
+// Next returns the next value of the iterator, the boolean is false when the iterator is exhausted.
+// An exhausted iterator stays exhausted
+func (i *Iterator) Next() (Object, bool) {
+	if i.done {
+		return nil, false
+	}
+
+	value, ok := i.next()
+	if !ok {
+		i.done = true
+		return nil, false
+	}
+
+	return value, true
+}
+
+// Type returns the type of the object, iterator
+func (i *Iterator) Type() ObjectType {
+	return ITERATOR_OBJECT
+}
+
+// Inspect returns the string representation of the object value, iterator
+func (i *Iterator) Inspect() string {
+	return "iterator"
+}
+
 // HashKey represents a a comparison object used in hashing jaba maps(hashes)
 type HashKey struct {
 	// Type returns the type of the key (string, boolean, integer, ...)
+	// keys of different types never collide even when their values do e.g. 1 and "1" or true and 1
 	Type ObjectType
 
 	// Value is hash used to represent the key
@@ -248,6 +471,16 @@ func (i *Integer) HashKey() HashKey {
 	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
 }
 
+// HashKey implements big integer hash function
+func (b *BigInt) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write(b.Value.Bytes())
+	if b.Value.Sign() < 0 {
+		h.Write([]byte{'-'})
+	}
+	return HashKey{Type: b.Type(), Value: h.Sum64()}
+}
+
 // HashKey implements string hash function
 func (s *String) HashKey() HashKey {
 	h := fnv.New64a()
@@ -263,8 +496,93 @@ type HashPair struct {
 
 // Hash represents a jaba hash
 // it fulfills the Object interface by implementing the Type() and Inspect() methods
+// Pairs are looked up in the map and iterated in the order their keys were first set, see OrderedPairs
 type Hash struct {
 	Pairs map[HashKey]HashPair
+
+	// order holds the keys in the order they were first set, it is kept by Set and Delete
+	order []HashKey
+
+	// Frozen hashes can not be changed, assigning to one of their keys is an error
+	Frozen bool
+}
+
+// NewHash returns an empty hash, pairs are added with Set
+func NewHash() *Hash {
+	return &Hash{Pairs: make(map[HashKey]HashPair)}
+}
+
+// Set sets the pair of a key, a new key goes after every key that is already set
+// and an existing key keeps its place
+func (p *Hash) Set(key HashKey, pair HashPair) {
+	if _, ok := p.Pairs[key]; !ok {
+		p.order = append(p.order, key)
+	}
+	p.Pairs[key] = pair
+}
+
+// Delete removes the pair of a key, it reports whether the key was set
+func (p *Hash) Delete(key HashKey) bool {
+	if _, ok := p.Pairs[key]; !ok {
+		return false
+	}
+
+	delete(p.Pairs, key)
+	for i, ordered := range p.order {
+		if ordered == key {
+			p.order = append(p.order[:i], p.order[i+1:]...)
+			break
+		}
+	}
+
+	return true
+}
+
+// OrderedPairs returns the pairs in the order their keys were first set.
+// Pairs that were put in the map without Set have no place in the order, they follow the others sorted by their key
+func (p *Hash) OrderedPairs() []HashPair {
+	pairs := make([]HashPair, 0, len(p.Pairs))
+	seen := make(map[HashKey]bool, len(p.order))
+
+	for _, key := range p.order {
+		if pair, ok := p.Pairs[key]; ok && !seen[key] {
+			pairs = append(pairs, pair)
+			seen[key] = true
+		}
+	}
+
+	if len(pairs) == len(p.Pairs) {
+		return pairs
+	}
+
+	unordered := make([]HashPair, 0, len(p.Pairs)-len(pairs))
+	for key, pair := range p.Pairs {
+		if !seen[key] {
+			unordered = append(unordered, pair)
+		}
+	}
+	sortPairs(unordered)
+
+	return append(pairs, unordered...)
+}
+
+// SortedPairs returns the pairs sorted by the inspected form of their key,
+// it gives the same order for hashes with the same keys no matter the order they were set in
+func (p *Hash) SortedPairs() []HashPair {
+	pairs := make([]HashPair, 0, len(p.Pairs))
+	for _, pair := range p.Pairs {
+		pairs = append(pairs, pair)
+	}
+	sortPairs(pairs)
+
+	return pairs
+}
+
+// sortPairs sorts pairs in place by the inspected form of their key
+func sortPairs(pairs []HashPair) {
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].Key.Inspect() < pairs[j].Key.Inspect()
+	})
 }
 
 // Type returns the type of the object, hash pair
@@ -274,11 +592,22 @@ func (p *Hash) Type() ObjectType {
 
 // Inspect returns the string representation of the object value, hash pair
 func (p *Hash) Inspect() string {
+	return p.inspect(map[Object]bool{})
+}
+
+// inspect returns the representation of the hash, a hash that is already being inspected is shown as {...}
+func (p *Hash) inspect(inspecting map[Object]bool) string {
+	if inspecting[p] {
+		return "{...}"
+	}
+	inspecting[p] = true
+	defer delete(inspecting, p)
+
 	var out bytes.Buffer
 
 	pairs := []string{}
-	for _, pair := range p.Pairs {
-		pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), pair.Value.Inspect()))
+	for _, pair := range p.OrderedPairs() {
+		pairs = append(pairs, fmt.Sprintf("%s: %s", pair.Key.Inspect(), inspectElement(pair.Value, inspecting)))
 	}
 
 	out.WriteString("{")
@@ -288,6 +617,74 @@ func (p *Hash) Inspect() string {
 	return out.String()
 }
 
+// PrettyInspect returns a multi-line representation of the object where arrays and hashes put every element on its own line
+// indented by two spaces per nesting level. Other objects look the same as in containers.
+// Hash pairs keep the order their keys were first set in, like Inspect
+func PrettyInspect(obj Object) string {
+	var out bytes.Buffer
+	prettyInspect(&out, obj, "", map[Object]bool{})
+	return out.String()
+}
+
+// prettyInspect writes the pretty representation of obj to out, indent is the indentation of the line obj starts on.
+// inspecting holds the arrays and hashes being written, one met again contains itself and is written as [...] or {...}
+func prettyInspect(out *bytes.Buffer, obj Object, indent string, inspecting map[Object]bool) {
+	inner := indent + "  "
+
+	switch obj := obj.(type) {
+	case *Array:
+		if len(obj.Elements) == 0 {
+			out.WriteString("[]")
+			return
+		}
+		if inspecting[obj] {
+			out.WriteString("[...]")
+			return
+		}
+		inspecting[obj] = true
+		defer delete(inspecting, obj)
+
+		out.WriteString("[\n")
+		for i, element := range obj.Elements {
+			out.WriteString(inner)
+			prettyInspect(out, element, inner, inspecting)
+			if i < len(obj.Elements)-1 {
+				out.WriteString(",")
+			}
+			out.WriteString("\n")
+		}
+		out.WriteString(indent + "]")
+
+	case *Hash:
+		if len(obj.Pairs) == 0 {
+			out.WriteString("{}")
+			return
+		}
+		if inspecting[obj] {
+			out.WriteString("{...}")
+			return
+		}
+		inspecting[obj] = true
+		defer delete(inspecting, obj)
+
+		pairs := obj.OrderedPairs()
+
+		out.WriteString("{\n")
+		for i, pair := range pairs {
+			out.WriteString(inner + pair.Key.Inspect() + ": ")
+			prettyInspect(out, pair.Value, inner, inspecting)
+			if i < len(pairs)-1 {
+				out.WriteString(",")
+			}
+			out.WriteString("\n")
+		}
+		out.WriteString(indent + "}")
+
+	default:
+		out.WriteString(inspectElement(obj, inspecting))
+	}
+}
+
 // Hashable is an interface that can be used to evaluate if an object can be used as a hash key
 type Hashable interface {
 	HashKey() HashKey
@@ -1,6 +1,11 @@
 package object
 
-import "testing"
+import (
+	"math/big"
+	"testing"
+
+	"github.com/maxwellgithinji/jaba/pkg/ast"
+)
 
 func TestStringHashKeys(t *testing.T) {
 	hello1 := &String{Value: "Hello world"}
@@ -21,3 +26,363 @@ func TestStringHashKeys(t *testing.T) {
 	}
 
 }
+
+func TestMultibyteStringHashKeys(t *testing.T) {
+	tests := []struct {
+		left, right string
+		equal       bool
+	}{
+		{"héllo", "héllo", true},
+		{"日本語", "日本語", true},
+		{"héllo", "hèllo", false},
+		{"héllo", "hello", false},
+		{"🌍", "🌎", false},
+		{"a🌍", "🌍a", false},
+	}
+
+	for _, tt := range tests {
+		left := (&String{Value: tt.left}).HashKey()
+		right := (&String{Value: tt.right}).HashKey()
+
+		if (left == right) != tt.equal {
+			t.Errorf("hash keys of %q and %q are equal: %t, want: %t", tt.left, tt.right, left == right, tt.equal)
+		}
+	}
+
+	// fnv is not seeded so the key of a string is the same in every run
+	if key := (&String{Value: "héllo"}).HashKey(); key.Value != 11772399666002542816 {
+		t.Errorf("hash key of %q is not stable, got: %d", "héllo", key.Value)
+	}
+}
+
+func TestHashKeysOfDifferentTypes(t *testing.T) {
+	keys := []struct {
+		name string
+		key  HashKey
+	}{
+		{"1", (&Integer{Value: 1}).HashKey()},
+		{`"1"`, (&String{Value: "1"}).HashKey()},
+		{"true", (&Boolean{Value: true}).HashKey()},
+		{"0", (&Integer{Value: 0}).HashKey()},
+		{`""`, (&String{Value: ""}).HashKey()},
+		{"false", (&Boolean{Value: false}).HashKey()},
+	}
+
+	for i, left := range keys {
+		for _, right := range keys[i+1:] {
+			if left.key == right.key {
+				t.Errorf("hash keys of %s and %s collide", left.name, right.name)
+			}
+		}
+	}
+
+	// true and 1 hash to the same value, only the type keeps them apart
+	if (&Boolean{Value: true}).HashKey().Value != (&Integer{Value: 1}).HashKey().Value {
+		t.Errorf("the values of the hash keys of true and 1 are expected to be equal")
+	}
+}
+
+func TestBigIntHashKeys(t *testing.T) {
+	big1 := &BigInt{Value: new(big.Int).Lsh(big.NewInt(1), 70)}
+	big2 := &BigInt{Value: new(big.Int).Lsh(big.NewInt(1), 70)}
+	negative := &BigInt{Value: new(big.Int).Neg(big1.Value)}
+
+	if big1.HashKey() != big2.HashKey() {
+		t.Fatalf("big integers with the same value have different hash keys")
+	}
+
+	if big1.HashKey() == negative.HashKey() {
+		t.Fatalf("big integers with opposite signs have the same hash keys")
+	}
+
+	if big1.Inspect() != "1180591620717411303424" {
+		t.Errorf("big1.Inspect() is wrong, got: %s", big1.Inspect())
+	}
+}
+
+func TestFunctionEnvironment(t *testing.T) {
+	outer := NewEnvironment()
+	outer.Set("y", &Integer{Value: 2})
+
+	env := NewFunctionEnvironment(outer, []string{"x"}, []Object{&Integer{Value: 1}})
+
+	x, ok := env.Get("x")
+	if !ok || x.(*Integer).Value != 1 {
+		t.Fatalf("parameter x is not 1, got %v", x)
+	}
+
+	y, ok := env.Get("y")
+	if !ok || y.(*Integer).Value != 2 {
+		t.Fatalf("outer binding y is not 2, got %v", y)
+	}
+
+	env.Set("x", &Integer{Value: 3})
+	env.Set("z", &Integer{Value: 4})
+
+	x, _ = env.Get("x")
+	if x.(*Integer).Value != 3 {
+		t.Fatalf("parameter x was not updated, got %v", x)
+	}
+
+	if _, ok := outer.Get("z"); ok {
+		t.Fatalf("binding z leaked into the outer environment")
+	}
+
+	z, ok := env.Get("z")
+	if !ok || z.(*Integer).Value != 4 {
+		t.Fatalf("binding z is not 4, got %v", z)
+	}
+}
+
+func TestFunctionParameterNamesAreCached(t *testing.T) {
+	fn := &Function{Parameters: []*ast.Identifier{{Value: "a"}, {Value: "b"}}}
+
+	first := fn.ParameterNames()
+	second := fn.ParameterNames()
+
+	if len(first) != 2 || first[0] != "a" || first[1] != "b" {
+		t.Fatalf("parameter names are not [a b], got %v", first)
+	}
+
+	if &first[0] != &second[0] {
+		t.Fatalf("parameter names were recomputed")
+	}
+}
+
+func TestContainersInspectFunctionsCompactly(t *testing.T) {
+	add := &Function{
+		Parameters: []*ast.Identifier{{Value: "x"}, {Value: "y"}},
+		Body:       &ast.BlockStatement{},
+	}
+	identity := &Function{
+		Parameters: []*ast.Identifier{{Value: "x"}},
+		Body:       &ast.BlockStatement{},
+	}
+
+	array := &Array{Elements: []Object{add, identity}}
+
+	if array.Inspect() != "[fn(x, y) {...}, fn(x) {...}]" {
+		t.Errorf("array of functions is not compact, got: %q", array.Inspect())
+	}
+
+	key := &String{Value: "add"}
+	hash := &Hash{Pairs: map[HashKey]HashPair{key.HashKey(): {Key: key, Value: add}}}
+
+	if hash.Inspect() != "{add: fn(x, y) {...}}" {
+		t.Errorf("hash of functions is not compact, got: %q", hash.Inspect())
+	}
+}
+
+func TestContainersThatHoldThemselves(t *testing.T) {
+	array := &Array{Elements: []Object{&Integer{Value: 1}, nil}}
+	array.Elements[1] = array
+
+	key := &String{Value: "self"}
+	hash := NewHash()
+	hash.Set(key.HashKey(), HashPair{Key: key, Value: hash})
+
+	// the same array twice is not a cycle and is shown in full both times
+	shared := &Array{Elements: []Object{&Integer{Value: 2}}}
+	twice := &Array{Elements: []Object{shared, shared}}
+
+	tests := []struct {
+		obj            Object
+		expected       string
+		expectedPretty string
+	}{
+		{array, "[1, [...]]", "[\n  1,\n  [...]\n]"},
+		{hash, "{self: {...}}", "{\n  self: {...}\n}"},
+		{&Array{Elements: []Object{hash}}, "[{self: {...}}]", "[\n  {\n    self: {...}\n  }\n]"},
+		{twice, "[[2], [2]]", "[\n  [\n    2\n  ],\n  [\n    2\n  ]\n]"},
+	}
+
+	for _, tt := range tests {
+		if tt.obj.Inspect() != tt.expected {
+			t.Errorf("wrong Inspect. expected: %q, got: %q", tt.expected, tt.obj.Inspect())
+		}
+
+		if PrettyInspect(tt.obj) != tt.expectedPretty {
+			t.Errorf("wrong PrettyInspect. expected: %q, got: %q", tt.expectedPretty, PrettyInspect(tt.obj))
+		}
+	}
+}
+
+func TestEnvironmentDelete(t *testing.T) {
+	outer := NewEnvironment()
+	outer.Set("x", &Integer{Value: 1})
+	outer.Set("y", &Integer{Value: 2})
+
+	inner := NewEnclosedEnvironment(outer)
+	inner.Set("x", &Integer{Value: 3})
+
+	if !inner.Delete("x") {
+		t.Fatalf("x was not deleted from the inner environment")
+	}
+
+	x, ok := inner.Get("x")
+	if !ok || x.(*Integer).Value != 1 {
+		t.Fatalf("deleting the inner x should reveal the outer x, got %v", x)
+	}
+
+	if !inner.Delete("y") {
+		t.Fatalf("y was not deleted from the outer environment")
+	}
+
+	if _, ok := outer.Get("y"); ok {
+		t.Fatalf("y is still bound in the outer environment")
+	}
+
+	if inner.Delete("missing") {
+		t.Fatalf("deleting a missing binding reported success")
+	}
+}
+
+func TestFunctionEnvironmentDelete(t *testing.T) {
+	names := []string{"a", "b"}
+
+	first := NewFunctionEnvironment(nil, names, []Object{&Integer{Value: 1}, &Integer{Value: 2}})
+	second := NewFunctionEnvironment(nil, names, []Object{&Integer{Value: 3}, &Integer{Value: 4}})
+
+	if !first.Delete("a") {
+		t.Fatalf("parameter a was not deleted")
+	}
+
+	if _, ok := first.Get("a"); ok {
+		t.Fatalf("parameter a is still bound")
+	}
+
+	b, ok := first.Get("b")
+	if !ok || b.(*Integer).Value != 2 {
+		t.Fatalf("parameter b is not 2, got %v", b)
+	}
+
+	a, ok := second.Get("a")
+	if !ok || a.(*Integer).Value != 3 {
+		t.Fatalf("deleting a parameter changed another call, got %v", a)
+	}
+
+	if first.Delete("a") {
+		t.Fatalf("deleting a parameter twice reported success")
+	}
+}
+
+func TestEnvironmentSnapshotRestore(t *testing.T) {
+	outer := NewEnvironment()
+	outer.Set("o", &Integer{Value: 0})
+
+	env := NewEnclosedEnvironment(outer)
+	env.Set("x", &Integer{Value: 1})
+
+	snapshot := env.Snapshot()
+
+	env.Set("x", &Integer{Value: 2})
+	env.Set("y", &Integer{Value: 3})
+
+	env.Restore(snapshot)
+
+	x, ok := env.Get("x")
+	if !ok || x.(*Integer).Value != 1 {
+		t.Fatalf("x is not restored to 1, got %v", x)
+	}
+
+	if _, ok := env.Get("y"); ok {
+		t.Fatalf("y is still bound after restoring")
+	}
+
+	if _, ok := env.Get("o"); !ok {
+		t.Fatalf("the outer environment is not reachable after restoring")
+	}
+
+	// the snapshot is unaffected by changes made after restoring so it can be restored again
+	env.Set("x", &Integer{Value: 4})
+	env.Delete("x")
+	env.Restore(snapshot)
+
+	x, ok = env.Get("x")
+	if !ok || x.(*Integer).Value != 1 {
+		t.Fatalf("x is not restored to 1 a second time, got %v", x)
+	}
+}
+
+func TestFunctionEnvironmentSnapshotRestore(t *testing.T) {
+	env := NewFunctionEnvironment(nil, []string{"a", "b"}, []Object{&Integer{Value: 1}, &Integer{Value: 2}})
+
+	snapshot := env.Snapshot()
+
+	env.Set("a", &Integer{Value: 10})
+	env.Delete("b")
+	env.Set("c", &Integer{Value: 3})
+
+	env.Restore(snapshot)
+
+	a, ok := env.Get("a")
+	if !ok || a.(*Integer).Value != 1 {
+		t.Fatalf("parameter a is not restored to 1, got %v", a)
+	}
+
+	b, ok := env.Get("b")
+	if !ok || b.(*Integer).Value != 2 {
+		t.Fatalf("parameter b is not restored to 2, got %v", b)
+	}
+
+	if _, ok := env.Get("c"); ok {
+		t.Fatalf("c is still bound after restoring")
+	}
+}
+
+func TestHashOrderedPairs(t *testing.T) {
+	hash := NewHash()
+
+	for _, name := range []string{"c", "a", "b"} {
+		key := &String{Value: name}
+		hash.Set(key.HashKey(), HashPair{Key: key, Value: &Integer{Value: 1}})
+	}
+
+	a := &String{Value: "a"}
+	hash.Set(a.HashKey(), HashPair{Key: a, Value: &Integer{Value: 2}})
+
+	if hash.Inspect() != "{c: 1, a: 2, b: 1}" {
+		t.Fatalf("pairs are not in insertion order, got: %s", hash.Inspect())
+	}
+
+	if !hash.Delete(a.HashKey()) || hash.Delete(a.HashKey()) {
+		t.Fatalf("a is not deleted exactly once")
+	}
+	hash.Set(a.HashKey(), HashPair{Key: a, Value: &Integer{Value: 3}})
+
+	if hash.Inspect() != "{c: 1, b: 1, a: 3}" {
+		t.Fatalf("a key set again after delete is not last, got: %s", hash.Inspect())
+	}
+
+	// pairs put straight into the map follow the ordered ones sorted by key
+	for _, name := range []string{"z", "y"} {
+		key := &String{Value: name}
+		hash.Pairs[key.HashKey()] = HashPair{Key: key, Value: &Integer{Value: 0}}
+	}
+
+	if hash.Inspect() != "{c: 1, b: 1, a: 3, y: 0, z: 0}" {
+		t.Fatalf("pairs without an order are not sorted after the others, got: %s", hash.Inspect())
+	}
+}
+
+func TestHashSortedPairs(t *testing.T) {
+	b, a := &String{Value: "b"}, &String{Value: "a"}
+
+	hash := NewHash()
+	hash.Set(b.HashKey(), HashPair{Key: b, Value: &Integer{Value: 1}})
+	hash.Set(a.HashKey(), HashPair{Key: a, Value: &Integer{Value: 2}})
+
+	pairs := hash.SortedPairs()
+	if len(pairs) != 2 || pairs[0].Key != a || pairs[1].Key != b {
+		t.Fatalf("pairs are not sorted by key, got: %v", pairs)
+	}
+
+	// sorting does not change the insertion order, Inspect and PrettyInspect both follow it
+	if hash.Inspect() != "{b: 1, a: 2}" {
+		t.Fatalf("hash is not inspected in insertion order, got: %s", hash.Inspect())
+	}
+
+	if PrettyInspect(hash) != "{\n  b: 1,\n  a: 2\n}" {
+		t.Fatalf("pretty hash is not in insertion order, got: %q", PrettyInspect(hash))
+	}
+}
@@ -0,0 +1,249 @@
+/*
+* Package optimizer rewrites the abstract syntax tree before it is evaluated.
+* The rewrites do not change the meaning of the program, they only simplify the tree
+* so the evaluator has less work to do.
+*
+* Example:
+* user input:
+* let x = 2 + 3 * 4;
+* output
+* let x = 14;
+ */
+package optimizer
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/maxwellgithinji/jaba/pkg/ast"
+	"github.com/maxwellgithinji/jaba/pkg/token"
+)
+
+// Fold walks the AST and replaces prefix and infix expressions whose operands are integer or boolean literals
+// with the literal they evaluate to. Identifiers, calls and anything else that could have side effects are left untouched.
+// Container nodes are rewritten in place and the (possibly replaced) node is returned.
+func Fold(node ast.Node) ast.Node {
+	switch node := node.(type) {
+	// Statements
+	case *ast.Program:
+		for i, statement := range node.Statements {
+			node.Statements[i] = Fold(statement).(ast.Statement)
+		}
+
+	case *ast.BlockStatement:
+		for i, statement := range node.Statements {
+			node.Statements[i] = Fold(statement).(ast.Statement)
+		}
+
+	case *ast.ExpressionStatement:
+		node.Value = foldExpression(node.Value)
+
+	case *ast.LetStatement:
+		node.Value = foldExpression(node.Value)
+
+	case *ast.DestructuringLetStatement:
+		node.Value = foldExpression(node.Value)
+
+	case *ast.ReturnStatement:
+		node.Value = foldExpression(node.Value)
+
+	case *ast.YieldStatement:
+		node.Value = foldExpression(node.Value)
+
+	case *ast.ThrowStatement:
+		node.Value = foldExpression(node.Value)
+
+	// Expressions
+	case *ast.PrefixExpression:
+		node.Right = foldExpression(node.Right)
+		return foldPrefixExpression(node)
+
+	case *ast.InfixExpression:
+		node.Left = foldExpression(node.Left)
+		node.Right = foldExpression(node.Right)
+		return foldInfixExpression(node)
+
+	case *ast.IfExpression:
+		node.Condition = foldExpression(node.Condition)
+		Fold(node.Consequence)
+		if node.Alternative != nil {
+			Fold(node.Alternative)
+		}
+		if node.ElseIf != nil {
+			Fold(node.ElseIf)
+		}
+
+	case *ast.DoWhileExpression:
+		Fold(node.Body)
+		node.Condition = foldExpression(node.Condition)
+
+	case *ast.ForInExpression:
+		node.Iterable = foldExpression(node.Iterable)
+		Fold(node.Body)
+
+	case *ast.CollectExpression:
+		node.Loop = foldExpression(node.Loop)
+
+	case *ast.TryExpression:
+		Fold(node.Block)
+		Fold(node.Catch)
+
+	case *ast.FunctionLiteral:
+		foldExpressions(node.Defaults)
+		Fold(node.Body)
+
+	case *ast.CallExpression:
+		node.Function = foldExpression(node.Function)
+		foldExpressions(node.Arguments)
+
+	case *ast.ArrayLiteral:
+		foldExpressions(node.Elements)
+
+	case *ast.SpreadExpression:
+		node.Value = foldExpression(node.Value)
+
+	case *ast.IndexExpression:
+		node.Left = foldExpression(node.Left)
+		node.Index = foldExpression(node.Index)
+
+	case *ast.AssignExpression:
+		node.Target = foldExpression(node.Target)
+		node.Value = foldExpression(node.Value)
+
+	case *ast.HashLiteral:
+		pairs := make(map[ast.Expression]ast.Expression, len(node.Pairs))
+		for i, key := range node.Keys {
+			folded := foldExpression(key)
+			pairs[folded] = foldExpression(node.Pairs[key])
+			node.Keys[i] = folded
+		}
+		node.Pairs = pairs
+	}
+
+	return node
+}
+
+// foldExpression is a helper function that folds an expression and keeps the expression type
+func foldExpression(expression ast.Expression) ast.Expression {
+	if expression == nil {
+		return nil
+	}
+
+	return Fold(expression).(ast.Expression)
+}
+
+// foldExpressions folds every expression in the list in place
+func foldExpressions(expressions []ast.Expression) {
+	for i, expression := range expressions {
+		expressions[i] = foldExpression(expression)
+	}
+}
+
+// foldPrefixExpression folds a prefix expression whose operand is a literal e.g. -5 or !true
+func foldPrefixExpression(node *ast.PrefixExpression) ast.Expression {
+	switch right := node.Right.(type) {
+	case *ast.IntegerLiteral:
+		switch node.Operator {
+		case "-":
+			if right.Value == math.MinInt64 {
+				return node
+			}
+			return newIntegerLiteral(-right.Value)
+
+		case "!":
+			// integers are truthy in jaba
+			return newBoolean(false)
+		}
+
+	case *ast.Boolean:
+		if node.Operator == "!" {
+			return newBoolean(!right.Value)
+		}
+	}
+
+	return node
+}
+
+// foldInfixExpression folds an infix expression whose operands are both integer or both boolean literals
+func foldInfixExpression(node *ast.InfixExpression) ast.Expression {
+	left, leftOk := node.Left.(*ast.IntegerLiteral)
+	right, rightOk := node.Right.(*ast.IntegerLiteral)
+	if leftOk && rightOk {
+		return foldIntegerInfixExpression(node, left.Value, right.Value)
+	}
+
+	leftBoolean, leftOk := node.Left.(*ast.Boolean)
+	rightBoolean, rightOk := node.Right.(*ast.Boolean)
+	if leftOk && rightOk {
+		switch node.Operator {
+		case "==":
+			return newBoolean(leftBoolean.Value == rightBoolean.Value)
+
+		case "!=":
+			return newBoolean(leftBoolean.Value != rightBoolean.Value)
+		}
+	}
+
+	return node
+}
+
+// foldIntegerInfixExpression computes the value of an integer infix expression
+// division by zero and overflow are left for the evaluator to report
+func foldIntegerInfixExpression(node *ast.InfixExpression, left, right int64) ast.Expression {
+	switch node.Operator {
+	case "+":
+		sum := left + right
+		if (sum > left) != (right > 0) {
+			return node
+		}
+		return newIntegerLiteral(sum)
+
+	case "-":
+		difference := left - right
+		if (difference < left) != (right > 0) {
+			return node
+		}
+		return newIntegerLiteral(difference)
+
+	case "*":
+		product := left * right
+		if left != 0 && (product/left != right || (left == -1 && right == math.MinInt64)) {
+			return node
+		}
+		return newIntegerLiteral(product)
+
+	case "/":
+		if right == 0 || (left == math.MinInt64 && right == -1) {
+			return node
+		}
+		return newIntegerLiteral(left / right)
+
+	case "<":
+		return newBoolean(left < right)
+
+	case ">":
+		return newBoolean(left > right)
+
+	case "==":
+		return newBoolean(left == right)
+
+	case "!=":
+		return newBoolean(left != right)
+	}
+
+	return node
+}
+
+// newIntegerLiteral returns an integer literal node for a folded value
+func newIntegerLiteral(value int64) *ast.IntegerLiteral {
+	literal := strconv.FormatInt(value, 10)
+	return &ast.IntegerLiteral{Token: token.Token{Type: token.INTEGER, Literal: literal}, Value: value}
+}
+
+// newBoolean returns a boolean node for a folded value
+func newBoolean(value bool) *ast.Boolean {
+	if value {
+		return &ast.Boolean{Token: token.Token{Type: token.TRUE, Literal: "true"}, Value: true}
+	}
+	return &ast.Boolean{Token: token.Token{Type: token.FALSE, Literal: "false"}, Value: false}
+}
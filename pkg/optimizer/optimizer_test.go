@@ -0,0 +1,84 @@
+package optimizer
+
+import (
+	"testing"
+
+	"github.com/maxwellgithinji/jaba/pkg/ast"
+	"github.com/maxwellgithinji/jaba/pkg/lexer"
+	"github.com/maxwellgithinji/jaba/pkg/parser"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser has %d errors: %v", len(p.Errors()), p.Errors())
+	}
+
+	return program
+}
+
+func TestFold(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"2 + 3 * 4", "14"},
+		{"(2 + 3) * 4", "20"},
+		{"10 / 2 - 1", "4"},
+		{"-(2 + 3)", "-5"},
+		{"!true", "false"},
+		{"!!false", "false"},
+		{"!5", "false"},
+		{"1 < 2", "true"},
+		{"1 > 2 == false", "true"},
+		{"true != false", "true"},
+		{"let x = 2 + 3 * 4;", "let x = 14;"},
+		{"return 1 + 1;", "return 2;"},
+		{"x + 2 * 3", "(x + 6)"},
+		{"1 + 2 + x", "(3 + x)"},
+		{"x + 1 + 2", "((x + 1) + 2)"},
+		{"-x", "(-x)"},
+		{"add(1 + 2, y)", "add(3, y)"},
+		{"[1 + 1, 2 * 2][0 + 1]", "([2, 4][1])"},
+		{"if (1 < 2) { 3 * 3 } else { x }", "iftrue 9else x"},
+		{"fn(x) { x * (2 + 2) }", "fn(x) (x * 4)"},
+		{"5 / 0", "(5 / 0)"},
+		{"9223372036854775807 + 1", "(9223372036854775807 + 1)"},
+		{"9223372036854775807 - 1", "9223372036854775806"},
+		{"-9223372036854775807 - 2", "(-9223372036854775807 - 2)"},
+		{"4611686018427387904 * 2", "(4611686018427387904 * 2)"},
+		{"-(-9223372036854775807 - 1)", "(--9223372036854775808)"},
+		{`"a" + "b"`, "(a + b)"},
+	}
+
+	for _, tt := range tests {
+		program := parse(t, tt.input)
+
+		folded := Fold(program)
+
+		if folded.String() != tt.expected {
+			t.Errorf("input %q: expected %q, got %q", tt.input, tt.expected, folded.String())
+		}
+	}
+}
+
+func TestFoldHashLiteral(t *testing.T) {
+	program := parse(t, `{1 + 1: 2 * 3}`)
+
+	folded := Fold(program).(*ast.Program)
+
+	hash := folded.Statements[0].(*ast.ExpressionStatement).Value.(*ast.HashLiteral)
+
+	for key, value := range hash.Pairs {
+		if key.String() != "2" {
+			t.Errorf("key is not 2, got %s", key.String())
+		}
+
+		if value.String() != "6" {
+			t.Errorf("value is not 6, got %s", value.String())
+		}
+	}
+}
@@ -25,20 +25,54 @@ type Parser struct {
 	peekToken token.Token
 
 	// errors holds a list of errors that occur when parsing
-	errors []string
+	errors []Error
 
 	// prefixParseFns holds a map of prefix functions
 	prefixParseFns map[token.TokenType]prefixParseFn
 
 	// infixParseFns holds a map of infix functions
 	infixParseFns map[token.TokenType]infixParseFn
+
+	// depth is the number of expressions currently being parsed inside each other
+	depth int
+
+	// tooDeep is set once the nesting limit is reached, the rest of the input is skipped after that
+	tooDeep bool
+
+	// yields is set when a yield statement is parsed, it tells the enclosing function literal that it is a generator
+	yields bool
+
+	// spans maps the statements, blocks and expressions that were parsed to the part of the source they were parsed from
+	spans map[ast.Node]ast.Span
+}
+
+// Error is a parser error along with the position of the token it was found at
+type Error struct {
+	// Message describes what is wrong with the input
+	Message string
+
+	// Line is the line of the token the error was found at, counting from 1
+	Line int
+
+	// Column is the column of the token the error was found at, counting from 1
+	Column int
+}
+
+// maxNestingDepth is the number of expressions that may be nested inside each other
+var maxNestingDepth = 1000
+
+// SetMaxNestingDepth changes the number of expressions that may be nested inside each other.
+// Deeper input is reported as an error instead of growing the stack without a bound
+func SetMaxNestingDepth(depth int) {
+	maxNestingDepth = depth
 }
 
 // New returns a new Parser. it also reads 2 tokens to initialize the current and peek tokens
 func New(l *lexer.Lexer) *Parser {
 	p := &Parser{
 		l:      l,
-		errors: []string{},
+		errors: []Error{},
+		spans:  make(map[ast.Node]ast.Span),
 	}
 
 	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
@@ -50,6 +84,10 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.FALSE, p.parseBoolean)
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
 	p.registerPrefix(token.IF, p.parseIfExpression)
+	p.registerPrefix(token.DO, p.parseDoWhileExpression)
+	p.registerPrefix(token.FOR, p.parseForInExpression)
+	p.registerPrefix(token.TRY, p.parseTryExpression)
+	p.registerPrefix(token.COLLECT, p.parseCollectExpression)
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
 	p.registerPrefix(token.STRING, p.parseStringLiteral)
 	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
@@ -58,6 +96,8 @@ func New(l *lexer.Lexer) *Parser {
 	p.infixParseFns = make(map[token.TokenType]infixParseFn)
 	p.registerInfix(token.EQ, p.parseInfixExpression)
 	p.registerInfix(token.NEQ, p.parseInfixExpression)
+	p.registerInfix(token.AND, p.parseInfixExpression)
+	p.registerInfix(token.OR, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
 	p.registerInfix(token.PLUS, p.parseInfixExpression)
@@ -66,6 +106,7 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.ASTERISK, p.parseInfixExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
 	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
+	p.registerInfix(token.ASSIGN, p.parseAssignExpression)
 
 	p.nextToken()
 	p.nextToken()
@@ -89,42 +130,148 @@ func (p *Parser) ParseProgram() *ast.Program {
 	program.Statements = []ast.Statement{}
 
 	for p.currentToken.Type != token.EOF {
+		errorCount := len(p.errors)
 		statement := p.parseStatement()
 
 		if statement != nil {
 			program.Statements = append(program.Statements, statement)
 		}
+
+		if len(p.errors) > errorCount {
+			p.synchronize()
+		}
 		p.nextToken()
 	}
 
 	return program
 }
 
+// synchronize skips the rest of a statement that failed to parse so the parser can resume at the next one.
+// It stops on the semicolon that ends the statement or on a closing brace that ends the enclosing block,
+// braces opened after the error are skipped as a whole so their contents are not parsed as new statements
+func (p *Parser) synchronize() {
+	depth := 0
+
+	for !p.currentTokenIS(token.EOF) {
+		switch p.currentToken.Type {
+		case token.LBRACE:
+			depth++
+		case token.RBRACE:
+			if depth == 0 {
+				return
+			}
+			depth--
+		case token.SEMICOLON:
+			if depth == 0 {
+				return
+			}
+		}
+
+		p.nextToken()
+	}
+}
+
 // parseStatement parses a statement and returns its AST representation
 func (p *Parser) parseStatement() ast.Statement {
+	start := p.currentToken
+
+	var statement ast.Statement
 	switch p.currentToken.Type {
 	case token.LET:
-		return p.parseLetStatement()
+		statement = p.parseLetStatement()
 	case token.RETURN:
-		return p.parseReturnStatement()
+		statement = p.parseReturnStatement()
+	case token.YIELD:
+		statement = p.parseYieldStatement()
+	case token.THROW:
+		statement = p.parseThrowStatement()
 	default:
-		return p.parseExpressionStatement()
+		statement = p.parseExpressionStatement()
+	}
+
+	p.recordSpan(statement, start)
+
+	return statement
+}
+
+// Span returns the part of the source a statement, block or expression of the parsed program was parsed from
+// it reports false for nodes the parser did not create
+func (p *Parser) Span(node ast.Node) (ast.Span, bool) {
+	span, ok := p.spans[node]
+	return span, ok
+}
+
+// recordSpan records that node was parsed from the start token up to and including the current token
+// a node keeps the first span recorded for it, which is its own source without e.g. the parentheses around it
+func (p *Parser) recordSpan(node ast.Node, start token.Token) {
+	if node == nil {
+		return
 	}
+
+	if _, ok := p.spans[node]; ok {
+		return
+	}
+
+	p.spans[node] = ast.Span{Start: start.Offset, End: p.currentToken.End}
 }
 
 // parseLetStatement creates an AST representation of a let statement
-func (p *Parser) parseLetStatement() *ast.LetStatement {
-	statement := &ast.LetStatement{Token: p.currentToken}
+// a comma after the first name makes it a destructuring let statement e.g. let a, b = [1, 2];
+func (p *Parser) parseLetStatement() ast.Statement {
+	letToken := p.currentToken
 
 	if !p.expectPeek(token.IDENTIFIER) {
 		return nil
 	}
 
-	statement.Name = &ast.Identifier{
+	name := &ast.Identifier{
 		Token: p.currentToken,
 		Value: p.currentToken.Literal,
 	}
 
+	if p.peekTokenIs(token.COMMA) {
+		return p.parseDestructuringLetStatement(letToken, name)
+	}
+
+	statement := &ast.LetStatement{Token: letToken, Name: name}
+
+	if !p.expectPeek(token.ASSIGN) {
+		return nil
+	}
+
+	p.nextToken()
+	statement.Value = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return statement
+}
+
+// parseDestructuringLetStatement creates an AST representation of a let statement that binds several names
+// the first name has already been parsed by parseLetStatement
+func (p *Parser) parseDestructuringLetStatement(letToken token.Token, first *ast.Identifier) ast.Statement {
+	statement := &ast.DestructuringLetStatement{Token: letToken, Names: []*ast.Identifier{first}}
+	bound := map[string]bool{first.Value: true}
+
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+
+		if !p.expectPeek(token.IDENTIFIER) {
+			return nil
+		}
+
+		// only one of the values could end up in a name that is bound twice
+		if bound[p.currentToken.Literal] {
+			p.addError(p.currentToken, fmt.Sprintf("name %s is bound more than once", p.currentToken.Literal))
+			return nil
+		}
+		bound[p.currentToken.Literal] = true
+
+		statement.Names = append(statement.Names, &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal})
+	}
+
 	if !p.expectPeek(token.ASSIGN) {
 		return nil
 	}
@@ -160,15 +307,35 @@ func (p *Parser) peekTokenIs(tokenType token.TokenType) bool {
 	return p.peekToken.Type == tokenType
 }
 
-// Errors returns a slice containing all the errors
+// Errors returns a slice containing the messages of all the errors
 func (p *Parser) Errors() []string {
+	messages := make([]string, len(p.errors))
+	for i, err := range p.errors {
+		messages[i] = err.Message
+	}
+
+	return messages
+}
+
+// PositionedErrors returns a slice containing all the errors along with where they were found
+func (p *Parser) PositionedErrors() []Error {
 	return p.errors
 }
 
+// addError appends an error found at the given token to errors
+func (p *Parser) addError(at token.Token, message string) {
+	p.errors = append(p.errors, Error{Message: message, Line: at.Line, Column: at.Column})
+}
+
 // peekError appends error message to errors when it encounters a peek token that does not match the given type
 func (p *Parser) peekError(tokenType token.TokenType) {
-	message := fmt.Sprintf("expected next token to be %v, got %v", tokenType, p.peekToken.Type)
-	p.errors = append(p.errors, message)
+	// the expressions that were still open when the nesting limit was reached all end early, their errors are noise
+	if p.tooDeep {
+		return
+	}
+
+	message := fmt.Sprintf("expected next token to be %s, got %s", tokenType, p.peekToken)
+	p.addError(p.peekToken, message)
 }
 
 // parseReturnStatement creates the AST representation of a return statement
@@ -186,6 +353,37 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	return statement
 }
 
+// parseYieldStatement creates an AST representation of a yield statement e.g. yield i;
+func (p *Parser) parseYieldStatement() *ast.YieldStatement {
+	statement := &ast.YieldStatement{Token: p.currentToken}
+	p.yields = true
+
+	p.nextToken()
+
+	statement.Value = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return statement
+}
+
+// parseThrowStatement creates an AST representation of a throw statement e.g. throw "not found";
+func (p *Parser) parseThrowStatement() *ast.ThrowStatement {
+	statement := &ast.ThrowStatement{Token: p.currentToken}
+
+	p.nextToken()
+
+	statement.Value = p.parseExpression(LOWEST)
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return statement
+}
+
 type (
 	// prefixParseFn  parses tokens that are in a prefix position
 	prefixParseFn func() ast.Expression
@@ -203,29 +401,41 @@ const (
 	// LOWEST has the value 1
 	LOWEST
 
-	// EQUALS has the value 2 (==)
+	// ASSIGN has the value 2 (x = y)
+	ASSIGN
+
+	// LOGICALOR has the value 3 (||)
+	LOGICALOR
+
+	// LOGICALAND has the value 4 (&&)
+	LOGICALAND
+
+	// EQUALS has the value 5 (==)
 	EQUALS
 
-	// LESSGREATER has the value 3 (< OR >)
+	// LESSGREATER has the value 6 (< OR >)
 	LESSGREATER
 
-	// SUM has the value 4 (+)
+	// SUM has the value 7 (+)
 	SUM
-	// PRODUCT has the value 5 (*)
+	// PRODUCT has the value 8 (*)
 	PRODUCT
 
-	// PREFIX has the value 6 (-x or !x)
+	// PREFIX has the value 9 (-x or !x)
 	PREFIX
 
-	// CALL has the value 7. add(x, y)
+	// CALL has the value 10. add(x, y)
 	CALL
 
-	// INDEX has the value 8. array[index]
+	// INDEX has the value 11. array[index]
 	INDEX
 )
 
 // precedences is a hashmap containing infix operator tokens mapped to respective precedence values
 var precedences = map[token.TokenType]int{
+	token.ASSIGN:   ASSIGN,
+	token.OR:       LOGICALOR,
+	token.AND:      LOGICALAND,
 	token.EQ:       EQUALS,
 	token.NEQ:      EQUALS,
 	token.LT:       LESSGREATER,
@@ -266,11 +476,30 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	return statement
 }
 
+// nestedTooDeeply reports that the nesting limit is reached.
+// there is no sensible place to resume inside the nesting so the rest of the input is skipped
+func (p *Parser) nestedTooDeeply() {
+	p.addError(p.currentToken, fmt.Sprintf("expression is nested too deeply, the maximum depth is %d", maxNestingDepth))
+	p.tooDeep = true
+
+	for !p.currentTokenIS(token.EOF) {
+		p.nextToken()
+	}
+}
+
 // parseExpression is a helper function to parse supported expressions
 func (p *Parser) parseExpression(precedence int) ast.Expression {
 	// Uncomment to visualizes parseExpression
 	// defer untrace(trace("parseExpression"))
 
+	p.depth++
+	defer func() { p.depth-- }()
+
+	if p.depth > maxNestingDepth {
+		p.nestedTooDeeply()
+		return nil
+	}
+
 	prefix := p.prefixParseFns[p.currentToken.Type]
 
 	if prefix == nil {
@@ -278,7 +507,10 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 		return nil
 	}
 
+	start := p.currentToken
+
 	leftExpression := prefix()
+	p.recordSpan(leftExpression, start)
 
 	// the loop helps the parser find the whole expression
 	for !p.peekTokenIs(token.SEMICOLON) && precedence < p.peekPrecedence() {
@@ -292,6 +524,7 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 		p.nextToken()
 
 		leftExpression = infix(leftExpression)
+		p.recordSpan(leftExpression, start)
 	}
 
 	return leftExpression
@@ -299,8 +532,12 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 
 // noPrefixParseError returns a formatted error when parser encounters no prefix
 func (p *Parser) noPrefixParseError(tokenType token.TokenType) {
+	if p.tooDeep {
+		return
+	}
+
 	message := fmt.Sprintf("no prefix parse function for %s found", tokenType)
-	p.errors = append(p.errors, message)
+	p.addError(p.currentToken, message)
 }
 
 // parseIdentifier returns a representation of an identifier  which contains the token as sIDENTIFIER and the value
@@ -319,7 +556,7 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	value, err := strconv.ParseInt(p.currentToken.Literal, 10, 64)
 	if err != nil {
 		message := fmt.Sprintf("could not parse %q as integer", p.currentToken.Literal)
-		p.errors = append(p.errors, message)
+		p.addError(p.currentToken, message)
 		return nil
 	}
 
@@ -435,6 +672,28 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	if p.peekTokenIs(token.ELSE) {
 		p.nextToken()
 
+		// else if chains the next if expression without wrapping it in a block
+		if p.peekTokenIs(token.IF) {
+			p.nextToken()
+
+			// the chained if is parsed by calling parseIfExpression again, not parseExpression, so it counts its own level of nesting
+			p.depth++
+			defer func() { p.depth-- }()
+
+			if p.depth > maxNestingDepth {
+				p.nestedTooDeeply()
+				return nil
+			}
+
+			elseIf, ok := p.parseIfExpression().(*ast.IfExpression)
+			if !ok {
+				return nil
+			}
+
+			expression.ElseIf = elseIf
+			return expression
+		}
+
 		if !p.expectPeek(token.LBRACE) {
 			return nil
 		}
@@ -445,6 +704,133 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	return expression
 }
 
+// parseDoWhileExpression returns a node representing a do while loop.
+// the body block is parsed first followed by the while keyword and the condition in parentheses
+func (p *Parser) parseDoWhileExpression() ast.Expression {
+	expression := &ast.DoWhileExpression{Token: p.currentToken}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	expression.Body = p.parseBlockStatement()
+
+	if !p.expectPeek(token.WHILE) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+
+	expression.Condition = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	return expression
+}
+
+// parseForInExpression returns a node representing a for in loop e.g. for (x in xs) { x }
+func (p *Parser) parseForInExpression() ast.Expression {
+	expression := &ast.ForInExpression{Token: p.currentToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.IDENTIFIER) {
+		return nil
+	}
+
+	expression.Variable = &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+
+	p.nextToken()
+
+	expression.Iterable = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	expression.Body = p.parseBlockStatement()
+
+	return expression
+}
+
+// parseCollectExpression returns a node representing a collect expression, the collect keyword must be followed by a loop
+func (p *Parser) parseCollectExpression() ast.Expression {
+	expression := &ast.CollectExpression{Token: p.currentToken}
+
+	p.nextToken()
+
+	switch p.currentToken.Type {
+	case token.DO:
+		expression.Loop = p.parseDoWhileExpression()
+	case token.FOR:
+		expression.Loop = p.parseForInExpression()
+	default:
+		message := fmt.Sprintf("expected a loop after collect, got %s", p.currentToken)
+		p.addError(p.currentToken, message)
+		return nil
+	}
+
+	if expression.Loop == nil {
+		return nil
+	}
+
+	return expression
+}
+
+// parseTryExpression returns a node representing a try catch expression.
+// the try block is followed by the catch keyword, the error identifier in parentheses and the catch block
+func (p *Parser) parseTryExpression() ast.Expression {
+	expression := &ast.TryExpression{Token: p.currentToken}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	expression.Block = p.parseBlockStatement()
+
+	if !p.expectPeek(token.CATCH) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.IDENTIFIER) {
+		return nil
+	}
+
+	expression.Parameter = &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	expression.Catch = p.parseBlockStatement()
+
+	return expression
+}
+
 // parseBlockStatement returns a node representing a block statement.
 // it parses the block until it encounters } which signifies end of block
 // or if it encounters an EOF
@@ -456,15 +842,27 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	p.nextToken()
 
 	for !p.currentTokenIS(token.RBRACE) && !p.currentTokenIS(token.EOF) {
+		errorCount := len(p.errors)
 		statement := p.parseStatement()
 
 		if statement != nil {
 			block.Statements = append(block.Statements, statement)
 		}
 
+		if len(p.errors) > errorCount {
+			p.synchronize()
+
+			// the closing brace of this block ends the loop
+			if p.currentTokenIS(token.RBRACE) {
+				break
+			}
+		}
+
 		p.nextToken()
 	}
 
+	p.recordSpan(block, block.Token)
+
 	return block
 }
 
@@ -476,46 +874,78 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 		return nil
 	}
 
-	literal.Parameters = p.parseFunctionParameters()
+	literal.Parameters, literal.Defaults = p.parseFunctionParameters()
 
 	if !p.expectPeek(token.LBRACE) {
 		return nil
 	}
 
+	// only yields that belong to this body make it a generator, yields in nested functions belong to those functions
+	outerYields := p.yields
+	p.yields = false
+
 	literal.Body = p.parseBlockStatement()
+	literal.Generator = p.yields
+
+	p.yields = outerYields
 
 	return literal
 }
 
-// parseFunctionParameters returns a list of identifiers that represent function parameters
-func (p *Parser) parseFunctionParameters() []*ast.Identifier {
+// parseFunctionParameters returns a list of identifiers that represent function parameters and their default values.
+// A parameter followed by = and an expression has a default value e.g. fn(x, y = 10)
+func (p *Parser) parseFunctionParameters() ([]*ast.Identifier, []ast.Expression) {
 	identifiers := []*ast.Identifier{}
 
+	// defaults stays nil until a parameter has a default value
+	var defaults []ast.Expression
+
 	// allow empty parameters
 	if p.peekTokenIs(token.RPAREN) {
 		p.nextToken()
-		return identifiers
+		return identifiers, nil
 	}
 
-	p.nextToken()
-
-	identifier := &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
-	identifiers = append(identifiers, identifier)
-
-	// parse function parameters
-	for p.peekTokenIs(token.COMMA) {
-		p.nextToken()
+	for {
 		p.nextToken()
 
 		identifier := &ast.Identifier{Token: p.currentToken, Value: p.currentToken.Literal}
 		identifiers = append(identifiers, identifier)
+
+		var value ast.Expression
+
+		if p.peekTokenIs(token.ASSIGN) {
+			p.nextToken()
+			p.nextToken()
+
+			value = p.parseExpression(LOWEST)
+
+			if defaults == nil {
+				defaults = make([]ast.Expression, len(identifiers)-1)
+			}
+		} else if defaults != nil {
+			// the caller could not leave out a default without also leaving out this parameter
+			message := fmt.Sprintf("parameter %s without a default value follows a parameter with one", identifier.Value)
+			p.addError(identifier.Token, message)
+			return nil, nil
+		}
+
+		if defaults != nil {
+			defaults = append(defaults, value)
+		}
+
+		// parse function parameters
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
 	}
 
 	if !p.expectPeek(token.RPAREN) {
-		return nil
+		return nil, nil
 	}
 
-	return identifiers
+	return identifiers, defaults
 }
 
 // parseCallExpression returns a node that represents the function call expression
@@ -553,14 +983,14 @@ func (p *Parser) parseExpressionList(delimiter token.TokenType) []ast.Expression
 
 	p.nextToken()
 
-	list = append(list, p.parseExpression(LOWEST))
+	list = append(list, p.parseListElement())
 
 	// parse function parameters
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
 		p.nextToken()
 
-		list = append(list, p.parseExpression(LOWEST))
+		list = append(list, p.parseListElement())
 	}
 
 	if !p.expectPeek(delimiter) {
@@ -570,6 +1000,21 @@ func (p *Parser) parseExpressionList(delimiter token.TokenType) []ast.Expression
 	return list
 }
 
+// parseListElement parses one element of a list, an element that starts with ... is spread into the list
+func (p *Parser) parseListElement() ast.Expression {
+	if !p.currentTokenIS(token.ELLIPSIS) {
+		return p.parseExpression(LOWEST)
+	}
+
+	spread := &ast.SpreadExpression{Token: p.currentToken}
+
+	p.nextToken()
+
+	spread.Value = p.parseExpression(LOWEST)
+
+	return spread
+}
+
 // parseIndexExpression is an infix expression where [ is the infix operator
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	expression := &ast.IndexExpression{Token: p.currentToken, Left: left}
@@ -605,6 +1050,7 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 		value := p.parseExpression(LOWEST)
 
 		hashLiteral.Pairs[key] = value
+		hashLiteral.Keys = append(hashLiteral.Keys, key)
 
 		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
 			return nil
@@ -617,3 +1063,30 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 
 	return hashLiteral
 }
+
+// parseAssignExpression is an infix expression where = is the infix operator
+// assignment is right associative so a = b = 5 assigns 5 to b and then to a
+func (p *Parser) parseAssignExpression(target ast.Expression) ast.Expression {
+	expression := &ast.AssignExpression{Token: p.currentToken, Target: target}
+
+	// the left side failed to parse, there is nothing to name in the error
+	if target == nil {
+		p.addError(expression.Token, fmt.Sprintf("invalid assignment target before %s", p.currentToken.Literal))
+		return nil
+	}
+
+	switch target.(type) {
+	case *ast.Identifier, *ast.IndexExpression:
+	default:
+		message := fmt.Sprintf("invalid assignment target: %s", target.String())
+		p.addError(expression.Token, message)
+		return nil
+	}
+
+	p.nextToken()
+
+	// parsing the right side with a lower precedence than ASSIGN makes the operator right associative
+	expression.Value = p.parseExpression(ASSIGN - 1)
+
+	return expression
+}
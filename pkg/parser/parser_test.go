@@ -2,6 +2,7 @@ package parser
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/maxwellgithinji/jaba/pkg/ast"
@@ -45,6 +46,65 @@ func TestLetStatement(t *testing.T) {
 	}
 }
 
+func TestDestructuringLetStatement(t *testing.T) {
+	input := "let a, b, c = [1, 2, 3];"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParseError(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("expected 1 statement, got %d", len(program.Statements))
+	}
+
+	statement, ok := program.Statements[0].(*ast.DestructuringLetStatement)
+	if !ok {
+		t.Fatalf("statement is not *ast.DestructuringLetStatement, got %T", program.Statements[0])
+	}
+
+	expectedNames := []string{"a", "b", "c"}
+	if len(statement.Names) != len(expectedNames) {
+		t.Fatalf("expected %d names, got %d", len(expectedNames), len(statement.Names))
+	}
+
+	for i, name := range expectedNames {
+		testIdentifier(t, statement.Names[i], name)
+	}
+
+	if statement.String() != "let a, b, c = [1, 2, 3];" {
+		t.Errorf("statement.String() is wrong, got: %q", statement.String())
+	}
+}
+
+func TestDestructuringLetStatementErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let a, = [1];", "expected next token to be IDENTIFIER, got ="},
+		{"let a, 1 = [1];", "expected next token to be IDENTIFIER, got INTEGER(\"1\")"},
+		{"let a, b [1];", "expected next token to be =, got ["},
+		{"let a, a = [1, 2];", "name a is bound more than once"},
+		{"let a, b, a = [1, 2, 3];", "name a is bound more than once"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for %q", tt.input)
+			continue
+		}
+
+		if errors[0] != tt.expected {
+			t.Errorf("wrong error message. expected: %q, got: %q", tt.expected, errors[0])
+		}
+	}
+}
+
 func TestReturnStatements(t *testing.T) {
 	tests := []struct {
 		input         string
@@ -87,6 +147,113 @@ func TestReturnStatements(t *testing.T) {
 
 }
 
+func TestYieldStatements(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedValue interface{}
+	}{
+		{"yield 1;", 1},
+		{"yield true", true},
+		{"yield foobar;", "foobar"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParseError(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("expected 1 statement, got %d", len(program.Statements))
+		}
+
+		yieldStatement, ok := program.Statements[0].(*ast.YieldStatement)
+		if !ok {
+			t.Fatalf("statement not *ast.YieldStatement, got: %T", program.Statements[0])
+		}
+
+		if yieldStatement.TokenLiteral() != "yield" {
+			t.Fatalf("yieldStatement.TokenLiteral() is not 'yield', got: %s", yieldStatement.TokenLiteral())
+		}
+
+		testLiteralExpression(t, yieldStatement.Value, tt.expectedValue)
+	}
+}
+
+func TestThrowStatements(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedValue interface{}
+		expectedText  string
+	}{
+		{`throw "not found";`, nil, `throw not found;`},
+		{"throw reason", "reason", "throw reason;"},
+		{"throw 404;", 404, "throw 404;"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParseError(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("expected 1 statement, got %d", len(program.Statements))
+		}
+
+		throwStatement, ok := program.Statements[0].(*ast.ThrowStatement)
+		if !ok {
+			t.Fatalf("statement not *ast.ThrowStatement, got: %T", program.Statements[0])
+		}
+
+		if tt.expectedValue != nil {
+			testLiteralExpression(t, throwStatement.Value, tt.expectedValue)
+		}
+
+		if throwStatement.String() != tt.expectedText {
+			t.Errorf("throwStatement.String() is wrong. expected: %q, got: %q", tt.expectedText, throwStatement.String())
+		}
+	}
+}
+
+func TestGeneratorFunctionLiteral(t *testing.T) {
+	tests := []struct {
+		input             string
+		expectedGenerator bool
+	}{
+		{"fn() { 1 }", false},
+		{"fn() { yield 1; }", true},
+		{"fn(x) { if (x) { yield x } }", true},
+		{"fn() { for (x in [1]) { yield x; } }", true},
+		// the yield belongs to the inner function
+		{"fn() { fn() { yield 1; } }", false},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParseError(t, p)
+
+		function, ok := program.Statements[0].(*ast.ExpressionStatement).Value.(*ast.FunctionLiteral)
+		if !ok {
+			t.Fatalf("%s is not *ast.FunctionLiteral, got: %T", tt.input, program.Statements[0].(*ast.ExpressionStatement).Value)
+		}
+
+		if function.Generator != tt.expectedGenerator {
+			t.Errorf("%s: function.Generator is not %t", tt.input, tt.expectedGenerator)
+		}
+	}
+
+	p := New(lexer.New("fn() { let f = fn() { yield 1; }; yield 2; }"))
+	program := p.ParseProgram()
+	checkParseError(t, p)
+
+	outer := program.Statements[0].(*ast.ExpressionStatement).Value.(*ast.FunctionLiteral)
+	inner := outer.Body.Statements[0].(*ast.LetStatement).Value.(*ast.FunctionLiteral)
+	if !outer.Generator || !inner.Generator {
+		t.Errorf("both functions should be generators, got: outer %t inner %t", outer.Generator, inner.Generator)
+	}
+}
+
 func TestIdentifierExpression(t *testing.T) {
 	input := "foobar"
 
@@ -256,6 +423,14 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"!-a",
 			"(!(-a))",
 		},
+		{
+			"--a",
+			"(-(-a))",
+		},
+		{
+			"5 - -5",
+			"(5 - (-5))",
+		},
 		{
 			"a + b + c",
 			"((a + b) + c)",
@@ -348,10 +523,58 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"a * [1, 2, 3, 3][b * c] * d",
 			"((a * ([1, 2, 3, 3][(b * c)])) * d)",
 		},
+		{
+			"a == b && c == d",
+			"((a == b) && (c == d))",
+		},
+		{
+			"a < b || c > d && e != f",
+			"((a < b) || ((c > d) && (e != f)))",
+		},
+		{
+			"a || b && c",
+			"(a || (b && c))",
+		},
+		{
+			"!a && b",
+			"((!a) && b)",
+		},
+		{
+			"a = b = 5",
+			"(a = (b = 5))",
+		},
+		{
+			"a[0] = b[0] = 1 + 2",
+			"((a[0]) = ((b[0]) = (1 + 2)))",
+		},
 		{
 			"add(a * b[2], b[1], 2 * [1, 2][1])",
 			"add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
 		},
+		{
+			"makeArray()[1]",
+			"(makeArray()[1])",
+		},
+		{
+			"arr[0](5)",
+			"(arr[0])(5)",
+		},
+		{
+			`hash["fn"](2)`,
+			"(hash[fn])(2)",
+		},
+		{
+			"getFns()[0]()",
+			"(getFns()[0])()",
+		},
+		{
+			"f()()[0][1](2)",
+			"((f()()[0])[1])(2)",
+		},
+		{
+			"-f()[0]",
+			"(-(f()[0]))",
+		},
 	}
 
 	for _, tt := range tests {
@@ -526,6 +749,107 @@ func TestIfElseExpression(t *testing.T) {
 	}
 }
 
+func TestElseIfExpression(t *testing.T) {
+	input := `if (x < y) { x } else if (x > y) { y } else { z }`
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParseError(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statement, got: %d", len(program.Statements))
+	}
+
+	statement, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement, got: %T", program.Statements[0])
+	}
+
+	expression, ok := statement.Value.(*ast.IfExpression)
+	if !ok {
+		t.Fatalf("statement.Value is not ast.IfExpression, got: %T", statement.Value)
+	}
+
+	if expression.Alternative != nil {
+		t.Errorf("expression.Alternative is not nil, got: %+v", expression.Alternative)
+	}
+
+	elseIf := expression.ElseIf
+	if elseIf == nil {
+		t.Fatalf("expression.ElseIf is nil")
+	}
+
+	if !testInfixExpression(t, elseIf.Condition, "x", ">", "y") {
+		return
+	}
+
+	if elseIf.Alternative == nil || elseIf.ElseIf != nil {
+		t.Fatalf("the last else is not a block, got: %+v", elseIf)
+	}
+
+	if expression.String() != "if(x < y) xelse if(x > y) yelse z" {
+		t.Errorf("expression.String() is wrong, got: %q", expression.String())
+	}
+}
+
+func TestFunctionParameterDefaults(t *testing.T) {
+	tests := []struct {
+		input            string
+		expectedDefaults []string
+		expectedString   string
+	}{
+		{"fn(x, y) {}", nil, "fn(x, y) "},
+		{"fn(x, y = 10) {}", []string{"", "10"}, "fn(x, y = 10) "},
+		{"fn(x = 1 + 2, y = x) {}", []string{"(1 + 2)", "x"}, "fn(x = (1 + 2), y = x) "},
+		{"fn(a, b = [1, 2], c = fn() { 3 }) {}", []string{"", "[1, 2]", "fn() 3"}, "fn(a, b = [1, 2], c = fn() 3) "},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParseError(t, p)
+
+		function := program.Statements[0].(*ast.ExpressionStatement).Value.(*ast.FunctionLiteral)
+
+		if len(function.Defaults) != len(tt.expectedDefaults) {
+			t.Fatalf("wrong number of defaults for %q. expected: %d, got: %d", tt.input, len(tt.expectedDefaults), len(function.Defaults))
+		}
+
+		for i, expected := range tt.expectedDefaults {
+			value := function.Defaults[i]
+
+			if expected == "" {
+				if value != nil {
+					t.Errorf("parameter %d of %q has a default, got: %s", i, tt.input, value.String())
+				}
+				continue
+			}
+
+			if value == nil || value.String() != expected {
+				t.Errorf("wrong default for parameter %d of %q. expected: %s, got: %v", i, tt.input, expected, value)
+			}
+		}
+
+		if function.String() != tt.expectedString {
+			t.Errorf("function.String() is wrong. expected: %q, got: %q", tt.expectedString, function.String())
+		}
+	}
+}
+
+func TestFunctionParameterDefaultsErrors(t *testing.T) {
+	p := New(lexer.New("fn(x = 1, y) { x + y }"))
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) == 0 {
+		t.Fatalf("expected parser errors")
+	}
+
+	if errors[0] != "parameter y without a default value follows a parameter with one" {
+		t.Errorf("wrong error message, got: %q", errors[0])
+	}
+}
+
 func TestFunctionLiteralParsing(t *testing.T) {
 	input := `fn(x, y) { x + y; }`
 
@@ -867,6 +1191,37 @@ func TestParsingArrayLiteral(t *testing.T) {
 
 }
 
+func TestParsingSpreadExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"f(...args)", "f(...args)"},
+		{"f(1, ...[2, 3], x + y)", "f(1, ...[2, 3], (x + y))"},
+		{"[...a, 3]", "[...a, 3]"},
+		{"[...a, ...b]", "[...a, ...b]"},
+		{"[...f(x)[0]]", "[...(f(x)[0])]"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParseError(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected: %q, got: %q", tt.expected, program.String())
+		}
+	}
+
+	p := New(lexer.New("...a"))
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) == 0 || errors[0] != "no prefix parse function for ... found" {
+		t.Errorf("spread outside a list: expected a parser error, got: %v", errors)
+	}
+}
+
 func TestParsingIndexExpression(t *testing.T) {
 	input := `myArray[1 + 1]`
 
@@ -1079,3 +1434,436 @@ func TestParsingHashLiteralsWithExpressions(t *testing.T) {
 	}
 
 }
+
+func TestDoWhileExpression(t *testing.T) {
+	input := "do { x } while (x < y)"
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParseError(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements expected 1 statements, got: %d", len(program.Statements))
+	}
+
+	statement, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement, got: %T", program.Statements[0])
+	}
+
+	expression, ok := statement.Value.(*ast.DoWhileExpression)
+	if !ok {
+		t.Fatalf("statement.Value is not ast.DoWhileExpression, got: %T", statement.Value)
+	}
+
+	if len(expression.Body.Statements) != 1 {
+		t.Fatalf("expression.Body.Statements expected 1 statements, got: %d", len(expression.Body.Statements))
+	}
+
+	body, ok := expression.Body.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("expression.Body.Statements[0] is not ast.ExpressionStatement, got: %T", expression.Body.Statements[0])
+	}
+
+	if !testIdentifier(t, body.Value, "x") {
+		return
+	}
+
+	testInfixExpression(t, expression.Condition, "x", "<", "y")
+}
+
+func TestForInExpression(t *testing.T) {
+	input := "for (x in xs) { x }"
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParseError(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements expected 1 statements, got: %d", len(program.Statements))
+	}
+
+	statement, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement, got: %T", program.Statements[0])
+	}
+
+	expression, ok := statement.Value.(*ast.ForInExpression)
+	if !ok {
+		t.Fatalf("statement.Value is not ast.ForInExpression, got: %T", statement.Value)
+	}
+
+	if !testIdentifier(t, expression.Variable, "x") {
+		return
+	}
+
+	if !testIdentifier(t, expression.Iterable, "xs") {
+		return
+	}
+
+	if len(expression.Body.Statements) != 1 {
+		t.Fatalf("expression.Body.Statements expected 1 statements, got: %d", len(expression.Body.Statements))
+	}
+
+	if expression.String() != "for (x in xs) x" {
+		t.Errorf("expression.String() is wrong, got: %q", expression.String())
+	}
+}
+
+func TestCollectExpression(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedLoop string
+	}{
+		{"collect for (x in xs) { x * 2 }", "*ast.ForInExpression"},
+		{"collect do { i = i + 1 } while (i < 3)", "*ast.DoWhileExpression"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParseError(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program.Statements expected 1 statements, got: %d", len(program.Statements))
+		}
+
+		statement, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatement, got: %T", program.Statements[0])
+		}
+
+		expression, ok := statement.Value.(*ast.CollectExpression)
+		if !ok {
+			t.Fatalf("statement.Value is not ast.CollectExpression, got: %T", statement.Value)
+		}
+
+		if loop := fmt.Sprintf("%T", expression.Loop); loop != tt.expectedLoop {
+			t.Errorf("expression.Loop is not %s, got: %s", tt.expectedLoop, loop)
+		}
+	}
+
+	p := New(lexer.New("collect [1, 2]"))
+	p.ParseProgram()
+
+	expected := `expected a loop after collect, got [`
+	if len(p.Errors()) == 0 || p.Errors()[0] != expected {
+		t.Errorf("expected error %q, got: %v", expected, p.Errors())
+	}
+}
+
+func TestTryExpression(t *testing.T) {
+	input := "try { x / y } catch (e) { e }"
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParseError(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements expected 1 statements, got: %d", len(program.Statements))
+	}
+
+	statement, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement, got: %T", program.Statements[0])
+	}
+
+	expression, ok := statement.Value.(*ast.TryExpression)
+	if !ok {
+		t.Fatalf("statement.Value is not ast.TryExpression, got: %T", statement.Value)
+	}
+
+	if len(expression.Block.Statements) != 1 {
+		t.Fatalf("expression.Block.Statements expected 1 statements, got: %d", len(expression.Block.Statements))
+	}
+
+	block, ok := expression.Block.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("expression.Block.Statements[0] is not ast.ExpressionStatement, got: %T", expression.Block.Statements[0])
+	}
+
+	if !testInfixExpression(t, block.Value, "x", "/", "y") {
+		return
+	}
+
+	if !testIdentifier(t, expression.Parameter, "e") {
+		return
+	}
+
+	if len(expression.Catch.Statements) != 1 {
+		t.Fatalf("expression.Catch.Statements expected 1 statements, got: %d", len(expression.Catch.Statements))
+	}
+
+	if expression.String() != "try (x / y) catch (e) e" {
+		t.Errorf("expression.String() is wrong, got: %q", expression.String())
+	}
+}
+
+func TestTryExpressionErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{"try { 1 }", "expected next token to be CATCH, got EOF"},
+		{"try { 1 } catch { 2 }", "expected next token to be (, got {"},
+		{"try { 1 } catch () { 2 }", "expected next token to be IDENTIFIER, got )"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 || errors[0] != tt.expectedError {
+			t.Errorf("%s: expected error %q, got: %v", tt.input, tt.expectedError, errors)
+		}
+	}
+}
+
+func TestSpans(t *testing.T) {
+	input := "let x = 1;\nlet café = x + 2 * 3;\nif (café) { (x) } else { f(\"☕\") }"
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParseError(t, p)
+
+	source := func(node ast.Node) string {
+		span, ok := p.Span(node)
+		if !ok {
+			t.Fatalf("%s has no span", node.String())
+		}
+		return input[span.Start:span.End]
+	}
+
+	let := program.Statements[0].(*ast.LetStatement)
+	second := program.Statements[1].(*ast.LetStatement)
+	sum := second.Value.(*ast.InfixExpression)
+	statement := program.Statements[2].(*ast.ExpressionStatement)
+	ifExpression := statement.Value.(*ast.IfExpression)
+	grouped := ifExpression.Consequence.Statements[0].(*ast.ExpressionStatement)
+	call := ifExpression.Alternative.Statements[0].(*ast.ExpressionStatement).Value.(*ast.CallExpression)
+
+	tests := []struct {
+		node     ast.Node
+		expected string
+	}{
+		{let, "let x = 1;"},
+		{let.Value, "1"},
+		{second, "let café = x + 2 * 3;"},
+		{sum, "x + 2 * 3"},
+		{sum.Left, "x"},
+		{sum.Right, "2 * 3"},
+		{statement, `if (café) { (x) } else { f("☕") }`},
+		{ifExpression.Condition, "café"},
+		{ifExpression.Consequence, "{ (x) }"},
+		// the parentheses around an expression are not part of it
+		{grouped.Value, "x"},
+		{ifExpression.Alternative, `{ f("☕") }`},
+		{call, `f("☕")`},
+		{call.Arguments[0], `"☕"`},
+	}
+
+	for _, tt := range tests {
+		if got := source(tt.node); got != tt.expected {
+			t.Errorf("wrong span of %s. expected: %q, got: %q", tt.node.String(), tt.expected, got)
+		}
+	}
+
+	if _, ok := p.Span(&ast.Identifier{Value: "x"}); ok {
+		t.Errorf("a node the parser did not create has a span")
+	}
+}
+
+func TestInvalidAssignmentTarget(t *testing.T) {
+	l := lexer.New("1 = 2")
+	p := New(l)
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) != 1 {
+		t.Fatalf("expected 1 error, got: %d %v", len(errors), errors)
+	}
+
+	if errors[0] != "invalid assignment target: 1" {
+		t.Errorf("wrong error message, got: %q", errors[0])
+	}
+}
+
+func TestAssignmentToAnExpressionThatFailedToParse(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{
+			"if = 1",
+			[]string{
+				"expected next token to be (, got =",
+				"invalid assignment target before =",
+			},
+		},
+		{
+			"let f = fn = 1",
+			[]string{
+				"expected next token to be (, got =",
+				"invalid assignment target before =",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != len(tt.expected) {
+			t.Errorf("wrong number of errors for %q. expected: %d, got: %d %v", tt.input, len(tt.expected), len(errors), errors)
+			continue
+		}
+
+		for i, message := range tt.expected {
+			if errors[i] != message {
+				t.Errorf("wrong error message. expected: %q, got: %q", message, errors[i])
+			}
+		}
+	}
+}
+
+func TestErrorRecovery(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{
+			"let = 1; let x 5; let y = 10; return y;",
+			[]string{
+				"expected next token to be IDENTIFIER, got =",
+				"expected next token to be =, got INTEGER(\"5\")",
+			},
+		},
+		{
+			"let f = fn(x) { let = x; x }; let 5 = f(1); f(2);",
+			[]string{
+				"expected next token to be IDENTIFIER, got =",
+				"expected next token to be IDENTIFIER, got INTEGER(\"5\")",
+			},
+		},
+		{
+			"let = fn() { 1; 2 }; let h = {1: 2}; let = 3;",
+			[]string{
+				"expected next token to be IDENTIFIER, got =",
+				"expected next token to be IDENTIFIER, got =",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != len(tt.expected) {
+			t.Errorf("wrong number of errors for %q. expected: %d, got: %d %v", tt.input, len(tt.expected), len(errors), errors)
+			continue
+		}
+
+		for i, message := range tt.expected {
+			if errors[i] != message {
+				t.Errorf("wrong error message. expected: %q, got: %q", message, errors[i])
+			}
+		}
+	}
+}
+
+func TestErrorRecoveryKeepsParsing(t *testing.T) {
+	p := New(lexer.New("let = 1; let x = 5; x;"))
+	program := p.ParseProgram()
+
+	if len(p.Errors()) != 1 {
+		t.Fatalf("expected 1 error, got: %v", p.Errors())
+	}
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements does not contain 2 statements, got: %d", len(program.Statements))
+	}
+
+	testLetStatements(t, program.Statements[0], "x")
+}
+
+func TestErrorPositions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []Error
+	}{
+		{
+			"let x 5;\nlet y = 10;\n  let = 1;",
+			[]Error{
+				{Message: "expected next token to be =, got INTEGER(\"5\")", Line: 1, Column: 7},
+				{Message: "expected next token to be IDENTIFIER, got =", Line: 3, Column: 7},
+			},
+		},
+		{
+			"let x = 1;\n1 = 2;",
+			[]Error{
+				{Message: "invalid assignment target: 1", Line: 2, Column: 3},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.PositionedErrors()
+		if len(errors) != len(tt.expected) {
+			t.Errorf("wrong number of errors for %q. expected: %d, got: %d %v", tt.input, len(tt.expected), len(errors), errors)
+			continue
+		}
+
+		for i, expected := range tt.expected {
+			if errors[i] != expected {
+				t.Errorf("wrong error for %q. expected: %+v, got: %+v", tt.input, expected, errors[i])
+			}
+		}
+	}
+}
+
+func TestNestingDepthLimit(t *testing.T) {
+	tests := []string{
+		strings.Repeat("(", 100000) + "1" + strings.Repeat(")", 100000),
+		strings.Repeat("-", 100000) + "1",
+		strings.Repeat("[", 100000),
+		"let x = " + strings.Repeat("fn() { ", 100000),
+		"if (x) { 1 }" + strings.Repeat(" else if (x) { 1 }", 100000),
+	}
+
+	for _, input := range tests {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != 1 {
+			t.Errorf("expected 1 error, got: %d %v", len(errors), errors)
+			continue
+		}
+
+		if errors[0] != "expression is nested too deeply, the maximum depth is 1000" {
+			t.Errorf("wrong error message, got: %q", errors[0])
+		}
+	}
+}
+
+func TestSetMaxNestingDepth(t *testing.T) {
+	SetMaxNestingDepth(5)
+	defer SetMaxNestingDepth(1000)
+
+	p := New(lexer.New("((((1))))"))
+	p.ParseProgram()
+	checkParseError(t, p)
+
+	p = New(lexer.New("(((((1)))))"))
+	p.ParseProgram()
+
+	if len(p.Errors()) != 1 {
+		t.Fatalf("expected 1 error, got: %v", p.Errors())
+	}
+}
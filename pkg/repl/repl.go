@@ -9,6 +9,7 @@ import (
 	"fmt"
 
 	"io"
+	"strings"
 
 	"github.com/maxwellgithinji/jaba/pkg/evaluator"
 	"github.com/maxwellgithinji/jaba/pkg/lexer"
@@ -30,19 +31,63 @@ ____
 |     \___\
 `
 
+// UndoCommand reverts the bindings changed by the last line that was evaluated
+const UndoCommand = ":undo"
+
+// maxUndoHistory is the number of evaluated lines that can be undone
+const maxUndoHistory = 20
+
+// undoEntry holds the environment as it was before a line was evaluated
+type undoEntry struct {
+	line     string
+	snapshot *object.Environment
+}
+
+// Options changes how Run talks to the user
+type Options struct {
+	// Quiet leaves out the prompt and the banner so only the results are written,
+	// which suits input that is piped in instead of typed e.g. echo 'let x = 1; x' | jaba
+	Quiet bool
+}
+
 // Run is a Read Eval Print Loop function that runs the jaba program.
 // it helps the user code the jaba program on the command line
-func Run(in io.Reader, out io.Writer) {
-	scanner := bufio.NewScanner(in)
+func Run(in io.Reader, out io.Writer, options Options) {
+	// the lines of the program and the lines read by input come from the same buffer,
+	// so a line typed for input is not taken as code
+	reader := bufio.NewReader(in)
 	env := object.NewEnvironment()
+	evaluator.SetOutput(out)
+	evaluator.SetInput(reader)
+
+	history := []undoEntry{}
+
 	for {
-		fmt.Fprint(out, Prompt)
-		scanned := scanner.Scan()
-		if !scanned {
+		if !options.Quiet {
+			fmt.Fprint(out, Prompt)
+		}
+		line, err := reader.ReadString('\n')
+		if err != nil && line == "" {
 			return
 		}
 
-		line := scanner.Text()
+		line = strings.TrimSuffix(line, "\n")
+		line = strings.TrimSuffix(line, "\r")
+
+		if strings.TrimSpace(line) == UndoCommand {
+			if len(history) == 0 {
+				io.WriteString(out, "nothing to undo\n")
+				continue
+			}
+
+			last := history[len(history)-1]
+			history = history[:len(history)-1]
+
+			env.Restore(last.snapshot)
+			io.WriteString(out, "undid: "+last.line+"\n")
+			continue
+		}
+
 		l := lexer.New(line)
 
 		p := parser.New(l)
@@ -50,10 +95,21 @@ func Run(in io.Reader, out io.Writer) {
 		program := p.ParseProgram()
 
 		if len(p.Errors()) != 0 {
-			printParserErrors(out, p.Errors())
+			printParserErrors(out, p.Errors(), options.Quiet)
+			continue
+		}
+
+		// an empty line has nothing worth printing
+		if len(program.Statements) == 0 {
 			continue
 		}
 
+		// the oldest entry is dropped once the history is full
+		if len(history) == maxUndoHistory {
+			history = history[1:]
+		}
+		history = append(history, undoEntry{line: line, snapshot: env.Snapshot()})
+
 		evaluated := evaluator.Eval(program, env)
 
 		if evaluated != nil {
@@ -63,9 +119,12 @@ func Run(in io.Reader, out io.Writer) {
 	}
 }
 
-func printParserErrors(out io.Writer, errors []string) {
-	io.WriteString(out, PRETTY_JABA)
-	io.WriteString(out, "Woops! We ran into some jaba stories here!\n")
+// printParserErrors writes the parser errors of a line, quiet leaves out the logo and the greeting
+func printParserErrors(out io.Writer, errors []string, quiet bool) {
+	if !quiet {
+		io.WriteString(out, PRETTY_JABA)
+		io.WriteString(out, "Woops! We ran into some jaba stories here!\n")
+	}
 	io.WriteString(out, "parser errors: \n")
 	for _, message := range errors {
 		io.WriteString(out, "\t"+message+"\n")
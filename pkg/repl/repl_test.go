@@ -0,0 +1,116 @@
+package repl
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/maxwellgithinji/jaba/pkg/evaluator"
+)
+
+func TestRunWritesPutsToOut(t *testing.T) {
+	defer evaluator.SetOutput(os.Stdout)
+
+	var out bytes.Buffer
+	Run(strings.NewReader(`puts("x")`), &out, Options{})
+
+	expected := Prompt + "x\nnull\n" + Prompt
+	if out.String() != expected {
+		t.Errorf("wrong output. expected: %q, got: %q", expected, out.String())
+	}
+}
+
+func TestRunSkipsEmptyLines(t *testing.T) {
+	var out bytes.Buffer
+	Run(strings.NewReader("\n   \n1"), &out, Options{})
+
+	expected := Prompt + Prompt + Prompt + "1\n" + Prompt
+	if out.String() != expected {
+		t.Errorf("wrong output. expected: %q, got: %q", expected, out.String())
+	}
+}
+
+func TestRunReportsThrownErrors(t *testing.T) {
+	var out bytes.Buffer
+	Run(strings.NewReader(`throw "bad input"`+"\n1"), &out, Options{})
+
+	expected := Prompt + "ERROR: bad input\n" + Prompt + "1\n" + Prompt
+	if out.String() != expected {
+		t.Errorf("wrong output. expected: %q, got: %q", expected, out.String())
+	}
+}
+
+func TestRunUndo(t *testing.T) {
+	input := "let x = 1\nlet x = 2\nx\n:undo\n:undo\nx\n:undo\n:undo"
+
+	var out bytes.Buffer
+	Run(strings.NewReader(input), &out, Options{})
+
+	expected := Prompt + // let x = 1
+		Prompt + // let x = 2
+		Prompt + "2\n" +
+		Prompt + "undid: x\n" +
+		Prompt + "undid: let x = 2\n" +
+		Prompt + "1\n" +
+		Prompt + "undid: x\n" +
+		Prompt + "undid: let x = 1\n" +
+		Prompt
+
+	if out.String() != expected {
+		t.Errorf("wrong output. expected: %q, got: %q", expected, out.String())
+	}
+}
+
+func TestRunUndoWithEmptyHistory(t *testing.T) {
+	var out bytes.Buffer
+	Run(strings.NewReader(":undo\nlet y = 5\n:undo\ny"), &out, Options{})
+
+	expected := Prompt + "nothing to undo\n" +
+		Prompt +
+		Prompt + "undid: let y = 5\n" +
+		Prompt + "ERROR: identifier not found: y\n" +
+		Prompt
+
+	if out.String() != expected {
+		t.Errorf("wrong output. expected: %q, got: %q", expected, out.String())
+	}
+}
+
+func TestRunQuiet(t *testing.T) {
+	defer evaluator.SetOutput(os.Stdout)
+
+	input := "let x = 1; x\n\nlet y = [x, 2];\ny\nputs(\"hi\")\nz\nlet = 1"
+
+	var out bytes.Buffer
+	Run(strings.NewReader(input), &out, Options{Quiet: true})
+
+	expected := "1\n" +
+		"[1, 2]\n" +
+		"hi\nnull\n" +
+		"ERROR: identifier not found: z\n" +
+		"parser errors: \n\texpected next token to be IDENTIFIER, got =\n"
+
+	if out.String() != expected {
+		t.Errorf("wrong output. expected: %q, got: %q", expected, out.String())
+	}
+}
+
+func TestRunSharesInputWithInputBuiltin(t *testing.T) {
+	defer evaluator.SetOutput(os.Stdout)
+	defer evaluator.SetInput(os.Stdin)
+
+	// the line after the call to input is read by input instead of being evaluated
+	input := "let x = input();\nhello\nx\nlet y = input(\"name: \"); y\nworld\nlen(x + y)\n"
+
+	var out bytes.Buffer
+	Run(strings.NewReader(input), &out, Options{Quiet: true})
+
+	expected := "hello\n" +
+		"name: world\n" +
+		"10\n"
+
+	if out.String() != expected {
+		t.Errorf("wrong output. expected: %q, got: %q", expected, out.String())
+	}
+}
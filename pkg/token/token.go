@@ -4,6 +4,8 @@
  */
 package token
 
+import "fmt"
+
 /*
 TokenType represents the category of a token.
 It is of type string
@@ -23,6 +25,24 @@ type Token struct {
 	Type TokenType
 	// Literal defines the actual value of the token.
 	Literal string
+	// Line is the line the token starts on, counting from 1.
+	Line int
+	// Column is the column the token starts at, counting from 1.
+	Column int
+	// Offset is the byte offset the token starts at in the input, counting from 0.
+	Offset int
+	// End is the byte offset just past the last byte of the token, input[Offset:End] is the source of the token.
+	End int
+}
+
+// String returns a readable form of the token for debugging and error messages e.g. IDENTIFIER("foo").
+// The literal is left out when it adds nothing to the type, e.g. for = or EOF
+func (t Token) String() string {
+	if t.Literal == "" || t.Literal == string(t.Type) {
+		return string(t.Type)
+	}
+
+	return fmt.Sprintf("%s(%q)", t.Type, t.Literal)
 }
 
 const (
@@ -72,6 +92,12 @@ const (
 	// NEQ represents the not equal operation. eg. x!= 1
 	NEQ TokenType = "!="
 
+	// AND represents the logical and operation. eg. x && y
+	AND TokenType = "&&"
+
+	// OR represents the logical or operation. eg. x || y
+	OR TokenType = "||"
+
 	// Delimiters (Special Characters)
 
 	// COMMA represents the comma operator.
@@ -95,6 +121,9 @@ const (
 	// COLON represents the operator which separates values in a map.
 	COLON TokenType = ":"
 
+	// ELLIPSIS represents the spread operator. it expands an array into a list e.g. f(...args) or [...a, b]
+	ELLIPSIS TokenType = "..."
+
 	// 	Keywords (Are reserved for the language and cannot be used as identifiers)
 
 	// FUNCTION represents the keyword function.
@@ -118,6 +147,33 @@ const (
 	// RETURN represents the keyword return. it is used to return a value from a function.
 	RETURN TokenType = "RETURN"
 
+	// YIELD represents the keyword yield. it hands a value out of a generator function and pauses it until the next value is asked for.
+	YIELD TokenType = "YIELD"
+
+	// DO represents the keyword do. it starts a loop whose body runs before its condition is checked.
+	DO TokenType = "DO"
+
+	// WHILE represents the keyword while. it introduces the condition of a loop.
+	WHILE TokenType = "WHILE"
+
+	// FOR represents the keyword for. it starts a loop over the elements of an array or the values of an iterator.
+	FOR TokenType = "FOR"
+
+	// IN represents the keyword in. it separates the loop variable from what is looped over e.g. for (x in xs)
+	IN TokenType = "IN"
+
+	// COLLECT represents the keyword collect. it turns the loop that follows it into an array of the values of its iterations.
+	COLLECT TokenType = "COLLECT"
+
+	// TRY represents the keyword try. it starts a block whose runtime errors are handled by the catch block that follows it.
+	TRY TokenType = "TRY"
+
+	// CATCH represents the keyword catch. it introduces the block that runs when the try block fails e.g. catch (e) { ... }
+	CATCH TokenType = "CATCH"
+
+	// THROW represents the keyword throw. it raises an error that stops the program unless a catch block handles it.
+	THROW TokenType = "THROW"
+
 	// STRING represents the string datatype. a string is anything enclosed in quotes
 	STRING TokenType = "STRING"
 
@@ -126,17 +182,29 @@ const (
 
 	// RBRACKET represents the closing square bracket character
 	RBRACKET TokenType = "]"
+
+	// AT represents the @ character. it is reserved for annotations e.g. @memoize fn(x) { x }
+	AT TokenType = "@"
 )
 
 // keywords defines the language reserves characters that cannot be used as identifiers.
 var keywords = map[string]TokenType{
-	"fn":     FUNCTION,
-	"let":    LET,
-	"true":   TRUE,
-	"false":  FALSE,
-	"if":     IF,
-	"else":   ELSE,
-	"return": RETURN,
+	"fn":      FUNCTION,
+	"let":     LET,
+	"true":    TRUE,
+	"false":   FALSE,
+	"if":      IF,
+	"else":    ELSE,
+	"return":  RETURN,
+	"yield":   YIELD,
+	"do":      DO,
+	"while":   WHILE,
+	"for":     FOR,
+	"in":      IN,
+	"collect": COLLECT,
+	"try":     TRY,
+	"catch":   CATCH,
+	"throw":   THROW,
 }
 
 // LookupIdentifier returns the token type for the given identifier.
@@ -0,0 +1,24 @@
+package token
+
+import "testing"
+
+func TestTokenString(t *testing.T) {
+	tests := []struct {
+		token    Token
+		expected string
+	}{
+		{Token{Type: IDENTIFIER, Literal: "foo"}, `IDENTIFIER("foo")`},
+		{Token{Type: INTEGER, Literal: "5"}, `INTEGER("5")`},
+		{Token{Type: STRING, Literal: "hello world"}, `STRING("hello world")`},
+		{Token{Type: STRING, Literal: ""}, "STRING"},
+		{Token{Type: LET, Literal: "let"}, `LET("let")`},
+		{Token{Type: ASSIGN, Literal: "="}, "="},
+		{Token{Type: EOF, Literal: ""}, "EOF"},
+	}
+
+	for _, tt := range tests {
+		if tt.token.String() != tt.expected {
+			t.Errorf("wrong string for %#v. expected: %s, got: %s", tt.token, tt.expected, tt.token.String())
+		}
+	}
+}
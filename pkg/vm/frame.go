@@ -0,0 +1,28 @@
+package vm
+
+import (
+	"github.com/maxwellgithinji/jaba/pkg/code"
+	"github.com/maxwellgithinji/jaba/pkg/object"
+)
+
+// Frame holds the execution state of a function call
+type Frame struct {
+	// fn is the function being executed
+	fn *object.CompiledFunction
+
+	// ip is the instruction pointer, the offset of the instruction being executed
+	ip int
+
+	// basePointer is the stack pointer before the call, the locals of the function start there
+	basePointer int
+}
+
+// NewFrame creates a frame for a function call
+func NewFrame(fn *object.CompiledFunction, basePointer int) *Frame {
+	return &Frame{fn: fn, ip: -1, basePointer: basePointer}
+}
+
+// Instructions returns the bytecode of the function being executed
+func (f *Frame) Instructions() code.Instructions {
+	return f.fn.Instructions
+}
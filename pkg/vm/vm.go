@@ -0,0 +1,457 @@
+/*
+* Package vm is a stack based virtual machine that executes the bytecode produced by the compiler.
+* It is an alternative to the tree walking evaluator which is faster for programs that call a lot of functions
+* because it does not walk the AST on every call.
+ */
+package vm
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/maxwellgithinji/jaba/pkg/code"
+	"github.com/maxwellgithinji/jaba/pkg/compiler"
+	"github.com/maxwellgithinji/jaba/pkg/object"
+)
+
+const (
+	// StackSize is the maximum number of objects on the stack
+	StackSize = 2048
+
+	// GlobalsSize is the maximum number of global bindings, the operand of OpSetGlobal is two bytes wide
+	GlobalsSize = 65536
+
+	// MaxFrames is the maximum depth of function calls
+	MaxFrames = 1024
+)
+
+var (
+	True  = &object.Boolean{Value: true}
+	False = &object.Boolean{Value: false}
+	Null  = &object.Null{}
+)
+
+// VM executes bytecode
+type VM struct {
+	// constants is the constant pool produced by the compiler
+	constants []object.Object
+
+	// stack holds the operands of the instructions
+	stack []object.Object
+
+	// sp is the stack pointer, it always points to the next free slot. the top of the stack is stack[sp-1]
+	sp int
+
+	// globals holds the global bindings
+	globals []object.Object
+
+	// frames holds the call stack
+	frames []*Frame
+
+	// framesIndex is the index of the next free frame
+	framesIndex int
+
+	// lastPopped is the value of the last expression statement of the main program,
+	// it is nil when the program ends with a let statement like the result of the evaluator
+	lastPopped object.Object
+}
+
+// New creates a virtual machine that runs the main program of the bytecode
+func New(bytecode *compiler.Bytecode) *VM {
+	mainFunction := &object.CompiledFunction{Instructions: bytecode.Instructions}
+	mainFrame := NewFrame(mainFunction, 0)
+
+	frames := make([]*Frame, MaxFrames)
+	frames[0] = mainFrame
+
+	return &VM{
+		constants:   bytecode.Constants,
+		stack:       make([]object.Object, StackSize),
+		sp:          0,
+		globals:     make([]object.Object, GlobalsSize),
+		frames:      frames,
+		framesIndex: 1,
+	}
+}
+
+// LastPoppedStackElem returns the value of the last statement of the main program,
+// which is nil when the last statement is a let statement
+func (vm *VM) LastPoppedStackElem() object.Object {
+	return vm.lastPopped
+}
+
+// Run executes the bytecode until the main program ends or an error occurs
+func (vm *VM) Run() error {
+	var ip int
+	var ins code.Instructions
+	var op code.Opcode
+
+	for vm.currentFrame().ip < len(vm.currentFrame().Instructions())-1 {
+		vm.currentFrame().ip++
+
+		ip = vm.currentFrame().ip
+		ins = vm.currentFrame().Instructions()
+		op = code.Opcode(ins[ip])
+
+		switch op {
+		case code.OpConstant:
+			constIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+
+			if err := vm.push(vm.constants[constIndex]); err != nil {
+				return err
+			}
+
+		case code.OpPop:
+			popped := vm.pop()
+
+			// statements inside functions do not give the program its value
+			if vm.framesIndex == 1 {
+				vm.lastPopped = popped
+			}
+
+		case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv:
+			if err := vm.executeBinaryOperation(op); err != nil {
+				return err
+			}
+
+		case code.OpEqual, code.OpNotEqual, code.OpGreaterThan:
+			if err := vm.executeComparison(op); err != nil {
+				return err
+			}
+
+		case code.OpTrue:
+			if err := vm.push(True); err != nil {
+				return err
+			}
+
+		case code.OpFalse:
+			if err := vm.push(False); err != nil {
+				return err
+			}
+
+		case code.OpNull:
+			if err := vm.push(Null); err != nil {
+				return err
+			}
+
+		case code.OpBang:
+			if err := vm.push(nativeBoolToBooleanObject(!isTruthy(vm.pop()))); err != nil {
+				return err
+			}
+
+		case code.OpMinus:
+			if err := vm.executeMinusOperator(); err != nil {
+				return err
+			}
+
+		case code.OpJump:
+			position := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip = position - 1
+
+		case code.OpJumpNotTruthy:
+			position := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			if !isTruthy(vm.pop()) {
+				vm.currentFrame().ip = position - 1
+			}
+
+		case code.OpSetGlobal:
+			globalIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+
+			vm.globals[globalIndex] = vm.pop()
+
+			// a let statement has no value, so a program that ends with one has none either
+			vm.lastPopped = nil
+
+		case code.OpGetGlobal:
+			globalIndex := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+
+			if err := vm.push(vm.globals[globalIndex]); err != nil {
+				return err
+			}
+
+		case code.OpSetLocal:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			frame := vm.currentFrame()
+			vm.stack[frame.basePointer+int(localIndex)] = vm.pop()
+
+		case code.OpGetLocal:
+			localIndex := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			frame := vm.currentFrame()
+			if err := vm.push(vm.stack[frame.basePointer+int(localIndex)]); err != nil {
+				return err
+			}
+
+		case code.OpCall:
+			numArgs := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			if err := vm.callFunction(int(numArgs)); err != nil {
+				return err
+			}
+
+		case code.OpReturnValue:
+			returnValue := vm.pop()
+
+			frame := vm.popFrame()
+			vm.sp = frame.basePointer - 1
+
+			if err := vm.push(returnValue); err != nil {
+				return err
+			}
+
+		case code.OpReturn:
+			frame := vm.popFrame()
+			vm.sp = frame.basePointer - 1
+
+			if err := vm.push(Null); err != nil {
+				return err
+			}
+
+		default:
+			return fmt.Errorf("unknown opcode %d", op)
+		}
+	}
+
+	return nil
+}
+
+// callFunction sets up a frame for the function below the arguments on the stack
+// the arguments become the first locals of the function
+func (vm *VM) callFunction(numArgs int) error {
+	fn, ok := vm.stack[vm.sp-1-numArgs].(*object.CompiledFunction)
+	if !ok {
+		return fmt.Errorf("not a function: %s", vm.stack[vm.sp-1-numArgs].Type())
+	}
+
+	if numArgs != fn.NumParameters {
+		return fmt.Errorf("wrong number of arguments. got: %d want: %d", numArgs, fn.NumParameters)
+	}
+
+	if vm.framesIndex >= MaxFrames {
+		return fmt.Errorf("maximum recursion depth exceeded")
+	}
+
+	frame := NewFrame(fn, vm.sp-numArgs)
+	vm.pushFrame(frame)
+
+	vm.sp = frame.basePointer + fn.NumLocals
+	if vm.sp >= StackSize {
+		return fmt.Errorf("stack overflow")
+	}
+
+	return nil
+}
+
+// executeBinaryOperation pops two integers and pushes the result of the arithmetic operation.
+// like the evaluator, a result that does not fit in an int64 becomes a big integer
+func (vm *VM) executeBinaryOperation(op code.Opcode) error {
+	right := vm.pop()
+	left := vm.pop()
+
+	if !isInteger(left) || !isInteger(right) {
+		return fmt.Errorf("unsupported types for binary operation: %s %s", left.Type(), right.Type())
+	}
+
+	if op == code.OpDiv && toBigInt(right).Sign() == 0 {
+		return fmt.Errorf("division by zero")
+	}
+
+	leftInteger, leftOk := left.(*object.Integer)
+	rightInteger, rightOk := right.(*object.Integer)
+	if leftOk && rightOk {
+		if result, ok := integerOperation(op, leftInteger.Value, rightInteger.Value); ok {
+			return vm.push(&object.Integer{Value: result})
+		}
+	}
+
+	leftValue, rightValue := toBigInt(left), toBigInt(right)
+	result := new(big.Int)
+
+	switch op {
+	case code.OpAdd:
+		result.Add(leftValue, rightValue)
+	case code.OpSub:
+		result.Sub(leftValue, rightValue)
+	case code.OpMul:
+		result.Mul(leftValue, rightValue)
+	case code.OpDiv:
+		// Quo truncates towards zero like the division of int64 values
+		result.Quo(leftValue, rightValue)
+	}
+
+	return vm.push(normalizeBigInt(result))
+}
+
+// integerOperation applies an arithmetic operation to two int64 values, the boolean is false when the result does not fit in an int64
+func integerOperation(op code.Opcode, left, right int64) (int64, bool) {
+	switch op {
+	case code.OpAdd:
+		sum := left + right
+		return sum, (left^sum)&(right^sum) >= 0
+	case code.OpSub:
+		difference := left - right
+		return difference, (left^right)&(left^difference) >= 0
+	case code.OpMul:
+		if left == 0 || right == 0 {
+			return 0, true
+		}
+		if (left == -1 && right == math.MinInt64) || (right == -1 && left == math.MinInt64) {
+			return 0, false
+		}
+		product := left * right
+		return product, product/right == left
+	case code.OpDiv:
+		if left == math.MinInt64 && right == -1 {
+			return 0, false
+		}
+		return left / right, true
+	}
+
+	return 0, false
+}
+
+// executeComparison pops two objects and pushes the result of the comparison
+// integers are compared by value, everything else by identity
+func (vm *VM) executeComparison(op code.Opcode) error {
+	right := vm.pop()
+	left := vm.pop()
+
+	if left.Type() == object.INTEGER_OBJECT && right.Type() == object.INTEGER_OBJECT {
+		leftValue := left.(*object.Integer).Value
+		rightValue := right.(*object.Integer).Value
+
+		switch op {
+		case code.OpEqual:
+			return vm.push(nativeBoolToBooleanObject(leftValue == rightValue))
+		case code.OpNotEqual:
+			return vm.push(nativeBoolToBooleanObject(leftValue != rightValue))
+		case code.OpGreaterThan:
+			return vm.push(nativeBoolToBooleanObject(leftValue > rightValue))
+		}
+	}
+
+	if isInteger(left) && isInteger(right) {
+		comparison := toBigInt(left).Cmp(toBigInt(right))
+
+		switch op {
+		case code.OpEqual:
+			return vm.push(nativeBoolToBooleanObject(comparison == 0))
+		case code.OpNotEqual:
+			return vm.push(nativeBoolToBooleanObject(comparison != 0))
+		case code.OpGreaterThan:
+			return vm.push(nativeBoolToBooleanObject(comparison > 0))
+		}
+	}
+
+	switch op {
+	case code.OpEqual:
+		return vm.push(nativeBoolToBooleanObject(left == right))
+	case code.OpNotEqual:
+		return vm.push(nativeBoolToBooleanObject(left != right))
+	default:
+		return fmt.Errorf("unknown operator: %d (%s %s)", op, left.Type(), right.Type())
+	}
+}
+
+// executeMinusOperator negates the integer on top of the stack
+func (vm *VM) executeMinusOperator() error {
+	operand := vm.pop()
+
+	if !isInteger(operand) {
+		return fmt.Errorf("unsupported type for negation: %s", operand.Type())
+	}
+
+	// the smallest int64 has no int64 opposite
+	if integer, ok := operand.(*object.Integer); ok && integer.Value != math.MinInt64 {
+		return vm.push(&object.Integer{Value: -integer.Value})
+	}
+
+	return vm.push(normalizeBigInt(new(big.Int).Neg(toBigInt(operand))))
+}
+
+// isInteger reports whether the object is an integer or a big integer
+func isInteger(obj object.Object) bool {
+	return obj.Type() == object.INTEGER_OBJECT || obj.Type() == object.BIGINT_OBJECT
+}
+
+// toBigInt returns the value of an integer or a big integer as a big.Int
+func toBigInt(obj object.Object) *big.Int {
+	if integer, ok := obj.(*object.Integer); ok {
+		return big.NewInt(integer.Value)
+	}
+	return obj.(*object.BigInt).Value
+}
+
+// normalizeBigInt returns an integer when the value fits in an int64 and a big integer otherwise
+func normalizeBigInt(value *big.Int) object.Object {
+	if value.IsInt64() {
+		return &object.Integer{Value: value.Int64()}
+	}
+	return &object.BigInt{Value: value}
+}
+
+// push puts an object on top of the stack
+func (vm *VM) push(obj object.Object) error {
+	if vm.sp >= StackSize {
+		return fmt.Errorf("stack overflow")
+	}
+
+	vm.stack[vm.sp] = obj
+	vm.sp++
+
+	return nil
+}
+
+// pop removes the object on top of the stack and returns it
+func (vm *VM) pop() object.Object {
+	obj := vm.stack[vm.sp-1]
+	vm.sp--
+	return obj
+}
+
+// currentFrame returns the frame being executed
+func (vm *VM) currentFrame() *Frame {
+	return vm.frames[vm.framesIndex-1]
+}
+
+// pushFrame makes a frame the one being executed
+func (vm *VM) pushFrame(f *Frame) {
+	vm.frames[vm.framesIndex] = f
+	vm.framesIndex++
+}
+
+// popFrame returns to the frame of the caller
+func (vm *VM) popFrame() *Frame {
+	vm.framesIndex--
+	return vm.frames[vm.framesIndex]
+}
+
+// nativeBoolToBooleanObject converts a native boolean to the shared boolean objects
+func nativeBoolToBooleanObject(input bool) *object.Boolean {
+	if input {
+		return True
+	}
+	return False
+}
+
+// isTruthy follows the same rules as the evaluator: null and false are falsy, everything else is truthy
+func isTruthy(obj object.Object) bool {
+	switch obj := obj.(type) {
+	case *object.Boolean:
+		return obj.Value
+	case *object.Null:
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,293 @@
+package vm
+
+import (
+	"testing"
+
+	"github.com/maxwellgithinji/jaba/pkg/ast"
+	"github.com/maxwellgithinji/jaba/pkg/compiler"
+	"github.com/maxwellgithinji/jaba/pkg/evaluator"
+	"github.com/maxwellgithinji/jaba/pkg/lexer"
+	"github.com/maxwellgithinji/jaba/pkg/object"
+	"github.com/maxwellgithinji/jaba/pkg/parser"
+)
+
+type vmTestCase struct {
+	input    string
+	expected interface{}
+}
+
+func parse(input string) *ast.Program {
+	l := lexer.New(input)
+	p := parser.New(l)
+	return p.ParseProgram()
+}
+
+func runVM(t *testing.T, input string) (object.Object, error) {
+	t.Helper()
+
+	c := compiler.New()
+	if err := c.Compile(parse(input)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	machine := New(c.Bytecode())
+	err := machine.Run()
+
+	return machine.LastPoppedStackElem(), err
+}
+
+func runVMTests(t *testing.T, tests []vmTestCase) {
+	t.Helper()
+
+	for _, tt := range tests {
+		result, err := runVM(t, tt.input)
+		if err != nil {
+			t.Fatalf("vm error for %q: %s", tt.input, err)
+		}
+
+		switch expected := tt.expected.(type) {
+		case int:
+			integer, ok := result.(*object.Integer)
+			if !ok {
+				t.Fatalf("%q: result is not *object.Integer, got: %T(%+v)", tt.input, result, result)
+			}
+			if integer.Value != int64(expected) {
+				t.Errorf("%q: integer.Value is not %d, got %d", tt.input, expected, integer.Value)
+			}
+
+		case bool:
+			boolean, ok := result.(*object.Boolean)
+			if !ok {
+				t.Fatalf("%q: result is not *object.Boolean, got: %T(%+v)", tt.input, result, result)
+			}
+			if boolean.Value != expected {
+				t.Errorf("%q: boolean.Value is not %t, got %t", tt.input, expected, boolean.Value)
+			}
+
+		case nil:
+			if result != Null {
+				t.Errorf("%q: result is not Null, got: %T(%+v)", tt.input, result, result)
+			}
+		}
+	}
+}
+
+func TestIntegerArithmetic(t *testing.T) {
+	tests := []vmTestCase{
+		{"1", 1},
+		{"1 + 2", 3},
+		{"1 - 2", -1},
+		{"4 / 2", 2},
+		{"50 / 2 * 2 + 10 - 5", 55},
+		{"5 * (2 + 10)", 60},
+		{"-5", -5},
+		{"-50 + 100 + -50", 0},
+		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+	}
+
+	runVMTests(t, tests)
+}
+
+func TestBooleanExpressions(t *testing.T) {
+	tests := []vmTestCase{
+		{"true", true},
+		{"false", false},
+		{"1 < 2", true},
+		{"1 > 2", false},
+		{"1 == 1", true},
+		{"1 != 1", false},
+		{"true == true", true},
+		{"true != false", true},
+		{"(1 < 2) == true", true},
+		{"!true", false},
+		{"!5", false},
+		{"!!5", true},
+		{"!(if (false) { 5; })", true},
+	}
+
+	runVMTests(t, tests)
+}
+
+func TestConditionals(t *testing.T) {
+	tests := []vmTestCase{
+		{"if (true) { 10 }", 10},
+		{"if (true) { 10 } else { 20 }", 10},
+		{"if (false) { 10 } else { 20 } ", 20},
+		{"if (1) { 10 }", 10},
+		{"if (1 > 2) { 10 }", nil},
+		{"if (false) { 10 }", nil},
+		{"if ((if (false) { 10 })) { 10 } else { 20 }", 20},
+		{"if (1 > 2) { 10 } else if (2 > 1) { 20 } else { 30 }", 20},
+		{"if (1 > 2) { 10 } else if (2 > 3) { 20 } else { 30 }", 30},
+		{"if (1 > 2) { 10 } else if (2 > 3) { 20 }", nil},
+	}
+
+	runVMTests(t, tests)
+}
+
+func TestGlobalLetStatements(t *testing.T) {
+	tests := []vmTestCase{
+		{"let one = 1; one", 1},
+		{"let one = 1; let two = 2; one + two", 3},
+		{"let one = 1; let two = one + one; one + two", 3},
+	}
+
+	runVMTests(t, tests)
+}
+
+func TestCallingFunctions(t *testing.T) {
+	tests := []vmTestCase{
+		{"let fivePlusTen = fn() { 5 + 10; }; fivePlusTen();", 15},
+		{"let early = fn() { return 99; 100; }; early();", 99},
+		{"let noReturn = fn() { }; noReturn();", nil},
+		{"let identity = fn(a) { a; }; identity(4);", 4},
+		{"let sum = fn(a, b) { let c = a + b; c; }; sum(1, 2) + sum(3, 4);", 10},
+		{
+			`
+			let fib = fn(x) {
+				if (x < 2) {
+					x
+				} else {
+					fib(x - 1) + fib(x - 2)
+				}
+			};
+			fib(15);
+			`,
+			610,
+		},
+	}
+
+	runVMTests(t, tests)
+}
+
+func TestMatchesEvaluator(t *testing.T) {
+	tests := []string{
+		"let x = 5;",
+		"1; let x = 5;",
+		"let x = 5; x",
+		"let f = fn() { 1; 2 }; let x = f();",
+		"let f = fn() { 1; 2 }; f()",
+		"9223372036854775807 + 1",
+		"-9223372036854775807 - 2",
+		"9223372036854775807 * 3",
+		"(9223372036854775807 + 1) - 1",
+		"(9223372036854775807 + 1) / 2",
+		"-(-9223372036854775807 - 1)",
+		"(-9223372036854775807 - 1) / -1",
+		"9223372036854775807 + 1 > 9223372036854775807",
+		"9223372036854775807 + 1 == 9223372036854775807 + 1",
+		"9223372036854775807 + 1 != 9223372036854775807",
+		"let big = fn(x) { x * x }; big(4294967296)",
+		"let x = 1; let x = x + 1; x",
+		"let a = 1; let f = fn() { let a = a + 1; a }; f()",
+	}
+
+	for _, input := range tests {
+		expected := evaluator.Eval(parse(input), object.NewEnvironment())
+
+		result, err := runVM(t, input)
+		if err != nil {
+			t.Fatalf("vm error for %q: %s", input, err)
+		}
+
+		if expected == nil || result == nil {
+			if expected != result {
+				t.Errorf("%q: the evaluator gives %v, the vm gives %v", input, expected, result)
+			}
+			continue
+		}
+
+		if result.Type() != expected.Type() || result.Inspect() != expected.Inspect() {
+			t.Errorf("%q: the evaluator gives %s %s, the vm gives %s %s", input, expected.Type(), expected.Inspect(), result.Type(), result.Inspect())
+		}
+	}
+}
+
+func TestRuntimeErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 / 0", "division by zero"},
+		{"(9223372036854775807 + 1) / 0", "division by zero"},
+		{"1 + true", "unsupported types for binary operation: INTEGER BOOLEAN"},
+		{"-true", "unsupported type for negation: BOOLEAN"},
+		{"fn(a) { a }()", "wrong number of arguments. got: 0 want: 1"},
+		{"1()", "not a function: INTEGER"},
+		{"let f = fn() { f() }; f()", "maximum recursion depth exceeded"},
+	}
+
+	for _, tt := range tests {
+		_, err := runVM(t, tt.input)
+		if err == nil {
+			t.Fatalf("expected vm error for %q", tt.input)
+		}
+
+		if err.Error() != tt.expected {
+			t.Errorf("wrong vm error. want: %q, got: %q", tt.expected, err.Error())
+		}
+	}
+}
+
+func TestLetCannotReadItsOwnName(t *testing.T) {
+	tests := []string{
+		"let x = x + 1; x",
+		"let f = fn() { let a = a + 1; a }; f()",
+	}
+
+	for _, input := range tests {
+		err := compiler.New().Compile(parse(input))
+		if err == nil {
+			t.Fatalf("expected compiler error for %q", input)
+		}
+
+		expected, ok := evaluator.Eval(parse(input), object.NewEnvironment()).(*object.Error)
+		if !ok {
+			t.Fatalf("expected evaluator error for %q", input)
+		}
+
+		if err.Error() != expected.Message {
+			t.Errorf("%q: the evaluator gives %q, the compiler gives %q", input, expected.Message, err.Error())
+		}
+	}
+}
+
+const fibInput = `
+let fib = fn(x) {
+	if (x < 2) {
+		x
+	} else {
+		fib(x - 1) + fib(x - 2)
+	}
+};
+fib(25);
+`
+
+func BenchmarkFibVM(b *testing.B) {
+	program := parse(fibInput)
+
+	c := compiler.New()
+	if err := c.Compile(program); err != nil {
+		b.Fatalf("compiler error: %s", err)
+	}
+	bytecode := c.Bytecode()
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		machine := New(bytecode)
+		if err := machine.Run(); err != nil {
+			b.Fatalf("vm error: %s", err)
+		}
+	}
+}
+
+func BenchmarkFibEvaluator(b *testing.B) {
+	program := parse(fibInput)
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		evaluator.Eval(program, object.NewEnvironment())
+	}
+}